@@ -15,26 +15,162 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
 
+	"go.felesatra.moe/keeper/internal/jsonfmt"
 	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/kpr/token"
 )
 
 var checkCmd = &command{
-	usageLine: "check [files]",
+	usageLine: "check [-Werror] [-format text|json|sarif] [files]",
 	run: func(cmd *command, args []string) {
 		fs := cmd.flagSet()
+		werror := fs.Bool("Werror", false, "Treat warnings as errors")
+		format := fs.String("format", "text", "Output format (text, json, or sarif)")
 		fs.Parse(args)
+		var write func(io.Writer, journal.DiagnosticList) error
+		w := io.Writer(os.Stdout)
+		// Machine-readable formats use the paths of the files
+		// as given, so tools can find the files.
+		inputs := journal.Paths(fs.Args()...)
+		switch *format {
+		case "text":
+			write = writeDiagnosticsText
+			w = os.Stderr
+			inputs = journal.Files(fs.Args()...)
+		case "json":
+			write = writeDiagnosticsJSON
+		case "sarif":
+			write = writeDiagnosticsSARIF
+		default:
+			log.Fatalf("unknown format %q", *format)
+		}
 		j, err := journal.Compile(&journal.CompileArgs{
-			Inputs: journal.Files(fs.Args()...),
+			Inputs: inputs,
 		})
+		var diags journal.DiagnosticList
 		if err != nil {
+			if !errors.As(err, &diags) {
+				log.Fatal(err)
+			}
+		} else {
+			diags = j.Diagnostics
+		}
+		if err := write(w, diags); err != nil {
 			log.Fatal(err)
 		}
-		checkBalanceErrsAndExit(j)
+		if diags.HasErrors() || *werror && len(diags) > 0 {
+			os.Exit(1)
+		}
 	},
 }
 
+// writeDiagnosticsText writes diagnostics grouped by severity, with a
+// count for each group.  The diagnostics should be sorted.
+func writeDiagnosticsText(w io.Writer, diags journal.DiagnosticList) error {
+	for i, d := range diags {
+		if i == 0 || d.Severity != diags[i-1].Severity {
+			n := 0
+			for _, d2 := range diags[i:] {
+				if d2.Severity == d.Severity {
+					n++
+				}
+			}
+			fmt.Fprintf(w, "%ss (%d):\n", d.Severity, n)
+		}
+		fmt.Fprintf(w, "  %s [%s]\n", d, d.Code)
+		for _, p := range d.Related {
+			fmt.Fprintf(w, "    related: %s\n", p)
+		}
+	}
+	return nil
+}
+
+// writeDiagnosticsJSON writes diagnostics as a JSON object with a
+// list of diagnostics.
+func writeDiagnosticsJSON(w io.Writer, diags journal.DiagnosticList) error {
+	type result struct {
+		Diagnostics []jsonfmt.Diagnostic `json:"diagnostics"`
+	}
+	res := result{Diagnostics: jsonfmt.Diagnostics(diags)}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(res)
+}
+
+// writeDiagnosticsSARIF writes diagnostics as a SARIF 2.1.0 log.
+// The account and balance amounts of diagnostics are included as
+// result properties.
+func writeDiagnosticsSARIF(w io.Writer, diags journal.DiagnosticList) error {
+	type object = map[string]interface{}
+	location := func(p token.Position) object {
+		l := object{"artifactLocation": object{"uri": filepath.ToSlash(p.Filename)}}
+		if p.IsValid() {
+			l["region"] = object{"startLine": p.Line, "startColumn": p.Column}
+		}
+		return object{"physicalLocation": l}
+	}
+	rules := []object{}
+	seen := make(map[string]bool)
+	results := []object{}
+	for _, d := range diags {
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			rules = append(rules, object{"id": d.Code})
+		}
+		r := object{
+			"ruleId":  d.Code,
+			"level":   d.Severity.String(),
+			"message": object{"text": d.Msg},
+		}
+		if d.Pos.Filename != "" {
+			r["locations"] = []object{location(d.Pos)}
+		}
+		if len(d.Related) > 0 {
+			var rel []object
+			for _, p := range d.Related {
+				rel = append(rel, location(p))
+			}
+			r["relatedLocations"] = rel
+		}
+		jd := jsonfmt.NewDiagnostic(d)
+		props := object{}
+		if jd.Account != "" {
+			props["account"] = jd.Account
+		}
+		if jd.Balance != nil {
+			props["balance"] = jd.Balance
+		}
+		if len(props) > 0 {
+			r["properties"] = props
+		}
+		results = append(results, r)
+	}
+	sarif := object{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []object{{
+			"tool": object{
+				"driver": object{
+					"name":  "keeper",
+					"rules": rules,
+				},
+			},
+			"results": results,
+		}},
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(sarif)
+}
+
 func checkBalanceErrsAndExit(j *journal.Journal) {
 	if len(j.BalanceErrors) > 0 {
 		for _, e := range j.BalanceErrors {
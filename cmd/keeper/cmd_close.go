@@ -25,14 +25,17 @@ import (
 	"cloud.google.com/go/civil"
 	"go.felesatra.moe/keeper/internal/month"
 	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
+	"go.felesatra.moe/keeper/reports"
 )
 
 var closeCmd = &command{
-	usageLine: "close [-month month] [-trading] [files]",
+	usageLine: "close [-config file] [-period period] [-trading] [files]",
 	run: func(cmd *command, args []string) {
 		fs := cmd.flagSet()
 		c := configPath(fs)
-		m := fs.String("month", "", "Month to close")
+		ps := fs.String("period", "", "Period to close (default previous month)")
+		m := fs.String("month", "", "Month to close (deprecated, use -period)")
 		t := fs.Bool("trading", false, "Include trading accounts")
 		fs.Parse(args)
 		if fs.NArg() < 1 {
@@ -40,25 +43,36 @@ var closeCmd = &command{
 			os.Exit(2)
 		}
 
-		var d civil.Date
-		if *m != "" {
+		fiscal := c.Period.FiscalStart()
+		var p period.Period
+		switch {
+		case *ps != "":
 			var err error
-			d, err = month.Parse(*m)
+			p, err = period.Parse(*ps, fiscal)
 			if err != nil {
 				log.Fatal(err)
 			}
-		} else {
-			d = month.Prev(month.Now())
+		case *m != "":
+			d, err := month.Parse(*m)
+			if err != nil {
+				log.Fatal(err)
+			}
+			p = period.Containing(period.Month, d, fiscal)
+		default:
+			p = period.Containing(period.Month, month.Now(), fiscal).Prev()
 		}
 
 		j, err := journal.Compile(&journal.CompileArgs{
 			Inputs: journal.Files(fs.Args()...),
-			Ending: month.LastDay(d),
+			Ending: p.End,
 		})
 		if err != nil {
 			log.Fatal(err)
 		}
 		checkBalanceErrsAndExit(j)
+		if err := c.SetAccounts(j.Accounts); err != nil {
+			log.Fatal(err)
+		}
 		var accsToClose []journal.Account
 		var equity journal.Account
 		for ac := range j.Accounts {
@@ -73,18 +87,20 @@ var closeCmd = &command{
 		}
 		sort.Slice(accsToClose, func(i, j int) bool { return accsToClose[i] < accsToClose[j] })
 		bals := closingBalances(j, accsToClose)
-		_ = printClosingBalances(os.Stdout, j, month.LastDay(d), bals)
-		_ = printClosingTx(os.Stdout, month.Next(d), equity, bals)
+		_ = printClosingBalances(os.Stdout, c.BaseUnit(j), p.End, bals)
+		_ = printClosingTx(os.Stdout, p.End.AddDays(1), equity, bals)
 	},
 }
 
-func printClosingBalances(w io.Writer, j *journal.Journal, d civil.Date, b journal.Balances) error {
+// printClosingBalances prints balance assertions for the closing
+// balances.  Empty balances are asserted as zero in the base unit.
+func printClosingBalances(w io.Writer, base journal.Unit, d civil.Date, b journal.Balances) error {
 	bw := bufio.NewWriter(w)
 	for _, a := range b.Accounts() {
 		b := b[a]
 		switch x := len(b.Units()); true {
 		case x == 0:
-			fmt.Fprintf(bw, "balance %s %s 0 USD\n", d, a)
+			fmt.Fprintf(bw, "balance %s %s 0 %s\n", d, a, base.Symbol)
 		case x == 1:
 			fmt.Fprintf(bw, "balance %s %s %v\n", d, a, b)
 		default:
@@ -100,10 +116,11 @@ func printClosingBalances(w io.Writer, j *journal.Journal, d civil.Date, b journ
 
 // printClosingTx prints a transaction entry that moves everything
 // from the given accounts (usually income, etc. accounts) into the
-// destination account (usually equity account).
+// destination account (usually equity account).  The transaction is
+// tagged so reports can ignore it.
 func printClosingTx(w io.Writer, d civil.Date, dst journal.Account, b journal.Balances) error {
 	bw := bufio.NewWriter(w)
-	fmt.Fprintf(bw, "tx %s \"Closing\"\n", d)
+	fmt.Fprintf(bw, "tx %s \"Closing\" #%s\n", d, reports.ClosingTag)
 	var total journal.Balance
 	for _, a := range b.Accounts() {
 		b := b[a]
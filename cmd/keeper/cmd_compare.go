@@ -0,0 +1,110 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
+	"go.felesatra.moe/keeper/reports"
+)
+
+var compareCmd = &command{
+	usageLine: "compare [-config file] [-report income|balance] [-period period] [-n count] [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		c := configPath(fs)
+		report := fs.String("report", "income", "Statement to compare (income or balance)")
+		ps := fs.String("period", "", "Last period to compare (default current month)")
+		n := fs.Int("n", 12, "Number of periods to compare")
+		fs.Parse(args)
+		if *n < 1 {
+			log.Fatalf("invalid number of periods %d", *n)
+		}
+		fiscal := c.Period.FiscalStart()
+		p := period.Containing(period.Month, civil.DateOf(time.Now()), fiscal)
+		if *ps != "" {
+			var err error
+			p, err = period.Parse(*ps, fiscal)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		j, err := journal.Compile(&journal.CompileArgs{
+			Inputs: journal.Files(fs.Args()...),
+			Ending: p.End,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := c.SetAccounts(j.Accounts); err != nil {
+			log.Fatal(err)
+		}
+		var r *reports.Comparative
+		switch *report {
+		case "income":
+			r = reports.NewComparativeIncomeStatement(j, c, period.Series(p, *n))
+		case "balance":
+			r = reports.NewComparativeBalanceSheet(j, c, period.Series(p, *n))
+		default:
+			log.Fatalf("unknown report %q", *report)
+		}
+		if err := writeComparativeCSV(os.Stdout, r); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// writeComparativeCSV writes a comparative statement as CSV.  Each
+// row contains the amounts of one unit, and the change is written as
+// a percentage.  Total rows have an empty account.
+func writeComparativeCSV(w io.Writer, r *reports.Comparative) error {
+	cw := csv.NewWriter(w)
+	h := []string{"section", "account", "unit"}
+	for _, p := range r.Periods {
+		h = append(h, p.String())
+	}
+	cw.Write(append(h, "variance", "change"))
+	for _, s := range r.Sections {
+		for _, rows := range [][]reports.ComparativeRow{s.Rows, s.Totals} {
+			for _, row := range rows {
+				rec := []string{s.Title, string(row.Account), row.Unit.Symbol}
+				for _, a := range row.Amounts {
+					rec = append(rec, a.Decimal())
+				}
+				rec = append(rec, row.Variance.Decimal(), percent(row.Change))
+				cw.Write(rec)
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func percent(r *big.Rat) string {
+	if r == nil {
+		return ""
+	}
+	var p big.Rat
+	p.Mul(r, big.NewRat(100, 1))
+	return p.FloatString(2)
+}
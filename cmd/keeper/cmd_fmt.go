@@ -0,0 +1,151 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+
+	"go.felesatra.moe/keeper/kpr/parser"
+	"go.felesatra.moe/keeper/kpr/printer"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+var fmtCmd = &command{
+	usageLine: "fmt [-w] [-d] [-l] [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		o := fmtOptions{
+			write: fs.Bool("w", false, "Write result to source file instead of stdout"),
+			diff:  fs.Bool("d", false, "Display diffs instead of rewriting files"),
+			list:  fs.Bool("l", false, "List files whose formatting differs"),
+		}
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			if *o.write {
+				log.Fatal("cannot use -w with standard input")
+			}
+			src, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := o.process("<standard input>", src); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		failed := false
+		for _, f := range fs.Args() {
+			src, err := os.ReadFile(f)
+			if err == nil {
+				err = o.process(f, src)
+			}
+			if err != nil {
+				log.Print(err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+type fmtOptions struct {
+	write *bool
+	diff  *bool
+	list  *bool
+}
+
+// process formats the source of a file and handles the result
+// according to the options.
+func (o fmtOptions) process(filename string, src []byte) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseBytes(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	res, err := printer.Format(fset, f)
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	changed := !bytes.Equal(src, res)
+	if *o.list && changed {
+		fmt.Println(filename)
+	}
+	if *o.diff && changed {
+		d, err := diff(filename, src, res)
+		if err != nil {
+			return fmt.Errorf("computing diff: %s", err)
+		}
+		os.Stdout.Write(d)
+	}
+	if *o.write {
+		if changed {
+			return os.WriteFile(filename, res, 0o644)
+		}
+		return nil
+	}
+	if !*o.list && !*o.diff {
+		_, err := os.Stdout.Write(res)
+		return err
+	}
+	return nil
+}
+
+// diff returns a unified diff of two versions of a file using the
+// system diff command.
+func diff(filename string, b1, b2 []byte) ([]byte, error) {
+	f1, err := writeTempFile("keeper-fmt", b1)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f1)
+	f2, err := writeTempFile("keeper-fmt", b2)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f2)
+	out, err := exec.Command("diff", "-u",
+		"--label", filename+".orig", "--label", filename,
+		f1, f2).Output()
+	// diff exits with status 1 if the files differ.
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() == 1 {
+		err = nil
+	}
+	return out, err
+}
+
+func writeTempFile(prefix string, data []byte) (string, error) {
+	f, err := os.CreateTemp("", prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
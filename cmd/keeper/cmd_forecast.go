@@ -0,0 +1,72 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
+)
+
+var forecastCmd = &command{
+	usageLine: "forecast [-config file] -until period [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		c := configPath(fs)
+		until := fs.String("until", "", "Forecast through the end of the period")
+		fs.Parse(args)
+		if fs.NArg() < 1 || *until == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		p, err := period.Parse(*until, c.Period.FiscalStart())
+		if err != nil {
+			log.Fatal(err)
+		}
+		j, err := journal.Compile(&journal.CompileArgs{
+			Inputs:   journal.Files(fs.Args()...),
+			Forecast: p.End,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		checkBalanceErrsAndExit(j)
+		if err := printForecast(os.Stdout, p.End, j.BalancesEnding(p.End)); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// printForecast prints the projected balances of accounts on a date.
+func printForecast(w io.Writer, d civil.Date, b journal.Balances) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Balances on %s\n", d)
+	for _, a := range b.Accounts() {
+		for i, am := range b[a].Amounts() {
+			name := string(a)
+			if i > 0 {
+				name = ""
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", name, am)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
+	"go.felesatra.moe/keeper/reports"
+)
+
+var gainsCmd = &command{
+	usageLine: "gains [-config file] [-period period] [-start date] [-end date] [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		c := configPath(fs)
+		ps := fs.String("period", "", "Period to report (default current year)")
+		start := fs.String("start", "", "Start date, overriding the start of the period")
+		end := fs.String("end", "", "End date, overriding the end of the period")
+		y := fs.Int("year", 0, "Tax year to report (deprecated, use -period)")
+		fs.Parse(args)
+		fiscal := c.Period.FiscalStart()
+		p := period.Containing(period.Year, civil.DateOf(time.Now()), fiscal)
+		switch {
+		case *ps != "":
+			var err error
+			p, err = period.Parse(*ps, fiscal)
+			if err != nil {
+				log.Fatal(err)
+			}
+		case *y != 0:
+			p = period.Containing(period.Year, civil.Date{Year: *y, Month: time.January, Day: 1}, fiscal)
+		}
+		if *start != "" {
+			d, err := civil.ParseDate(*start)
+			if err != nil {
+				log.Fatalf("invalid start date %q", *start)
+			}
+			p.Start = d
+			p.Kind = period.Custom
+		}
+		if *end != "" {
+			d, err := civil.ParseDate(*end)
+			if err != nil {
+				log.Fatalf("invalid end date %q", *end)
+			}
+			p.End = d
+			p.Kind = period.Custom
+		}
+		if p.End.Before(p.Start) {
+			log.Fatalf("end %s is before start %s", p.End, p.Start)
+		}
+		j, err := journal.Compile(&journal.CompileArgs{
+			Inputs: journal.Files(fs.Args()...),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		g, err := reports.NewCapitalGains(j, c.BaseUnit(j), p.Start, p.End)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writeGainsCSV(os.Stdout, g); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// writeGainsCSV writes the disposals in a capital gains report as CSV,
+// followed by a blank line and the totals per unit.
+func writeGainsCSV(w io.Writer, g *reports.CapitalGains) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"account", "unit", "amount", "acquired", "disposed",
+		"proceeds", "cost", "gain", "long_term", "ref",
+	})
+	for _, r := range g.Rows {
+		cw.Write([]string{
+			string(r.Account),
+			r.Amount.Unit.Symbol,
+			r.Amount.Decimal(),
+			r.Acquired.String(),
+			r.Disposed.String(),
+			r.Proceeds.Decimal(),
+			r.Cost.Decimal(),
+			r.Gain.Decimal(),
+			strconv.FormatBool(r.LongTerm),
+			r.Ref,
+		})
+	}
+	cw.Write(nil)
+	cw.Write([]string{
+		"unit", "amount", "proceeds", "cost", "gain",
+		"short_term", "long_term",
+	})
+	for _, t := range g.Totals {
+		cw.Write([]string{
+			t.Unit.Symbol,
+			t.Amount.Decimal(),
+			t.Proceeds.Decimal(),
+			t.Cost.Decimal(),
+			t.Gain.Decimal(),
+			t.ShortTerm.Decimal(),
+			t.LongTerm.Decimal(),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
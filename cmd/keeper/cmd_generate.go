@@ -0,0 +1,61 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/importer"
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
+)
+
+var generateCmd = &command{
+	usageLine: "generate [-config file] [-through period] [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		c := configPath(fs)
+		through := fs.String("through", "", "Generate occurrences through the end of the period (default today)")
+		fs.Parse(args)
+		if fs.NArg() < 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		d := civil.DateOf(time.Now())
+		if *through != "" {
+			p, err := period.Parse(*through, c.Period.FiscalStart())
+			if err != nil {
+				log.Fatal(err)
+			}
+			d = p.End
+		}
+		j, err := journal.Compile(&journal.CompileArgs{
+			Inputs: journal.Files(fs.Args()...),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		var entries []journal.Entry
+		for _, t := range j.Due(d) {
+			entries = append(entries, t)
+		}
+		if err := importer.Fprint(os.Stdout, entries); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"go.felesatra.moe/keeper/importer"
+	"go.felesatra.moe/keeper/journal"
+)
+
+var importCmd = &command{
+	usageLine: "import csv|ofx [-journal file] [-days n] [flags] [files]",
+	run: func(cmd *command, args []string) {
+		if len(args) < 1 {
+			log.Fatal("no import format specified")
+		}
+		format, args := args[0], args[1:]
+		fs := cmd.flagSet()
+		jf := fs.String("journal", "", "Existing journal file, used for units and to skip duplicate transactions")
+		days := fs.Int("days", 3, "Maximum date difference when matching duplicate transactions by amount")
+		var newImporter func(units map[string]journal.Unit) (importer.Importer, error)
+		switch format {
+		case "csv":
+			rules := fs.String("rules", "", "Path to CSV rules file")
+			newImporter = func(units map[string]journal.Unit) (importer.Importer, error) {
+				if *rules == "" {
+					return nil, fmt.Errorf("no rules file specified")
+				}
+				imp, err := loadCSVImporter(*rules)
+				if err != nil {
+					return nil, err
+				}
+				imp.Units = units
+				return imp, nil
+			}
+		case "ofx", "qfx":
+			account := fs.String("account", "", "Account for the statement")
+			counter := fs.String("counter", "Expenses:Unknown", "Counter account for transactions")
+			newImporter = func(units map[string]journal.Unit) (importer.Importer, error) {
+				if *account == "" {
+					return nil, fmt.Errorf("no account specified")
+				}
+				return &importer.OFXImporter{
+					Account:        journal.Account(*account),
+					DefaultAccount: journal.Account(*counter),
+					Units:          units,
+				}, nil
+			}
+		default:
+			log.Fatalf("unknown import format %s", format)
+		}
+		fs.Parse(args)
+		var j *journal.Journal
+		var units map[string]journal.Unit
+		if *jf != "" {
+			var err error
+			j, err = journal.Compile(&journal.CompileArgs{
+				Inputs: journal.Files(*jf),
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+			units = j.Units
+		}
+		imp, err := newImporter(units)
+		if err != nil {
+			log.Fatal(err)
+		}
+		entries, err := importFiles(imp, fs.Args())
+		if err != nil {
+			log.Fatal(err)
+		}
+		entries = importer.RemoveDuplicates(entries, j, *days)
+		if err := importer.Fprint(os.Stdout, entries); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func loadCSVImporter(path string) (*importer.CSVImporter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := importer.LoadCSVRules(f)
+	if err != nil {
+		return nil, err
+	}
+	return importer.NewCSVImporter(c)
+}
+
+// importFiles imports the files, or standard input if no files are
+// given.
+func importFiles(imp importer.Importer, files []string) ([]journal.Entry, error) {
+	if len(files) == 0 {
+		return imp.Import(os.Stdin)
+	}
+	var entries []journal.Entry
+	for _, path := range files {
+		e, err := importFile(imp, path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+func importFile(imp importer.Importer, path string) ([]journal.Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	e, err := imp.Import(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return e, nil
+}
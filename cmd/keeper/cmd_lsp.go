@@ -0,0 +1,33 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"go.felesatra.moe/keeper/internal/lsp"
+)
+
+var lspCmd = &command{
+	usageLine: "lsp [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		fs.Parse(args)
+		if err := lsp.Serve(os.Stdin, os.Stdout, fs.Args()); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
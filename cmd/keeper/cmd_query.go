@@ -0,0 +1,165 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"go.felesatra.moe/keeper/internal/jsonfmt"
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/query"
+)
+
+var queryCmd = &command{
+	usageLine: "query [-format table|csv|json] query [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		format := fs.String("format", "table", "Output format (table, csv, or json)")
+		fs.Parse(args)
+		if fs.NArg() < 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		var write func(io.Writer, *query.Result) error
+		switch *format {
+		case "table":
+			write = writeQueryTable
+		case "csv":
+			write = writeQueryCSV
+		case "json":
+			write = writeQueryJSON
+		default:
+			log.Fatalf("unknown format %q", *format)
+		}
+		q, err := query.Parse(fs.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		j, err := journal.Compile(&journal.CompileArgs{
+			Inputs: journal.Files(fs.Args()[1:]...),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, err := q.Run(j)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := write(os.Stdout, r); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// writeQueryTable writes a query result as an aligned text table.
+func writeQueryTable(w io.Writer, r *query.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(r.Columns, "\t"))
+	rule := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		rule[i] = strings.Repeat("-", len(c))
+	}
+	fmt.Fprintln(tw, strings.Join(rule, "\t"))
+	for _, row := range r.Rows {
+		s := make([]string, len(row))
+		for i, v := range row {
+			s[i] = v.String()
+		}
+		fmt.Fprintln(tw, strings.Join(s, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeQueryCSV writes a query result as CSV.  Amounts are written
+// without digit grouping.
+func writeQueryCSV(w io.Writer, r *query.Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write(r.Columns)
+	for _, row := range r.Rows {
+		s := make([]string, len(row))
+		for i, v := range row {
+			s[i] = csvValue(v)
+		}
+		cw.Write(s)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v query.Value) string {
+	switch v := v.(type) {
+	case query.Amount:
+		return plainAmount(v.Amount)
+	case query.Balance:
+		var s []string
+		for _, a := range v.Amounts() {
+			s = append(s, plainAmount(a))
+		}
+		return strings.Join(s, ", ")
+	default:
+		return v.String()
+	}
+}
+
+func plainAmount(a *journal.Amount) string {
+	return a.Decimal() + " " + a.Unit.Symbol
+}
+
+// writeQueryJSON writes a query result as a JSON object with the
+// column names and the rows as arrays.  Amounts and balances are
+// written as in package jsonfmt.
+func writeQueryJSON(w io.Writer, r *query.Result) error {
+	type result struct {
+		Columns []string        `json:"columns"`
+		Rows    [][]interface{} `json:"rows"`
+	}
+	res := result{
+		Columns: r.Columns,
+		Rows:    make([][]interface{}, len(r.Rows)),
+	}
+	for i, row := range r.Rows {
+		res.Rows[i] = make([]interface{}, len(row))
+		for j, v := range row {
+			res.Rows[i][j] = jsonValue(v)
+		}
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(res)
+}
+
+func jsonValue(v query.Value) interface{} {
+	switch v := v.(type) {
+	case query.Null:
+		return nil
+	case query.Bool:
+		return bool(v)
+	case query.Number:
+		return json.Number(v.String())
+	case query.Amount:
+		return jsonfmt.NewAmount(v.Amount)
+	case query.Balance:
+		return jsonfmt.Balance(v.Balance)
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"go.felesatra.moe/keeper/internal/refactor"
+	"go.felesatra.moe/keeper/journal"
+)
+
+var renameAccountCmd = &command{
+	usageLine: "rename-account [-merge] [-n] old new [files]",
+	run: func(cmd *command, args []string) {
+		fs := cmd.flagSet()
+		merge := fs.Bool("merge", false, "Allow renaming to an existing account, merging the accounts")
+		dryRun := fs.Bool("n", false, "List files that would be changed without writing them")
+		fs.Parse(args)
+		if fs.NArg() < 3 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		old, new := journal.Account(fs.Arg(0)), journal.Account(fs.Arg(1))
+		paths, err := journal.SourceFiles(fs.Args()[2:]...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var srcs []refactor.Source
+		for _, p := range paths {
+			src, err := os.ReadFile(p)
+			if err != nil {
+				log.Fatal(err)
+			}
+			srcs = append(srcs, refactor.Source{Filename: p, Src: src})
+		}
+		changed, err := refactor.RenameAccount(srcs, old, new, *merge)
+		if errors.Is(err, refactor.ErrAccountExists) {
+			log.Fatalf("%s (use -merge to merge the accounts)", err)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range changed {
+			fmt.Println(s.Filename)
+			if *dryRun {
+				continue
+			}
+			if err := os.WriteFile(s.Filename, s.Src, 0o644); err != nil {
+				log.Fatal(err)
+			}
+		}
+	},
+}
@@ -43,7 +43,16 @@ func init() {
 	commands = []*command{
 		checkCmd,
 		closeCmd,
+		compareCmd,
+		fmtCmd,
+		forecastCmd,
+		gainsCmd,
+		generateCmd,
 		helpCmd,
+		importCmd,
+		lspCmd,
+		queryCmd,
+		renameAccountCmd,
 		serveCmd,
 	}
 }
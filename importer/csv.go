@@ -0,0 +1,395 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/civil"
+	"github.com/pelletier/go-toml"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// CSVRules describe how to import a CSV statement.
+//
+// Columns are named by the header if the statement has one, or by
+// their 1-based index.
+//
+// Example rules file:
+//
+//	account = "Assets:Bank"
+//	unit = "USD"
+//	header = true
+//	date_column = "Date"
+//	date_format = "01/02/2006"
+//	description_column = "Description"
+//	amount_column = "Amount"
+//	default_account = "Expenses:Unknown"
+//
+//	[[rule]]
+//	match = "(?i)grocery"
+//	account = "Expenses:Food"
+type CSVRules struct {
+	// Account is the account that the statement is for.
+	Account string `toml:"account"`
+	Unit    string `toml:"unit"`
+	// Skip is the number of lines to skip at the start.
+	Skip int `toml:"skip"`
+	// Header is whether the first line after skipped lines
+	// contains column names.
+	Header bool `toml:"header"`
+	// Delimiter is the field delimiter.  The default is a comma.
+	Delimiter string `toml:"delimiter"`
+
+	DateColumn string `toml:"date_column"`
+	// DateFormat is a Go time layout.  The default is
+	// 2006-01-02.
+	DateFormat        string `toml:"date_format"`
+	DescriptionColumn string `toml:"description_column"`
+	// AmountColumn contains amounts added to the account.
+	// Alternatively, InflowColumn and OutflowColumn contain
+	// amounts added to and removed from the account.
+	AmountColumn  string `toml:"amount_column"`
+	InflowColumn  string `toml:"inflow_column"`
+	OutflowColumn string `toml:"outflow_column"`
+	// Negate flips the sign of amounts, for statements that
+	// show charges as positive amounts.
+	Negate bool `toml:"negate"`
+
+	// DefaultAccount is the counter account for rows that do
+	// not match any rule.
+	DefaultAccount string `toml:"default_account"`
+	// Rules assign counter accounts.  The first matching rule
+	// is used.
+	Rules []Rule `toml:"rule"`
+}
+
+// A Rule assigns a counter account to matching rows.
+type Rule struct {
+	// Match is a regular expression matched against the column.
+	Match string `toml:"match"`
+	// Column is the column to match.  The default is the
+	// description column.
+	Column  string `toml:"column"`
+	Account string `toml:"account"`
+	// Description replaces the transaction description if set.
+	Description string `toml:"description"`
+}
+
+// LoadCSVRules loads CSV rules from TOML.
+func LoadCSVRules(r io.Reader) (*CSVRules, error) {
+	c := &CSVRules{}
+	if err := toml.NewDecoder(r).Decode(c); err != nil {
+		return nil, fmt.Errorf("load csv rules: %s", err)
+	}
+	return c, nil
+}
+
+// A CSVImporter imports CSV statements.
+type CSVImporter struct {
+	// Units are the declared units, used for the scale of
+	// amounts.  If the statement unit is not declared, the
+	// smallest scale that fits the amounts in the statement is
+	// used.
+	Units   map[string]journal.Unit
+	rules   *CSVRules
+	regexps []*regexp.Regexp
+}
+
+var _ Importer = &CSVImporter{}
+
+// NewCSVImporter returns an importer using the rules.
+func NewCSVImporter(c *CSVRules) (*CSVImporter, error) {
+	switch {
+	case c.Account == "":
+		return nil, errors.New("new csv importer: account not set")
+	case c.Unit == "":
+		return nil, errors.New("new csv importer: unit not set")
+	case c.DateColumn == "":
+		return nil, errors.New("new csv importer: date column not set")
+	case c.AmountColumn == "" && c.InflowColumn == "" && c.OutflowColumn == "":
+		return nil, errors.New("new csv importer: amount columns not set")
+	case c.AmountColumn != "" && (c.InflowColumn != "" || c.OutflowColumn != ""):
+		return nil, errors.New("new csv importer: both amount and inflow/outflow columns set")
+	case c.DefaultAccount == "":
+		return nil, errors.New("new csv importer: default account not set")
+	}
+	if utf8.RuneCountInString(c.Delimiter) > 1 {
+		return nil, fmt.Errorf("new csv importer: invalid delimiter %q", c.Delimiter)
+	}
+	i := &CSVImporter{rules: c}
+	for _, r := range c.Rules {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("new csv importer: rule %q: %s", r.Match, err)
+		}
+		if r.Account == "" {
+			return nil, fmt.Errorf("new csv importer: rule %q: account not set", r.Match)
+		}
+		if r.Column == "" && c.DescriptionColumn == "" {
+			return nil, fmt.Errorf("new csv importer: rule %q: column not set", r.Match)
+		}
+		i.regexps = append(i.regexps, re)
+	}
+	return i, nil
+}
+
+// Import imports a CSV statement.
+func (i *CSVImporter) Import(r io.Reader) ([]journal.Entry, error) {
+	c := i.rules
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	if c.Delimiter != "" {
+		cr.Comma, _ = utf8.DecodeRuneInString(c.Delimiter)
+	}
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("import csv: %s", err)
+	}
+	if c.Skip > len(records) {
+		return nil, nil
+	}
+	records = records[c.Skip:]
+	var header []string
+	if c.Header {
+		if len(records) == 0 {
+			return nil, nil
+		}
+		header, records = records[0], records[1:]
+	}
+	cols := columns{header: header}
+	var recs []csvRecord
+	for n, rec := range records {
+		line := n + c.Skip + 1
+		if c.Header {
+			line++
+		}
+		if isBlank(rec) {
+			continue
+		}
+		r, err := i.importRecord(cols, rec)
+		if err != nil {
+			return nil, fmt.Errorf("import csv: record on line %d: %s", line, err)
+		}
+		r.line = line
+		recs = append(recs, r)
+	}
+	amounts := make([]decimal, len(recs))
+	for k, r := range recs {
+		amounts[k] = r.amount
+	}
+	u := statementUnit(c.Unit, i.Units, amounts)
+	var entries []journal.Entry
+	for _, r := range recs {
+		t, err := newTransaction(r.amount, u, journal.Account(c.Account), journal.Account(r.counter))
+		if err != nil {
+			return nil, fmt.Errorf("import csv: record on line %d: %s", r.line, err)
+		}
+		t.EntryDate = r.date
+		t.Description = r.description
+		entries = append(entries, t)
+	}
+	return entries, nil
+}
+
+// A csvRecord is a parsed CSV record.
+type csvRecord struct {
+	line        int
+	date        civil.Date
+	description string
+	amount      decimal
+	counter     string
+}
+
+func (i *CSVImporter) importRecord(cols columns, rec []string) (csvRecord, error) {
+	c := i.rules
+	var t csvRecord
+	s, err := cols.get(rec, c.DateColumn)
+	if err != nil {
+		return t, err
+	}
+	t.date, err = parseDate(c.DateFormat, s)
+	if err != nil {
+		return t, err
+	}
+	if c.DescriptionColumn != "" {
+		s, err := cols.get(rec, c.DescriptionColumn)
+		if err != nil {
+			return t, err
+		}
+		t.description = strings.TrimSpace(s)
+	}
+	t.amount, err = i.amount(cols, rec)
+	if err != nil {
+		return t, err
+	}
+	t.counter = c.DefaultAccount
+	for k, r := range c.Rules {
+		col := r.Column
+		if col == "" {
+			col = c.DescriptionColumn
+		}
+		v, err := cols.get(rec, col)
+		if err != nil {
+			return t, err
+		}
+		if i.regexps[k].MatchString(v) {
+			t.counter = r.Account
+			if r.Description != "" {
+				t.description = r.Description
+			}
+			break
+		}
+	}
+	return t, nil
+}
+
+// amount returns the amount added to the account in a record.
+func (i *CSVImporter) amount(cols columns, rec []string) (decimal, error) {
+	c := i.rules
+	if c.AmountColumn != "" {
+		s, err := cols.get(rec, c.AmountColumn)
+		if err != nil {
+			return decimal{}, err
+		}
+		d, err := parseAmount(s)
+		if err != nil {
+			return decimal{}, err
+		}
+		if c.Negate {
+			d.r.Neg(d.r)
+		}
+		return d, nil
+	}
+	d := decimal{r: new(big.Rat)}
+	for _, col := range []string{c.InflowColumn, c.OutflowColumn} {
+		if col == "" {
+			continue
+		}
+		s, err := cols.get(rec, col)
+		if err != nil {
+			return decimal{}, err
+		}
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		d2, err := parseAmount(s)
+		if err != nil {
+			return decimal{}, err
+		}
+		if col == c.OutflowColumn {
+			d2.r.Neg(d2.r)
+		}
+		d.r.Add(d.r, d2.r)
+		d.prec = max(d.prec, d2.prec)
+	}
+	if c.Negate {
+		d.r.Neg(d.r)
+	}
+	return d, nil
+}
+
+var amountPattern = regexp.MustCompile(`^-?[0-9]+(\.([0-9]+))?$`)
+
+// parseAmount parses a statement amount.  Currency symbols, digit
+// grouping and parentheses for negative amounts are handled.
+func parseAmount(s string) (decimal, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = s[1 : len(s)-1]
+	}
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == '.' || '0' <= r && r <= '9':
+			return r
+		default:
+			return -1
+		}
+	}, s)
+	m := amountPattern.FindStringSubmatch(s)
+	if m == nil {
+		return decimal{}, fmt.Errorf("invalid amount %q", orig)
+	}
+	n, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return decimal{}, fmt.Errorf("invalid amount %q", orig)
+	}
+	if neg {
+		n.Neg(n)
+	}
+	return decimal{r: n, prec: len(m[2])}, nil
+}
+
+func parseDate(layout, s string) (civil.Date, error) {
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+	t, err := time.Parse(layout, strings.TrimSpace(s))
+	if err != nil {
+		return civil.Date{}, err
+	}
+	return civil.DateOf(t), nil
+}
+
+// columns finds columns in records by name or index.
+type columns struct {
+	header []string
+}
+
+func (c columns) get(rec []string, col string) (string, error) {
+	i := c.index(col)
+	if i < 0 {
+		return "", fmt.Errorf("unknown column %q", col)
+	}
+	if i >= len(rec) {
+		return "", fmt.Errorf("missing column %q", col)
+	}
+	return rec[i], nil
+}
+
+// index returns the index of the column, or -1 if not found.
+func (c columns) index(col string) int {
+	for i, h := range c.header {
+		h = strings.TrimPrefix(h, "\ufeff")
+		if strings.TrimSpace(h) == col {
+			return i
+		}
+	}
+	if n, err := strconv.Atoi(col); err == nil && n > 0 {
+		return n - 1
+	}
+	return -1
+}
+
+func isBlank(rec []string) bool {
+	for _, s := range rec {
+		if strings.TrimSpace(s) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/journal"
+)
+
+func TestCSVImporter(t *testing.T) {
+	t.Parallel()
+	const rules = `account = "Assets:Bank"
+unit = "USD"
+skip = 1
+header = true
+date_column = "Date"
+date_format = "01/02/2006"
+description_column = "Description"
+amount_column = "Amount"
+default_account = "Expenses:Unknown"
+
+[[rule]]
+match = "(?i)grocery"
+account = "Expenses:Food"
+
+[[rule]]
+match = "^PAYROLL"
+account = "Income:Salary"
+description = "Salary"
+`
+	const statement = `Account 1234
+Date,Description,Amount
+01/05/2024,SUPER GROCERY #12,"-1,234.50"
+01/15/2024,PAYROLL ACME,$2000.00
+
+01/20/2024,"Book ""Go""",(15.00)
+`
+	c, err := LoadCSVRules(strings.NewReader(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp, err := NewCSVImporter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs, err := imp.Import(strings.NewReader(statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := Fprint(&b, txs); err != nil {
+		t.Fatal(err)
+	}
+	const want = `tx 2024-01-05 "SUPER GROCERY #12"
+Assets:Bank   -1,234.50 USD
+Expenses:Food  1,234.50 USD
+end
+
+tx 2024-01-15 "Salary"
+Assets:Bank    2,000.00 USD
+Income:Salary -2,000.00 USD
+end
+
+tx 2024-01-20 "Book \"Go\""
+Assets:Bank      -15.00 USD
+Expenses:Unknown  15.00 USD
+end
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCSVImporter_inflow_outflow(t *testing.T) {
+	t.Parallel()
+	c := &CSVRules{
+		Account:           "Liabilities:Card",
+		Unit:              "USD",
+		DateColumn:        "1",
+		DescriptionColumn: "2",
+		InflowColumn:      "4",
+		OutflowColumn:     "3",
+		DefaultAccount:    "Expenses:Unknown",
+	}
+	imp, err := NewCSVImporter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs, err := imp.Import(strings.NewReader(`2024-02-01,Coffee,4.5,
+2024-02-03,Payment,,100
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range txs {
+		t := e.(*journal.Transaction)
+		got = append(got, fmt.Sprintf("%s %s %s %s", t.EntryDate, t.Description, t.Splits[0].Amount, t.Splits[1].Amount))
+	}
+	want := []string{
+		"2024-02-01 Coffee -4.5 USD 4.5 USD",
+		"2024-02-03 Payment 100.0 USD -100.0 USD",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("transactions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCSVImporter_errors(t *testing.T) {
+	t.Parallel()
+	c := &CSVRules{
+		Account:        "Assets:Bank",
+		Unit:           "USD",
+		Header:         true,
+		DateColumn:     "Date",
+		AmountColumn:   "Amount",
+		DefaultAccount: "Expenses:Unknown",
+	}
+	imp, err := NewCSVImporter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"Date,Amount\n2024-01-01,abc\n", `record on line 2: invalid amount "abc"`},
+		{"Date,Amount\n01/01/2024,1\n", `record on line 2: parsing time`},
+		{"Day,Amount\n2024-01-01,1\n", `unknown column "Date"`},
+		{"Date,Amount\n2024-01-01\n", `missing column "Amount"`},
+	}
+	for _, c := range cases {
+		_, err := imp.Import(strings.NewReader(c.input))
+		if err == nil {
+			t.Errorf("Import(%q): expected error", c.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Import(%q) = %q; want %q", c.input, err, c.want)
+		}
+	}
+}
+
+func TestNewCSVImporter_errors(t *testing.T) {
+	t.Parallel()
+	base := CSVRules{
+		Account:        "Assets:Bank",
+		Unit:           "USD",
+		DateColumn:     "1",
+		AmountColumn:   "2",
+		DefaultAccount: "Expenses:Unknown",
+	}
+	cases := []struct {
+		desc   string
+		modify func(*CSVRules)
+		want   string
+	}{
+		{"no account", func(c *CSVRules) { c.Account = "" }, "account not set"},
+		{"no amount", func(c *CSVRules) { c.AmountColumn = "" }, "amount columns not set"},
+		{"both amounts", func(c *CSVRules) { c.InflowColumn = "3" }, "both amount and inflow/outflow"},
+		{"bad rule", func(c *CSVRules) { c.Rules = []Rule{{Match: "(", Account: "A:B"}} }, "missing closing )"},
+		{"rule without account", func(c *CSVRules) { c.Rules = []Rule{{Match: "x"}} }, "account not set"},
+	}
+	for _, c := range cases {
+		r := base
+		c.modify(&r)
+		_, err := NewCSVImporter(&r)
+		if err == nil {
+			t.Errorf("%s: expected error", c.desc)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %q; want %q", c.desc, err, c.want)
+		}
+	}
+}
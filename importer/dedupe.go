@@ -0,0 +1,155 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// FITIDKey is the transaction metadata key for the financial
+// institution transaction ID of imported transactions.
+const FITIDKey = "fitid"
+
+// RemoveDuplicates returns the imported entries without the
+// transactions and balance assertions that are already in the journal
+// or earlier in the imported entries, as when importing overlapping
+// statements.  The journal may be nil.
+//
+// An imported transaction is a duplicate if a journal transaction or
+// an earlier imported transaction has the same FITIDKey metadata.
+// Otherwise, it is a duplicate if a journal transaction within the
+// given number of days has a split with the same account and amount
+// as the first split of the imported transaction.  Imported
+// transactions with an ID are only matched this way against journal
+// transactions without an ID, such as hand-entered ones.  Each
+// journal transaction is matched at most once, so repeated identical
+// transactions are kept if the journal has fewer of them.
+//
+// An imported balance assertion is a duplicate if a journal balance
+// assertion or an earlier imported balance assertion has the same
+// date, account, and amounts.
+//
+// Other entries are kept.
+func RemoveDuplicates(entries []journal.Entry, j *journal.Journal, days int) []journal.Entry {
+	ids := make(map[string]*journal.Transaction)
+	// existing contains all transactions and noID contains the
+	// transactions without an ID.
+	var existing, noID []*journal.Transaction
+	var asserts []*journal.BalanceAssert
+	if j != nil {
+		for _, e := range j.Entries {
+			switch e := e.(type) {
+			case *journal.Transaction:
+				if id, ok := e.Metadata[FITIDKey]; ok {
+					ids[id] = e
+				} else {
+					noID = append(noID, e)
+				}
+				existing = append(existing, e)
+			case *journal.BalanceAssert:
+				asserts = append(asserts, e)
+			}
+		}
+	}
+	used := make(map[*journal.Transaction]bool)
+	var result []journal.Entry
+	for _, e := range entries {
+		switch e := e.(type) {
+		case *journal.Transaction:
+			candidates := existing
+			if id, ok := e.Metadata[FITIDKey]; ok {
+				if m, ok := ids[id]; ok {
+					used[m] = true
+					continue
+				}
+				ids[id] = e
+				candidates = noID
+			}
+			if m := findMatch(candidates, used, e, days); m != nil {
+				used[m] = true
+				continue
+			}
+		case *journal.BalanceAssert:
+			if hasAssert(asserts, e) {
+				continue
+			}
+			asserts = append(asserts, e)
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// hasAssert returns whether the balance assertions contain one with
+// the same date, account, and amounts as the given assertion.
+func hasAssert(asserts []*journal.BalanceAssert, b *journal.BalanceAssert) bool {
+	for _, b2 := range asserts {
+		if b2.EntryDate == b.EntryDate && b2.Account == b.Account && b2.Tree == b.Tree && sameBalance(&b2.Declared, &b.Declared) {
+			return true
+		}
+	}
+	return false
+}
+
+// findMatch finds an unused transaction matching an imported
+// transaction by date and amount.
+func findMatch(existing []*journal.Transaction, used map[*journal.Transaction]bool, t *journal.Transaction, days int) *journal.Transaction {
+	if len(t.Splits) == 0 {
+		return nil
+	}
+	s := t.Splits[0]
+	start := t.EntryDate.AddDays(-days)
+	end := t.EntryDate.AddDays(days)
+	for _, t2 := range existing {
+		if used[t2] || !inRange(t2.EntryDate, start, end) {
+			continue
+		}
+		for _, s2 := range t2.Splits {
+			if s2.Account == s.Account && sameAmount(s2.Amount, s.Amount) {
+				return t2
+			}
+		}
+	}
+	return nil
+}
+
+// sameAmount returns whether the amounts are equal, ignoring
+// differences in the unit scale of imported amounts.
+func sameAmount(a, b *journal.Amount) bool {
+	if a.Unit.Symbol != b.Unit.Symbol {
+		return false
+	}
+	return amountRat(a).Cmp(amountRat(b)) == 0
+}
+
+// sameBalance returns whether the balances are equal, ignoring
+// differences in the unit scale of imported amounts.
+func sameBalance(a, b *journal.Balance) bool {
+	as, bs := a.Amounts(), b.Amounts()
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if !sameAmount(as[i], bs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func inRange(d, start, end civil.Date) bool {
+	return !d.Before(start) && !d.After(end)
+}
@@ -0,0 +1,164 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/journal"
+)
+
+func TestRemoveDuplicates(t *testing.T) {
+	t.Parallel()
+	j, err := journal.Compile(&journal.CompileArgs{
+		Inputs: []journal.CompileInput{journal.Bytes("testfile", []byte(`unit USD 100
+tx 2024-01-05 "Groceries"
+meta "fitid" "A1"
+Assets:Bank -12.50 USD
+Expenses:Food
+end
+tx 2024-01-14 "Paycheck"
+Assets:Bank 2000 USD
+Income:Salary
+end
+`))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const statement = `<OFX><STMTRS><CURDEF>USD<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20240105<TRNAMT>-12.50<FITID>A1<NAME>Groceries</STMTTRN>
+<STMTTRN><DTPOSTED>20240106<TRNAMT>-12.50<FITID>A3<NAME>Groceries again</STMTTRN>
+<STMTTRN><DTPOSTED>20240115<TRNAMT>2000.00<NAME>Payroll</STMTTRN>
+<STMTTRN><DTPOSTED>20240115<TRNAMT>2000.00<NAME>Payroll bonus</STMTTRN>
+<STMTTRN><DTPOSTED>20240125<TRNAMT>2000.00<NAME>Payroll late</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1.00<DTASOF>20240131</LEDGERBAL>
+</STMTRS></OFX>
+`
+	imp := &OFXImporter{
+		Account:        "Assets:Bank",
+		DefaultAccount: "Expenses:Unknown",
+		Units:          j.Units,
+	}
+	entries, err := imp.Import(strings.NewReader(statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = RemoveDuplicates(entries, j, 3)
+	var got []string
+	for _, e := range entries {
+		switch e := e.(type) {
+		case *journal.Transaction:
+			got = append(got, e.Description)
+		case *journal.BalanceAssert:
+			got = append(got, "balance")
+		}
+	}
+	want := []string{"Groceries again", "Payroll bonus", "Payroll late", "balance"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRemoveDuplicates_journal_without_ids(t *testing.T) {
+	t.Parallel()
+	j, err := journal.Compile(&journal.CompileArgs{
+		Inputs: []journal.CompileInput{journal.Bytes("testfile", []byte(`unit USD 100
+tx 2024-01-05 "Groceries"
+Assets:Bank -12.50 USD
+Expenses:Food
+end
+tx 2024-01-14 "Paycheck"
+Assets:Bank 2000 USD
+Income:Salary
+end
+`))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const statement = `<OFX><STMTRS><CURDEF>USD<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20240105<TRNAMT>-12.50<FITID>A1<NAME>Groceries</STMTTRN>
+<STMTTRN><DTPOSTED>20240106<TRNAMT>-12.50<FITID>A2<NAME>Groceries again</STMTTRN>
+<STMTTRN><DTPOSTED>20240115<TRNAMT>2000.00<FITID>A3<NAME>Payroll</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></OFX>
+`
+	imp := &OFXImporter{
+		Account:        "Assets:Bank",
+		DefaultAccount: "Expenses:Unknown",
+		Units:          j.Units,
+	}
+	entries, err := imp.Import(strings.NewReader(statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = RemoveDuplicates(entries, j, 3)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.(*journal.Transaction).Description)
+	}
+	want := []string{"Groceries again"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRemoveDuplicates_overlapping_statements(t *testing.T) {
+	t.Parallel()
+	const a = `<OFX><STMTRS><CURDEF>USD<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20240105<TRNAMT>-12.50<FITID>A1<NAME>Groceries</STMTTRN>
+<STMTTRN><DTPOSTED>20240106<TRNAMT>-12.50<FITID>A2<NAME>Groceries again</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>-25.00<DTASOF>20240131</LEDGERBAL>
+</STMTRS></OFX>
+`
+	const b = `<OFX><STMTRS><CURDEF>USD<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20240106<TRNAMT>-12.50<FITID>A2<NAME>Groceries again</STMTTRN>
+<STMTTRN><DTPOSTED>20240107<TRNAMT>-12.50<FITID>A3<NAME>More groceries</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>-25.00<DTASOF>20240131</LEDGERBAL>
+</STMTRS></OFX>
+`
+	imp := &OFXImporter{
+		Account:        "Assets:Bank",
+		DefaultAccount: "Expenses:Unknown",
+	}
+	var entries []journal.Entry
+	for _, s := range []string{a, b} {
+		e, err := imp.Import(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e...)
+	}
+	entries = RemoveDuplicates(entries, nil, 3)
+	var got []string
+	for _, e := range entries {
+		switch e := e.(type) {
+		case *journal.Transaction:
+			got = append(got, e.Description)
+		case *journal.BalanceAssert:
+			got = append(got, "balance")
+		}
+	}
+	want := []string{"Groceries", "Groceries again", "balance", "More groceries"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
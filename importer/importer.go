@@ -0,0 +1,230 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package importer implements importing entries into keeper files
+// from other sources, such as bank statements.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/printer"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// An Importer converts a statement into entries.
+//
+// Imported transactions have the split for the statement account
+// first.
+type Importer interface {
+	Import(r io.Reader) ([]journal.Entry, error)
+}
+
+// Fprint prints entries as keeper entries.
+// Only transactions and balance assertions are supported.
+func Fprint(w io.Writer, entries []journal.Entry) error {
+	f := &ast.File{}
+	for _, e := range entries {
+		switch e := e.(type) {
+		case *journal.Transaction:
+			f.Entries = append(f.Entries, transactionNode(e))
+		case *journal.BalanceAssert:
+			f.Entries = append(f.Entries, balanceNode(e))
+		default:
+			return fmt.Errorf("importer: cannot print entry %T", e)
+		}
+	}
+	return printer.Fprint(w, nil, f)
+}
+
+func transactionNode(t *journal.Transaction) *ast.Transaction {
+	n := &ast.Transaction{
+		Date:        &ast.BasicValue{Kind: token.DATE, Value: t.EntryDate.String()},
+		Description: &ast.BasicValue{Kind: token.STRING, Value: quote(t.Description)},
+		Tags:        tagNodes(t.Tags),
+	}
+	n.Splits = append(n.Splits, metadataNodes(t.Metadata)...)
+	for _, s := range t.Splits {
+		n.Splits = append(n.Splits, &ast.SplitLine{
+			Account: &ast.BasicValue{Kind: token.ACCTNAME, Value: string(s.Account)},
+			Amount:  amountNode(s.Amount),
+			Tags:    tagNodes(s.Tags),
+		})
+		n.Splits = append(n.Splits, metadataNodes(s.Metadata)...)
+	}
+	return n
+}
+
+func balanceNode(b *journal.BalanceAssert) ast.Entry {
+	h := ast.BalanceHeader{
+		Token:   token.BALANCE,
+		Date:    &ast.BasicValue{Kind: token.DATE, Value: b.EntryDate.String()},
+		Account: &ast.BasicValue{Kind: token.ACCTNAME, Value: string(b.Account)},
+	}
+	if b.Tree {
+		h.Token = token.TREEBAL
+	}
+	amts := b.Declared.Amounts()
+	// Listed units declared as zero are not in the balance.
+	for _, u := range b.Listed {
+		if a := b.Declared.Amount(u); a.Zero() {
+			amts = append(amts, a)
+		}
+	}
+	sort.Slice(amts, func(i, j int) bool {
+		return amts[i].Unit.Symbol < amts[j].Unit.Symbol
+	})
+	if len(amts) == 1 {
+		return &ast.SingleBalance{
+			BalanceHeader: h,
+			Amount:        amountNode(amts[0]),
+			Tolerance:     toleranceNode(b, amts[0].Unit),
+		}
+	}
+	n := &ast.MultiBalance{BalanceHeader: h}
+	for _, a := range amts {
+		n.Amounts = append(n.Amounts, &ast.AmountLine{
+			Amount:    amountNode(a),
+			Tolerance: toleranceNode(b, a.Unit),
+		})
+	}
+	return n
+}
+
+// toleranceNode returns the tolerance for a unit in a balance
+// assertion, or nil if there is none.
+func toleranceNode(b *journal.BalanceAssert, u journal.Unit) *ast.BasicValue {
+	t := b.Tolerance.Amount(u)
+	if t.Number.Sign() == 0 {
+		return nil
+	}
+	return &ast.BasicValue{Kind: token.TILDE, Value: "~" + t.Decimal()}
+}
+
+func amountNode(a *journal.Amount) *ast.Amount {
+	return &ast.Amount{
+		Decimal: &ast.BasicValue{Kind: token.DECIMAL, Value: a.Decimal()},
+		Unit:    &ast.BasicValue{Kind: token.USYMBOL, Value: a.Unit.Symbol},
+	}
+}
+
+func tagNodes(tags []string) []*ast.BasicValue {
+	var n []*ast.BasicValue
+	for _, t := range tags {
+		n = append(n, &ast.BasicValue{Kind: token.TAG, Value: "#" + t})
+	}
+	return n
+}
+
+// metadataNodes returns metadata lines sorted by key.
+func metadataNodes(m map[string]string) []ast.LineNode {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var n []ast.LineNode
+	for _, k := range keys {
+		n = append(n, &ast.MetadataLine{
+			Key: &ast.BasicValue{Kind: token.STRING, Value: quote(k)},
+			Val: &ast.BasicValue{Kind: token.STRING, Value: quote(m[k])},
+		})
+	}
+	return n
+}
+
+// quote returns a keeper string literal for the string.
+// Newlines are replaced with spaces since strings cannot span lines.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n', '\r':
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// A decimal is a parsed statement amount.
+type decimal struct {
+	r *big.Rat
+	// prec is the number of decimal places.
+	prec int
+}
+
+// statementUnit returns the unit for amounts in a statement.  Units
+// not found in units are given the smallest scale that can represent
+// all of the amounts.
+func statementUnit(sym string, units map[string]journal.Unit, amounts []decimal) journal.Unit {
+	if u, ok := units[sym]; ok {
+		return u
+	}
+	prec := 0
+	for _, d := range amounts {
+		prec = max(prec, d.prec)
+	}
+	u := journal.Unit{Symbol: sym, Scale: 1}
+	for i := 0; i < prec; i++ {
+		u.Scale *= 10
+	}
+	return u
+}
+
+// newAmount converts a decimal to an amount.
+func newAmount(d decimal, u journal.Unit) (*journal.Amount, error) {
+	var r big.Rat
+	r.Mul(d.r, new(big.Rat).SetInt(new(big.Int).SetUint64(u.Scale)))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("amount %s is too precise for %s", d.r.FloatString(d.prec), u)
+	}
+	a := &journal.Amount{Unit: u}
+	a.Number.Set(r.Num())
+	return a, nil
+}
+
+// newTransaction returns a transaction moving an amount between the
+// statement account and a counter account.
+func newTransaction(d decimal, u journal.Unit, account, counter journal.Account) (*journal.Transaction, error) {
+	a, err := newAmount(d, u)
+	if err != nil {
+		return nil, err
+	}
+	neg := &journal.Amount{Unit: u}
+	neg.Number.Neg(&a.Number)
+	return &journal.Transaction{
+		Splits: []journal.Split{
+			{Account: account, Amount: a},
+			{Account: counter, Amount: neg},
+		},
+	}, nil
+}
+
+// amountRat returns the value of an amount as a rational number.
+func amountRat(a *journal.Amount) *big.Rat {
+	return new(big.Rat).SetFrac(&a.Number, new(big.Int).SetUint64(a.Unit.Scale))
+}
@@ -0,0 +1,378 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// An OFXStatement is a bank or credit card statement in an OFX file.
+type OFXStatement struct {
+	Currency     string
+	AccountID    string
+	Transactions []OFXTransaction
+	// LedgerBalance is the statement balance, or nil if missing.
+	LedgerBalance *OFXBalance
+}
+
+// An OFXTransaction is a transaction in an OFX statement.
+type OFXTransaction struct {
+	// FITID is the financial institution transaction ID.
+	FITID  string
+	Type   string
+	Posted civil.Date
+	Amount string
+	Name   string
+	Memo   string
+}
+
+// An OFXBalance is a balance in an OFX statement.
+type OFXBalance struct {
+	Amount string
+	AsOf   civil.Date
+}
+
+// ParseOFX parses the statements in an OFX or QFX file.
+// Both SGML (OFX 1) and XML (OFX 2) files are supported.
+func ParseOFX(r io.Reader) ([]OFXStatement, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse ofx: %s", err)
+	}
+	root, err := parseOFXTree(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("parse ofx: %s", err)
+	}
+	var stmts []OFXStatement
+	var errs []error
+	root.walk(func(e *ofxElement) {
+		if e.name != "STMTRS" && e.name != "CCSTMTRS" {
+			return
+		}
+		s, err := newOFXStatement(e)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		stmts = append(stmts, s)
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("parse ofx: %w", err)
+	}
+	return stmts, nil
+}
+
+func newOFXStatement(e *ofxElement) (OFXStatement, error) {
+	s := OFXStatement{Currency: e.text("CURDEF")}
+	for _, n := range []string{"BANKACCTFROM", "CCACCTFROM"} {
+		if a := e.child(n); a != nil {
+			s.AccountID = a.text("ACCTID")
+		}
+	}
+	if l := e.child("BANKTRANLIST"); l != nil {
+		for _, c := range l.children {
+			if c.name != "STMTTRN" {
+				continue
+			}
+			d, err := parseOFXDate(c.text("DTPOSTED"))
+			if err != nil {
+				return s, err
+			}
+			s.Transactions = append(s.Transactions, OFXTransaction{
+				FITID:  c.text("FITID"),
+				Type:   c.text("TRNTYPE"),
+				Posted: d,
+				Amount: c.text("TRNAMT"),
+				Name:   c.text("NAME"),
+				Memo:   c.text("MEMO"),
+			})
+		}
+	}
+	if b := e.child("LEDGERBAL"); b != nil {
+		d, err := parseOFXDate(b.text("DTASOF"))
+		if err != nil {
+			return s, err
+		}
+		s.LedgerBalance = &OFXBalance{
+			Amount: b.text("BALAMT"),
+			AsOf:   d,
+		}
+	}
+	return s, nil
+}
+
+// parseOFXDate parses the date part of an OFX datetime, e.g.,
+// 20240105120000.000[-5:EST].
+func parseOFXDate(s string) (civil.Date, error) {
+	if len(s) < 8 {
+		return civil.Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	t, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return civil.Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	return civil.DateOf(t), nil
+}
+
+// An ofxElement is an element in an OFX file.  Elements either have
+// a value or children.
+type ofxElement struct {
+	name     string
+	value    string
+	children []*ofxElement
+}
+
+func (e *ofxElement) child(name string) *ofxElement {
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// text returns the value of a child element, or the empty string.
+func (e *ofxElement) text(name string) string {
+	if c := e.child(name); c != nil {
+		return c.value
+	}
+	return ""
+}
+
+// walk calls f for the element and its descendants in order.
+func (e *ofxElement) walk(f func(*ofxElement)) {
+	f(e)
+	for _, c := range e.children {
+		c.walk(f)
+	}
+}
+
+type ofxTokenKind int
+
+const (
+	ofxOpen ofxTokenKind = iota
+	ofxClose
+	ofxText
+)
+
+type ofxToken struct {
+	kind ofxTokenKind
+	val  string
+}
+
+// parseOFXTree parses the elements of an OFX file, returning a root
+// element containing the top level elements.  In SGML files, elements
+// with values do not have closing tags.
+func parseOFXTree(src string) (*ofxElement, error) {
+	toks, err := lexOFX(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &ofxParser{toks: toks}
+	root := &ofxElement{}
+	for p.i < len(p.toks) {
+		t := p.toks[p.i]
+		p.i++
+		if t.kind != ofxOpen {
+			return nil, fmt.Errorf("unexpected %q outside of element", t.val)
+		}
+		e, err := p.element(t.val)
+		if err != nil {
+			return nil, err
+		}
+		root.children = append(root.children, e)
+	}
+	return root, nil
+}
+
+type ofxParser struct {
+	toks []ofxToken
+	i    int
+}
+
+// element parses an element after its opening tag.
+func (p *ofxParser) element(name string) (*ofxElement, error) {
+	e := &ofxElement{name: name}
+	if p.i < len(p.toks) && p.toks[p.i].kind == ofxText {
+		e.value = p.toks[p.i].val
+		p.i++
+		if p.i < len(p.toks) && p.toks[p.i].kind == ofxClose && p.toks[p.i].val == name {
+			p.i++
+		}
+		return e, nil
+	}
+	for p.i < len(p.toks) {
+		t := p.toks[p.i]
+		p.i++
+		switch t.kind {
+		case ofxOpen:
+			c, err := p.element(t.val)
+			if err != nil {
+				return nil, err
+			}
+			e.children = append(e.children, c)
+		case ofxClose:
+			if t.val != name {
+				return nil, fmt.Errorf("element %s closed by %s", name, t.val)
+			}
+			return e, nil
+		case ofxText:
+			return nil, fmt.Errorf("unexpected text %q in element %s", t.val, name)
+		}
+	}
+	return nil, fmt.Errorf("element %s not closed", name)
+}
+
+// lexOFX splits an OFX file into tags and text, skipping the header,
+// processing instructions and comments.
+func lexOFX(src string) ([]ofxToken, error) {
+	i := strings.Index(strings.ToUpper(src), "<OFX>")
+	if i < 0 {
+		return nil, errors.New("missing OFX element")
+	}
+	src = src[i:]
+	var toks []ofxToken
+	for len(src) > 0 {
+		if src[0] != '<' {
+			j := strings.IndexByte(src, '<')
+			if j < 0 {
+				j = len(src)
+			}
+			if s := strings.TrimSpace(src[:j]); s != "" {
+				toks = append(toks, ofxToken{kind: ofxText, val: html.UnescapeString(s)})
+			}
+			src = src[j:]
+			continue
+		}
+		if strings.HasPrefix(src, "<!--") {
+			j := strings.Index(src, "-->")
+			if j < 0 {
+				return nil, errors.New("unclosed comment")
+			}
+			src = src[j+3:]
+			continue
+		}
+		j := strings.IndexByte(src, '>')
+		if j < 0 {
+			return nil, errors.New("unclosed tag")
+		}
+		tag := src[1:j]
+		src = src[j+1:]
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"):
+		case strings.HasPrefix(tag, "/"):
+			toks = append(toks, ofxToken{kind: ofxClose, val: strings.ToUpper(strings.TrimSpace(tag[1:]))})
+		default:
+			toks = append(toks, ofxToken{kind: ofxOpen, val: strings.ToUpper(strings.TrimSpace(tag))})
+		}
+	}
+	return toks, nil
+}
+
+// An OFXImporter imports OFX and QFX statements.
+//
+// Imported transactions have the FITID in the FITIDKey metadata, and
+// the ledger balance is imported as a balance assertion.
+type OFXImporter struct {
+	// Account is the account that the statements are for.
+	Account journal.Account
+	// DefaultAccount is the counter account for transactions.
+	DefaultAccount journal.Account
+	// Units are the declared units, used for the scale of
+	// amounts.  If the statement currency is not declared, the
+	// smallest scale that fits the amounts in the statement is
+	// used.
+	Units map[string]journal.Unit
+}
+
+var _ Importer = &OFXImporter{}
+
+// Import imports the statements in an OFX or QFX file.
+func (i *OFXImporter) Import(r io.Reader) ([]journal.Entry, error) {
+	stmts, err := ParseOFX(r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []journal.Entry
+	for _, s := range stmts {
+		e, err := i.importStatement(s)
+		if err != nil {
+			return nil, fmt.Errorf("import ofx: %s", err)
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+func (i *OFXImporter) importStatement(s OFXStatement) ([]journal.Entry, error) {
+	if s.Currency == "" {
+		return nil, fmt.Errorf("statement for account %q missing currency", s.AccountID)
+	}
+	amounts := make([]decimal, len(s.Transactions))
+	for k, t := range s.Transactions {
+		d, err := parseAmount(t.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %q: %s", t.FITID, err)
+		}
+		amounts[k] = d
+	}
+	var bal decimal
+	if s.LedgerBalance != nil {
+		var err error
+		bal, err = parseAmount(s.LedgerBalance.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("ledger balance: %s", err)
+		}
+	}
+	u := statementUnit(s.Currency, i.Units, append(amounts, bal))
+	var entries []journal.Entry
+	for k, ot := range s.Transactions {
+		t, err := newTransaction(amounts[k], u, i.Account, i.DefaultAccount)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %q: %s", ot.FITID, err)
+		}
+		t.EntryDate = ot.Posted
+		t.Description = ot.Name
+		if t.Description == "" {
+			t.Description = ot.Memo
+		}
+		if ot.FITID != "" {
+			t.Metadata = map[string]string{FITIDKey: ot.FITID}
+		}
+		entries = append(entries, t)
+	}
+	if s.LedgerBalance != nil {
+		a, err := newAmount(bal, u)
+		if err != nil {
+			return nil, fmt.Errorf("ledger balance: %s", err)
+		}
+		b := &journal.BalanceAssert{
+			EntryDate: s.LedgerBalance.AsOf,
+			Account:   i.Account,
+			Listed:    []journal.Unit{u},
+		}
+		b.Declared.Add(a)
+		entries = append(entries, b)
+	}
+	return entries, nil
+}
@@ -0,0 +1,194 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>1<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>123<ACCTID>9876<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101<DTEND>20240131
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240105120000[-5:EST]<TRNAMT>-12.50<FITID>A1<NAME>GROCERY &amp; CO</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240115<TRNAMT>2000.00<FITID>A2<MEMO>Payroll</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1987.50<DTASOF>20240131</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>JPY</CURDEF>
+        <CCACCTFROM><ACCTID>4444</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240210</DTPOSTED>
+            <TRNAMT>-900</TRNAMT>
+            <FITID>B1</FITID>
+            <NAME>Ramen</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		desc  string
+		input string
+		want  []OFXStatement
+	}{
+		{
+			desc:  "sgml",
+			input: testSGML,
+			want: []OFXStatement{{
+				Currency:  "USD",
+				AccountID: "9876",
+				Transactions: []OFXTransaction{
+					{FITID: "A1", Type: "DEBIT", Posted: civil.Date{2024, 1, 5}, Amount: "-12.50", Name: "GROCERY & CO"},
+					{FITID: "A2", Type: "CREDIT", Posted: civil.Date{2024, 1, 15}, Amount: "2000.00", Memo: "Payroll"},
+				},
+				LedgerBalance: &OFXBalance{Amount: "1987.50", AsOf: civil.Date{2024, 1, 31}},
+			}},
+		},
+		{
+			desc:  "xml",
+			input: testXML,
+			want: []OFXStatement{{
+				Currency:  "JPY",
+				AccountID: "4444",
+				Transactions: []OFXTransaction{
+					{FITID: "B1", Type: "DEBIT", Posted: civil.Date{2024, 2, 10}, Amount: "-900", Name: "Ramen"},
+				},
+			}},
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseOFX(strings.NewReader(c.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("statements mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseOFX_errors(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"<FOO></FOO>", "missing OFX element"},
+		{"<OFX><STMTRS><CURDEF>USD", "element STMTRS not closed"},
+		{"<OFX><STMTRS></OFX>", "element STMTRS closed by OFX"},
+		{"<OFX><STMTRS><LEDGERBAL><DTASOF>2024</LEDGERBAL></STMTRS></OFX>", `invalid date "2024"`},
+	}
+	for _, c := range cases {
+		_, err := ParseOFX(strings.NewReader(c.input))
+		if err == nil {
+			t.Errorf("ParseOFX(%q): expected error", c.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("ParseOFX(%q) = %q; want %q", c.input, err, c.want)
+		}
+	}
+}
+
+func TestOFXImporter(t *testing.T) {
+	t.Parallel()
+	imp := &OFXImporter{
+		Account:        "Assets:Bank",
+		DefaultAccount: "Expenses:Unknown",
+	}
+	entries, err := imp.Import(strings.NewReader(testSGML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := Fprint(&b, entries); err != nil {
+		t.Fatal(err)
+	}
+	const want = `tx 2024-01-05 "GROCERY & CO"
+meta "fitid" "A1"
+Assets:Bank      -12.50 USD
+Expenses:Unknown  12.50 USD
+end
+
+tx 2024-01-15 "Payroll"
+meta "fitid" "A2"
+Assets:Bank       2,000.00 USD
+Expenses:Unknown -2,000.00 USD
+end
+
+balance 2024-01-31 Assets:Bank 1,987.50 USD
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOFXImporter_zero_balance(t *testing.T) {
+	t.Parallel()
+	imp := &OFXImporter{
+		Account:        "Assets:Bank",
+		DefaultAccount: "Expenses:Unknown",
+	}
+	const input = `<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<LEDGERBAL><BALAMT>0.00<DTASOF>20240131</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`
+	entries, err := imp.Import(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := Fprint(&b, entries); err != nil {
+		t.Fatal(err)
+	}
+	const want = "balance 2024-01-31 Assets:Bank 0.00 USD\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
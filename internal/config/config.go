@@ -13,12 +13,28 @@
 // limitations under the License.
 
 // Package config implements configuration for keeper.
+//
+// Example config file:
+//
+//	[account]
+//	base_unit = "JPY"
+//	cash_prefix = ["資産:現金", "資産:銀行"]
+//	assets_prefix = ["資産"]
+//	liabilities_prefix = ["負債"]
+//	income_prefix = ["収益"]
+//	expenses_prefix = ["費用"]
+//	type_key = "type"
+//
+//	[period]
+//	fiscal_year_start = 4
 package config
 
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pelletier/go-toml"
 	"go.felesatra.moe/keeper/journal"
@@ -26,11 +42,37 @@ import (
 
 type Config struct {
 	Account `toml:"account"`
+	Period  Period `toml:"period"`
 }
 
 type Account struct {
-	// Prefix for matching cash accounts.
+	// Prefix for matching cash accounts.  Unlike the category
+	// prefixes, these are matched as string prefixes of account
+	// names.
 	CashPrefix []string `toml:"cash_prefix"`
+
+	// Prefixes for matching accounts in each category.  A prefix
+	// matches the account with that name and its sub-accounts.
+	// If no prefixes are set for a category, accounts under the
+	// English root account (e.g., Income) are matched.
+	AssetsPrefix      []string `toml:"assets_prefix"`
+	LiabilitiesPrefix []string `toml:"liabilities_prefix"`
+	EquityPrefix      []string `toml:"equity_prefix"`
+	IncomePrefix      []string `toml:"income_prefix"`
+	ExpensesPrefix    []string `toml:"expenses_prefix"`
+	TradingPrefix     []string `toml:"trading_prefix"`
+
+	// TypeKey is an account metadata key whose value is the
+	// category of the account (e.g., "income").  Sub-accounts
+	// inherit the category of their parent.  Categories set with
+	// metadata take precedence over prefixes.
+	TypeKey string `toml:"type_key"`
+
+	// Base is the symbol of the base unit.  The default is USD.
+	Base string `toml:"base_unit"`
+
+	// accounts is used for looking up account metadata.
+	accounts journal.AccountMap
 }
 
 func Load(c *Config, r io.Reader) error {
@@ -38,31 +80,158 @@ func Load(c *Config, r io.Reader) error {
 	if err := d.Decode(c); err != nil {
 		return fmt.Errorf("load account classifier: %s", err)
 	}
+	if m := c.Period.FiscalYearStart; m < 0 || m > 12 {
+		return fmt.Errorf("load config: invalid fiscal year start %d", m)
+	}
+	for _, p := range c.categoryPrefixes() {
+		for _, s := range p.prefixes {
+			if s == "" {
+				return fmt.Errorf("load account classifier: empty %s prefix", p.cat)
+			}
+		}
+	}
+	return nil
+}
+
+// A Category is an account category.
+type Category int
+
+const (
+	Uncategorized Category = iota
+	Assets
+	Liabilities
+	Equity
+	Income
+	Expenses
+	Trading
+)
+
+var categoryNames = [...]string{
+	Uncategorized: "uncategorized",
+	Assets:        "assets",
+	Liabilities:   "liabilities",
+	Equity:        "equity",
+	Income:        "income",
+	Expenses:      "expenses",
+	Trading:       "trading",
+}
+
+func (c Category) String() string {
+	if c < 0 || int(c) >= len(categoryNames) {
+		return fmt.Sprintf("Category(%d)", int(c))
+	}
+	return categoryNames[c]
+}
+
+// parseCategory parses a category name, ignoring case.
+func parseCategory(s string) (Category, bool) {
+	for i, n := range categoryNames {
+		if Category(i) != Uncategorized && strings.EqualFold(s, n) {
+			return Category(i), true
+		}
+	}
+	return Uncategorized, false
+}
+
+// SetAccounts sets the account information used for categorizing
+// accounts with TypeKey metadata.  This is usually the Accounts of a
+// compiled journal.  An error is returned if an account has TypeKey
+// metadata that is not a category name.
+func (c *Account) SetAccounts(m journal.AccountMap) error {
+	if c.TypeKey != "" {
+		var bad []string
+		for a, ai := range m {
+			if v, ok := ai.Metadata[c.TypeKey]; ok {
+				if _, ok := parseCategory(v); !ok {
+					bad = append(bad, fmt.Sprintf("%s has unknown %s %q", a, c.TypeKey, v))
+				}
+			}
+		}
+		if len(bad) > 0 {
+			sort.Strings(bad)
+			return fmt.Errorf("set accounts: %s", strings.Join(bad, "; "))
+		}
+	}
+	c.accounts = m
 	return nil
 }
 
-func (*Account) IsIncome(a journal.Account) bool {
-	return a.Under("Income")
+// Category returns the category of an account.
+func (c *Account) Category(a journal.Account) Category {
+	if cat, ok := c.metadataCategory(a); ok {
+		return cat
+	}
+	for _, p := range c.categoryPrefixes() {
+		if len(p.prefixes) == 0 {
+			if a.Under(p.root) {
+				return p.cat
+			}
+			continue
+		}
+		if hasPrefix(a, p.prefixes) {
+			return p.cat
+		}
+	}
+	return Uncategorized
+}
+
+// metadataCategory returns the category of an account set by
+// metadata on the account or its closest parent.
+func (c *Account) metadataCategory(a journal.Account) (Category, bool) {
+	if c.TypeKey == "" {
+		return Uncategorized, false
+	}
+	for ; a != ""; a = a.Parent() {
+		ai, ok := c.accounts[a]
+		if !ok {
+			continue
+		}
+		if v, ok := ai.Metadata[c.TypeKey]; ok {
+			return parseCategory(v)
+		}
+	}
+	return Uncategorized, false
+}
+
+type categoryPrefix struct {
+	cat      Category
+	root     journal.Account
+	prefixes []string
+}
+
+func (c *Account) categoryPrefixes() []categoryPrefix {
+	return []categoryPrefix{
+		{Assets, "Assets", c.AssetsPrefix},
+		{Liabilities, "Liabilities", c.LiabilitiesPrefix},
+		{Equity, "Equity", c.EquityPrefix},
+		{Income, "Income", c.IncomePrefix},
+		{Expenses, "Expenses", c.ExpensesPrefix},
+		{Trading, "Trading", c.TradingPrefix},
+	}
+}
+
+func (c *Account) IsIncome(a journal.Account) bool {
+	return c.Category(a) == Income
 }
 
-func (*Account) IsExpenses(a journal.Account) bool {
-	return a.Under("Expenses")
+func (c *Account) IsExpenses(a journal.Account) bool {
+	return c.Category(a) == Expenses
 }
 
-func (*Account) IsAssets(a journal.Account) bool {
-	return a.Under("Assets")
+func (c *Account) IsAssets(a journal.Account) bool {
+	return c.Category(a) == Assets
 }
 
-func (*Account) IsLiabilities(a journal.Account) bool {
-	return a.Under("Liabilities")
+func (c *Account) IsLiabilities(a journal.Account) bool {
+	return c.Category(a) == Liabilities
 }
 
-func (*Account) IsEquity(a journal.Account) bool {
-	return a.Under("Equity")
+func (c *Account) IsEquity(a journal.Account) bool {
+	return c.Category(a) == Equity
 }
 
-func (*Account) IsTrading(a journal.Account) bool {
-	return a.Under("Trading")
+func (c *Account) IsTrading(a journal.Account) bool {
+	return c.Category(a) == Trading
 }
 
 func (c *Account) IsCash(a journal.Account) bool {
@@ -74,6 +243,43 @@ func (c *Account) IsCash(a journal.Account) bool {
 	return false
 }
 
+// hasPrefix returns whether the account is one of the prefix
+// accounts or under one of them.
+func hasPrefix(a journal.Account, prefixes []string) bool {
+	for _, p := range prefixes {
+		if p := journal.Account(p); a == p || a.Under(p) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Account) BaseUnitSymbol() string {
+	if c.Base != "" {
+		return c.Base
+	}
 	return "USD"
 }
+
+// BaseUnit returns the base unit as declared in the journal.
+func (c *Account) BaseUnit(j *journal.Journal) journal.Unit {
+	sym := c.BaseUnitSymbol()
+	if u, ok := j.Units[sym]; ok {
+		return u
+	}
+	return journal.Unit{Symbol: sym, Scale: 100}
+}
+
+type Period struct {
+	// FiscalYearStart is the month (1-12) that fiscal years
+	// start in.  The default is January.
+	FiscalYearStart int `toml:"fiscal_year_start"`
+}
+
+// FiscalStart returns the month that fiscal years start in.
+func (p *Period) FiscalStart() time.Month {
+	if p.FiscalYearStart == 0 {
+		return time.January
+	}
+	return time.Month(p.FiscalYearStart)
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2020  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"go.felesatra.moe/keeper/journal"
+)
+
+func TestAccount_Category(t *testing.T) {
+	t.Parallel()
+	const src = `
+[account]
+base_unit = "EUR"
+assets_prefix = ["Aktiva"]
+income_prefix = ["Erträge", "Zinsen"]
+type_key = "type"
+`
+	c := &Config{}
+	if err := Load(c, strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	err := c.SetAccounts(journal.AccountMap{
+		"Sonstiges":       {Metadata: map[string]string{"type": "Expenses"}},
+		"Aktiva:Darlehen": {Metadata: map[string]string{"type": "liabilities"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		a    journal.Account
+		want Category
+	}{
+		{"Aktiva:Bank", Assets},
+		{"Aktiva:Darlehen:Auto", Liabilities},
+		{"Erträge:Gehalt", Income},
+		{"Zinsen:Bank", Income},
+		{"Zinsen", Income},
+		{"ZinsenAlt:Bank", Uncategorized},
+		{"Sonstiges:Porto", Expenses},
+		{"Equity:Opening", Equity},
+		{"Expenses:Food", Expenses},
+		{"Assets:Cash", Uncategorized},
+		{"Income:Salary", Uncategorized},
+	}
+	for _, c2 := range cases {
+		if got := c.Category(c2.a); got != c2.want {
+			t.Errorf("Category(%q) = %v; want %v", c2.a, got, c2.want)
+		}
+	}
+	if got := c.BaseUnitSymbol(); got != "EUR" {
+		t.Errorf("BaseUnitSymbol() = %q; want %q", got, "EUR")
+	}
+}
+
+func TestAccount_Category_default(t *testing.T) {
+	t.Parallel()
+	c := &Config{}
+	cases := []struct {
+		a    journal.Account
+		want Category
+	}{
+		{"Assets:Cash", Assets},
+		{"Liabilities:Card", Liabilities},
+		{"Equity:Opening", Equity},
+		{"Income:Salary", Income},
+		{"Expenses:Food", Expenses},
+		{"Trading:USD", Trading},
+		{"Other:Foo", Uncategorized},
+	}
+	for _, c2 := range cases {
+		if got := c.Category(c2.a); got != c2.want {
+			t.Errorf("Category(%q) = %v; want %v", c2.a, got, c2.want)
+		}
+	}
+	if got := c.BaseUnitSymbol(); got != "USD" {
+		t.Errorf("BaseUnitSymbol() = %q; want %q", got, "USD")
+	}
+}
+
+func TestAccount_SetAccounts_unknown_type(t *testing.T) {
+	t.Parallel()
+	c := &Config{Account: Account{TypeKey: "type"}}
+	err := c.SetAccounts(journal.AccountMap{
+		"Sonstiges": {Metadata: map[string]string{"type": "expense"}},
+	})
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestAccount_IsCash(t *testing.T) {
+	t.Parallel()
+	c := &Config{Account: Account{CashPrefix: []string{"Assets:Bank:", "Assets:Cash"}}}
+	cases := []struct {
+		a    journal.Account
+		want bool
+	}{
+		{"Assets:Bank:Checking", true},
+		{"Assets:Bank", false},
+		{"Assets:Cash", true},
+		{"Assets:CashBox", true},
+		{"Assets:Stocks", false},
+	}
+	for _, c2 := range cases {
+		if got := c.IsCash(c2.a); got != c2.want {
+			t.Errorf("IsCash(%q) = %v; want %v", c2.a, got, c2.want)
+		}
+	}
+}
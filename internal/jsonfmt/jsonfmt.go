@@ -0,0 +1,117 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonfmt defines the JSON encoding of journal data shared by
+// the machine-readable outputs of keeper, so they stay consistent.
+package jsonfmt
+
+import (
+	"go.felesatra.moe/keeper/journal"
+)
+
+// An Amount is an amount with the number as an exact decimal string.
+type Amount struct {
+	Number string `json:"number"`
+	Unit   string `json:"unit"`
+	Scale  uint64 `json:"scale"`
+}
+
+// NewAmount returns the JSON encoding of an amount, or nil if the
+// amount is nil.
+func NewAmount(a *journal.Amount) *Amount {
+	if a == nil {
+		return nil
+	}
+	return &Amount{
+		Number: a.Decimal(),
+		Unit:   a.Unit.Symbol,
+		Scale:  a.Unit.Scale,
+	}
+}
+
+// Balance returns the amounts of a balance.  The result is not nil,
+// so empty balances are encoded as empty arrays.
+func Balance(b *journal.Balance) []Amount {
+	amts := []Amount{}
+	for _, a := range b.Amounts() {
+		amts = append(amts, *NewAmount(a))
+	}
+	return amts
+}
+
+// A Diagnostic is a diagnostic.  Balance assertion errors include the
+// declared, actual and difference amounts per unit.
+type Diagnostic struct {
+	Severity string        `json:"severity"`
+	Code     string        `json:"code"`
+	Message  string        `json:"message"`
+	File     string        `json:"file,omitempty"`
+	Line     int           `json:"line,omitempty"`
+	Column   int           `json:"column,omitempty"`
+	Account  string        `json:"account,omitempty"`
+	Balance  *BalanceError `json:"balance,omitempty"`
+	Related  []Position    `json:"related,omitempty"`
+}
+
+// A BalanceError contains the amounts of a failed balance assertion.
+type BalanceError struct {
+	Declared []Amount `json:"declared"`
+	Actual   []Amount `json:"actual"`
+	Diff     []Amount `json:"diff"`
+}
+
+// A Position is a position in a file.
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// NewDiagnostic returns the JSON encoding of a diagnostic.
+func NewDiagnostic(d *journal.Diagnostic) Diagnostic {
+	jd := Diagnostic{
+		Severity: d.Severity.String(),
+		Code:     d.Code,
+		Message:  d.Msg,
+		File:     d.Pos.Filename,
+		Line:     d.Pos.Line,
+		Column:   d.Pos.Column,
+		Account:  string(d.Account),
+	}
+	if b := d.Balance; b != nil {
+		jd.Balance = &BalanceError{
+			Declared: Balance(&b.Declared),
+			Actual:   Balance(&b.Actual),
+			Diff:     Balance(&b.Diff),
+		}
+	}
+	for _, p := range d.Related {
+		jd.Related = append(jd.Related, Position{
+			File:   p.Filename,
+			Line:   p.Line,
+			Column: p.Column,
+		})
+	}
+	return jd
+}
+
+// Diagnostics returns the JSON encoding of diagnostics.  The result
+// is not nil, so no diagnostics are encoded as an empty array.
+func Diagnostics(diags journal.DiagnosticList) []Diagnostic {
+	jd := []Diagnostic{}
+	for _, d := range diags {
+		jd = append(jd, NewDiagnostic(d))
+	}
+	return jd
+}
@@ -0,0 +1,732 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lsp implements a Language Server Protocol server for keeper
+// files.
+//
+// The server provides diagnostics from compiling the journal,
+// completion of accounts and units, going to the declarations of
+// accounts and units, hovering over accounts for their running
+// balance, and formatting.
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/kpr/parser"
+	"go.felesatra.moe/keeper/kpr/printer"
+	"go.felesatra.moe/keeper/kpr/scanner"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// Serve serves the Language Server Protocol over r and w until the
+// client sends the exit notification or r is closed.
+//
+// If roots are given, the journal is compiled from the root files
+// and diagnostics are published for all files in the journal.
+// Otherwise, each open document is compiled by itself.  The text of
+// open documents is used for root files and documents, but included
+// files are read from disk, so diagnostics for changes to included
+// files are updated when they are saved.  Changes are checked after
+// the client stops sending changes for a short delay.
+func Serve(r io.Reader, w io.Writer, roots []string) error {
+	s := &server{
+		conn:      newConn(r, w),
+		docs:      make(map[string]*document),
+		published: make(map[string]map[string]bool),
+		cache:     journal.NewCache(),
+		pending:   make(map[string]bool),
+	}
+	for _, p := range roots {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			return fmt.Errorf("lsp serve: %s", err)
+		}
+		s.roots = append(s.roots, p)
+	}
+	if err := s.run(); err != nil {
+		return fmt.Errorf("lsp serve: %s", err)
+	}
+	return nil
+}
+
+type server struct {
+	conn  *conn
+	roots []string
+	// Open documents, by path.
+	docs map[string]*document
+	// Last journal compiled successfully from the roots.
+	journal *journal.Journal
+	// Files with published diagnostics, by the root or document
+	// path that was compiled.
+	published map[string]map[string]bool
+	// Cache for compiling the roots.
+	cache *journal.Cache
+	// Paths of documents with changes that have not been checked.
+	pending map[string]bool
+	// Timer for checking pending changes, or nil.
+	timer *time.Timer
+}
+
+// A document is an open text document.
+type document struct {
+	path string
+	text []byte
+	// Last journal compiled successfully from the document, if
+	// the server has no roots.
+	journal *journal.Journal
+	// Cache for compiling the document, if the server has no
+	// roots.
+	cache *journal.Cache
+}
+
+// checkDelay is how long to wait after a change before checking it.
+const checkDelay = 200 * time.Millisecond
+
+// A readResult is the result of reading a message.
+type readResult struct {
+	m   *message
+	err error
+}
+
+func (s *server) run() error {
+	msgs := make(chan readResult)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			m, err := s.conn.read()
+			select {
+			case msgs <- readResult{m, err}:
+			case <-done:
+				return
+			}
+			var rerr *responseError
+			if err != nil && !errors.As(err, &rerr) {
+				return
+			}
+		}
+	}()
+	for {
+		var r readResult
+		select {
+		case r = <-msgs:
+		case <-s.timerC():
+			if err := s.flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		m, err := r.m, r.err
+		var rerr *responseError
+		switch {
+		case errors.As(err, &rerr):
+			if err := s.conn.reply(nil, nil, rerr); err != nil {
+				return err
+			}
+			continue
+		case err == io.EOF:
+			return s.flush()
+		case err != nil:
+			return err
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+		if m.isRequest() {
+			// Requests use the journal, so check pending
+			// changes first.
+			if err := s.flush(); err != nil {
+				return err
+			}
+		}
+		result, rerr := s.handle(m)
+		if !m.isRequest() {
+			continue
+		}
+		if err := s.conn.reply(m.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a request or notification.
+func (s *server) handle(m *message) (interface{}, *responseError) {
+	switch m.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           syncFull,
+				"completionProvider":         map[string]interface{}{},
+				"definitionProvider":         true,
+				"hoverProvider":              true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "keeper"},
+		}, nil
+	case "initialized", "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		path := uriPath(p.TextDocument.URI)
+		s.docs[path] = &document{
+			path:  path,
+			text:  []byte(p.TextDocument.Text),
+			cache: journal.NewCache(),
+		}
+		return nil, s.check(path)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.doc(p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			d.text = []byte(p.ContentChanges[n-1].Text)
+		}
+		s.schedule(d.path)
+		return nil, nil
+	case "textDocument/didSave":
+		var p textDocumentParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.doc(p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.check(d.path)
+	case "textDocument/didClose":
+		var p textDocumentParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		path := uriPath(p.TextDocument.URI)
+		delete(s.docs, path)
+		delete(s.pending, path)
+		if len(s.roots) > 0 {
+			return nil, s.check(path)
+		}
+		return nil, s.publish(path, nil)
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.doc(p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.completion(d), nil
+	case "textDocument/definition":
+		var p textDocumentPositionParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.doc(p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.definition(d, p.Position), nil
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.doc(p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.hover(d, p.Position), nil
+	case "textDocument/formatting":
+		var p textDocumentParams
+		if err := decode(m, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.doc(p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return format(d), nil
+	default:
+		return nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("method %s not found", m.Method),
+		}
+	}
+}
+
+func decode(m *message, v interface{}) *responseError {
+	if err := json.Unmarshal(m.Params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// doc returns the open document for an identifier.
+func (s *server) doc(id textDocumentIdentifier) (*document, *responseError) {
+	d, ok := s.docs[uriPath(id.URI)]
+	if !ok {
+		return nil, &responseError{
+			Code:    codeInvalidParams,
+			Message: fmt.Sprintf("document %s is not open", id.URI),
+		}
+	}
+	return d, nil
+}
+
+// journalFor returns the last journal compiled successfully for a
+// document, or nil.
+func (s *server) journalFor(d *document) *journal.Journal {
+	if len(s.roots) > 0 {
+		return s.journal
+	}
+	return d.journal
+}
+
+// An input is a compile input for a file that uses the text of the
+// document if the file is open.
+type input struct {
+	s    *server
+	path string
+}
+
+func (i input) Filename() string {
+	return i.path
+}
+
+func (i input) Src() ([]byte, error) {
+	if d, ok := i.s.docs[i.path]; ok {
+		return d.text, nil
+	}
+	return os.ReadFile(i.path)
+}
+
+// schedule schedules checking the document at the path after
+// checkDelay, postponing the pending checks.
+func (s *server) schedule(path string) {
+	s.pending[path] = true
+	if s.timer == nil {
+		s.timer = time.NewTimer(checkDelay)
+		return
+	}
+	s.stopTimer()
+	s.timer.Reset(checkDelay)
+}
+
+// stopTimer stops the timer and drains its channel.
+func (s *server) stopTimer() {
+	if s.timer != nil && !s.timer.Stop() {
+		select {
+		case <-s.timer.C:
+		default:
+		}
+	}
+}
+
+// timerC returns the channel of the timer if there are pending
+// checks, or nil.
+func (s *server) timerC() <-chan time.Time {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	return s.timer.C
+}
+
+// flush checks the pending changes.
+func (s *server) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	s.stopTimer()
+	paths := make([]string, 0, len(s.pending))
+	for p := range s.pending {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		// In roots mode, each check compiles the roots and
+		// clears the pending changes.
+		if !s.pending[p] {
+			continue
+		}
+		if rerr := s.check(p); rerr != nil {
+			return rerr
+		}
+	}
+	return nil
+}
+
+// check compiles the journal for the document at the path and
+// publishes the diagnostics.
+func (s *server) check(path string) *responseError {
+	var inputs []journal.CompileInput
+	c := s.cache
+	if len(s.roots) > 0 {
+		for _, r := range s.roots {
+			inputs = append(inputs, input{s: s, path: r})
+		}
+		clear(s.pending)
+	} else {
+		inputs = append(inputs, input{s: s, path: path})
+		c = s.docs[path].cache
+		delete(s.pending, path)
+	}
+	j, err := c.Compile(&journal.CompileArgs{Inputs: inputs})
+	var diags journal.DiagnosticList
+	switch {
+	case errors.As(err, &diags):
+	case err != nil:
+		diags = journal.DiagnosticList{{
+			Severity: journal.SeverityError,
+			Code:     journal.CodeInput,
+			Msg:      err.Error(),
+		}}
+	case len(s.roots) > 0:
+		s.journal = j
+		diags = j.Diagnostics
+	default:
+		s.docs[path].journal = j
+		diags = j.Diagnostics
+	}
+	if len(s.roots) > 0 {
+		path = s.roots[0]
+	}
+	return s.publish(path, diags)
+}
+
+// publish publishes the diagnostics from compiling the document at
+// the path, or the roots if the path is the first root.  Diagnostics
+// without a file are published for the document or the first root.
+// Diagnostics previously published for other files are cleared.
+func (s *server) publish(path string, diags journal.DiagnosticList) *responseError {
+	files := make(map[string][]diagnostic)
+	for _, d := range diags {
+		f := d.Pos.Filename
+		if f == "" {
+			f = path
+		}
+		files[f] = append(files[f], s.diagnostic(d))
+	}
+	for f := range s.published[path] {
+		if _, ok := files[f]; !ok {
+			files[f] = []diagnostic{}
+		}
+	}
+	s.published[path] = make(map[string]bool)
+	for f := range files {
+		if len(files[f]) > 0 {
+			s.published[path][f] = true
+		}
+	}
+	names := make([]string, 0, len(files))
+	for f := range files {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	for _, f := range names {
+		err := s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         pathURI(f),
+			Diagnostics: files[f],
+		})
+		if err != nil {
+			return &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+func (s *server) diagnostic(d *journal.Diagnostic) diagnostic {
+	ld := diagnostic{
+		Range:    s.lineRange(d.Pos),
+		Severity: severityError,
+		Code:     d.Code,
+		Source:   "keeper",
+		Message:  d.Msg,
+	}
+	if d.Severity == journal.SeverityWarning {
+		ld.Severity = severityWarning
+	}
+	for _, p := range d.Related {
+		ld.RelatedInformation = append(ld.RelatedInformation, diagnosticRelatedInformation{
+			Location: s.location(p),
+			Message:  "related entry",
+		})
+	}
+	return ld
+}
+
+func (s *server) completion(d *document) completionList {
+	l := completionList{Items: []completionItem{}}
+	j := s.journalFor(d)
+	if j == nil {
+		return l
+	}
+	var accounts []string
+	for a := range j.Accounts {
+		accounts = append(accounts, string(a))
+	}
+	sort.Strings(accounts)
+	for _, a := range accounts {
+		l.Items = append(l.Items, completionItem{Label: a, Kind: completionVariable, Detail: "account"})
+	}
+	var units []string
+	for u := range j.Units {
+		units = append(units, u)
+	}
+	sort.Strings(units)
+	for _, u := range units {
+		l.Items = append(l.Items, completionItem{Label: u, Kind: completionUnit, Detail: "unit"})
+	}
+	return l
+}
+
+// definition returns the location of the declaration of the account
+// or unit at the position, or nil.
+func (s *server) definition(d *document, p position) *location {
+	j := s.journalFor(d)
+	if j == nil {
+		return nil
+	}
+	tok, lit, _, _ := tokenAt(d, offsetAt(d.text, p))
+	var pos token.Position
+	switch tok {
+	case token.ACCTNAME:
+		if ai := j.Accounts[journal.Account(lit)]; ai != nil {
+			pos = ai.DeclPos
+		}
+	case token.USYMBOL:
+		pos = j.UnitPos[lit]
+	}
+	if !pos.IsValid() {
+		return nil
+	}
+	l := s.location(pos)
+	return &l
+}
+
+// hover returns the balance of the account at the position after
+// the entry containing the position, or the final balance if the
+// position is not in an entry.
+func (s *server) hover(d *document, p position) *hover {
+	j := s.journalFor(d)
+	if j == nil {
+		return nil
+	}
+	off := offsetAt(d.text, p)
+	tok, lit, start, end := tokenAt(d, off)
+	if tok != token.ACCTNAME {
+		return nil
+	}
+	a := journal.Account(lit)
+	var b strings.Builder
+	fmt.Fprintf(&b, "`%s`\n\n", a)
+	if bal, ok := runningBalance(j, a, entryAt(d, off)); ok {
+		fmt.Fprintf(&b, "Balance after this entry: %s", bal)
+	} else {
+		fmt.Fprintf(&b, "Balance: %s", j.Balances[a])
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: b.String()},
+		Range: &lspRange{
+			Start: positionAt(d.text, start),
+			End:   positionAt(d.text, end),
+		},
+	}
+}
+
+// entryAt returns the position of the entry in the document
+// containing the offset, or an invalid position.
+func entryAt(d *document, off int) token.Position {
+	fset := token.NewFileSet()
+	f, _ := parser.ParseBytes(fset, d.path, d.text, 0)
+	for _, n := range f.Entries {
+		start := fset.Position(n.Pos())
+		end := fset.Position(n.End())
+		if start.Offset <= off && off <= end.Offset {
+			return start
+		}
+	}
+	return token.Position{}
+}
+
+// runningBalance returns the balance of the account after the journal
+// entry at the position.  It returns false if there is no such
+// entry.
+func runningBalance(j *journal.Journal, a journal.Account, pos token.Position) (*journal.Balance, bool) {
+	if !pos.IsValid() {
+		return nil, false
+	}
+	bal := &journal.Balance{}
+	for _, e := range j.Entries {
+		if t, ok := e.(*journal.Transaction); ok {
+			for _, s := range t.Splits {
+				if s.Account == a {
+					bal.Add(s.Amount)
+				}
+			}
+		}
+		if p := e.Position(); p.Filename == pos.Filename && p.Line == pos.Line {
+			return bal, true
+		}
+	}
+	return nil, false
+}
+
+// format returns the edits for formatting the document.
+func format(d *document) []textEdit {
+	fset := token.NewFileSet()
+	f, err := parser.ParseBytes(fset, d.path, d.text, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+	res, err := printer.Format(fset, f)
+	if err != nil {
+		return nil
+	}
+	if bytes.Equal(res, d.text) {
+		return []textEdit{}
+	}
+	return []textEdit{{
+		Range: lspRange{
+			End: positionAt(d.text, len(d.text)),
+		},
+		NewText: string(res),
+	}}
+}
+
+// tokenAt returns the account or unit token containing the offset
+// in the document, and its start and end offsets.
+func tokenAt(d *document, off int) (tok token.Token, lit string, start, end int) {
+	fset := token.NewFileSet()
+	f := fset.AddFile(d.path, -1, len(d.text))
+	var s scanner.Scanner
+	s.Init(f, d.text, nil, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return token.ILLEGAL, "", 0, 0
+		}
+		start := f.Offset(pos)
+		if start > off {
+			return token.ILLEGAL, "", 0, 0
+		}
+		end := start + len(lit)
+		if off <= end && (tok == token.ACCTNAME || tok == token.USYMBOL) {
+			return tok, lit, start, end
+		}
+	}
+}
+
+// location returns the location of the line starting at the
+// position.
+func (s *server) location(p token.Position) location {
+	return location{URI: pathURI(p.Filename), Range: s.lineRange(p)}
+}
+
+// lineRange returns the range from the position to the end of the
+// line.  The text of open documents is used for converting
+// positions; otherwise, columns are assumed to be ASCII.
+func (s *server) lineRange(p token.Position) lspRange {
+	d, ok := s.docs[p.Filename]
+	if !ok || p.Offset > len(d.text) {
+		start := position{Line: max(p.Line-1, 0), Character: max(p.Column-1, 0)}
+		return lspRange{Start: start, End: start}
+	}
+	end := p.Offset
+	for end < len(d.text) && d.text[end] != '\n' {
+		end++
+	}
+	return lspRange{
+		Start: positionAt(d.text, p.Offset),
+		End:   positionAt(d.text, end),
+	}
+}
+
+// positionAt returns the protocol position of a byte offset in the
+// text.  Protocol positions count characters in UTF-16 code units.
+func positionAt(text []byte, off int) position {
+	var p position
+	for _, r := range string(text[:min(off, len(text))]) {
+		if r == '\n' {
+			p.Line++
+			p.Character = 0
+			continue
+		}
+		p.Character += utf16Len(r)
+	}
+	return p
+}
+
+// offsetAt returns the byte offset of a protocol position in the
+// text.
+func offsetAt(text []byte, p position) int {
+	off := 0
+	for line := 0; line < p.Line; line++ {
+		i := bytes.IndexByte(text[off:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		off += i + 1
+	}
+	for c := 0; c < p.Character && off < len(text); {
+		r, size := utf8.DecodeRune(text[off:])
+		if r == '\n' {
+			break
+		}
+		c += utf16Len(r)
+		off += size
+	}
+	return off
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// uriPath returns the file path for a file URI.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathURI returns the file URI for a path.
+func pathURI(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
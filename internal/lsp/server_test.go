@@ -0,0 +1,270 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testURI = "file:///ledger/main.kpr"
+
+const testText = `unit USD 100
+account Assets:Bank
+end
+tx 2000-01-01 "Salary"
+Assets:Bank 100 USD
+Income:Salary
+end
+tx 2000-01-02 "Food"
+Assets:Bank -10 USD
+Expenses:Food
+end
+`
+
+// session runs the server with the messages and returns the messages
+// written by the server.
+func session(t *testing.T, msgs ...string) []map[string]interface{} {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	var out bytes.Buffer
+	if err := Serve(&in, &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	c := newConn(&out, nil)
+	var got []map[string]interface{}
+	for {
+		m, err := c.read()
+		if err != nil {
+			break
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	return got
+}
+
+func openMsg(text string) string {
+	b, _ := json.Marshal(text)
+	return fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":%q,"version":1,"text":%s}}}`, testURI, b)
+}
+
+func changeMsg(text string) string {
+	b, _ := json.Marshal(text)
+	return fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":%q,"version":2},"contentChanges":[{"text":%s}]}}`, testURI, b)
+}
+
+func requestMsg(method string, line, char int) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":{"textDocument":{"uri":%q},"position":{"line":%d,"character":%d}}}`, method, testURI, line, char)
+}
+
+func TestServe_diagnostics(t *testing.T) {
+	t.Parallel()
+	got := session(t, openMsg(testText+"balance 2000-01-03 Assets:Bank 100 USD\n"))
+	want := []map[string]interface{}{{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/publishDiagnostics",
+		"params": map[string]interface{}{
+			"uri": testURI,
+			"diagnostics": []interface{}{
+				map[string]interface{}{
+					"range":    lineRange(11, 0, 38),
+					"severity": 1.0,
+					"code":     "balance",
+					"source":   "keeper",
+					"message":  "balance of Assets:Bank is 90.00 USD, declared 100.00 USD (diff -10.00 USD)",
+				},
+				map[string]interface{}{
+					"range":    lineRange(3, 0, 22),
+					"severity": 2.0,
+					"code":     "undeclared-account",
+					"source":   "keeper",
+					"message":  "account Income:Salary is not declared",
+				},
+				map[string]interface{}{
+					"range":    lineRange(7, 0, 20),
+					"severity": 2.0,
+					"code":     "undeclared-account",
+					"source":   "keeper",
+					"message":  "account Expenses:Food is not declared",
+				},
+			},
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("messages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServe_didChange(t *testing.T) {
+	t.Parallel()
+	got := session(t, openMsg(testText),
+		changeMsg(testText+"balance 2000-01-03 Assets:Bank 100 USD\n"),
+		changeMsg(testText+"balance 2000-01-03 Assets:Bank 90 USD\n"))
+	if len(got) != 2 {
+		t.Fatalf("Got %d messages; want 2", len(got))
+	}
+	var codes []string
+	for _, d := range got[1]["params"].(map[string]interface{})["diagnostics"].([]interface{}) {
+		codes = append(codes, d.(map[string]interface{})["code"].(string))
+	}
+	want := []string{"undeclared-account", "undeclared-account"}
+	if diff := cmp.Diff(want, codes); diff != "" {
+		t.Errorf("diagnostic codes mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServe_hover(t *testing.T) {
+	t.Parallel()
+	got := session(t, openMsg(testText), requestMsg("textDocument/hover", 8, 3))
+	want := map[string]interface{}{
+		"contents": map[string]interface{}{
+			"kind":  "markdown",
+			"value": "`Assets:Bank`\n\nBalance after this entry: 90.00 USD",
+		},
+		"range": lineRange(8, 0, 11),
+	}
+	if diff := cmp.Diff(want, got[len(got)-1]["result"]); diff != "" {
+		t.Errorf("hover mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServe_definition(t *testing.T) {
+	t.Parallel()
+	got := session(t, openMsg(testText),
+		requestMsg("textDocument/definition", 4, 2),
+		requestMsg("textDocument/definition", 4, 17))
+	want := []interface{}{
+		map[string]interface{}{"uri": testURI, "range": lineRange(1, 0, 19)},
+		map[string]interface{}{"uri": testURI, "range": lineRange(0, 0, 12)},
+	}
+	var results []interface{}
+	for _, m := range got[len(got)-2:] {
+		results = append(results, m["result"])
+	}
+	if diff := cmp.Diff(want, results); diff != "" {
+		t.Errorf("definitions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServe_completion(t *testing.T) {
+	t.Parallel()
+	got := session(t, openMsg(testText), requestMsg("textDocument/completion", 5, 0))
+	var labels []string
+	res := got[len(got)-1]["result"].(map[string]interface{})
+	for _, i := range res["items"].([]interface{}) {
+		labels = append(labels, i.(map[string]interface{})["label"].(string))
+	}
+	want := []string{"Assets:Bank", "Expenses:Food", "Income:Salary", "USD"}
+	if diff := cmp.Diff(want, labels); diff != "" {
+		t.Errorf("completion mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServe_formatting(t *testing.T) {
+	t.Parallel()
+	text := strings.Replace(testText, "Assets:Bank -10 USD", "Assets:Bank   -10    USD", 1)
+	got := session(t, openMsg(text),
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"textDocument/formatting","params":{"textDocument":{"uri":%q},"options":{}}}`, testURI))
+	res := got[len(got)-1]["result"].([]interface{})
+	if len(res) != 1 {
+		t.Fatalf("Got %d edits; want 1", len(res))
+	}
+	e := res[0].(map[string]interface{})
+	if diff := cmp.Diff(lineRange(0, 0, 0)["start"], e["range"].(map[string]interface{})["start"]); diff != "" {
+		t.Errorf("edit start mismatch (-want +got):\n%s", diff)
+	}
+	want := `unit USD 100
+
+account Assets:Bank
+end
+
+tx 2000-01-01 "Salary"
+Assets:Bank   100 USD
+Income:Salary
+end
+
+tx 2000-01-02 "Food"
+Assets:Bank   -10 USD
+Expenses:Food
+end
+`
+	if got := e["newText"]; got != want {
+		t.Errorf("Got formatted text %q; want %q", got, want)
+	}
+}
+
+func TestServe_unknown_method(t *testing.T) {
+	t.Parallel()
+	got := session(t, `{"jsonrpc":"2.0","id":1,"method":"foo"}`)
+	want := []map[string]interface{}{{
+		"jsonrpc": "2.0",
+		"id":      1.0,
+		"error": map[string]interface{}{
+			"code":    float64(codeMethodNotFound),
+			"message": "method foo not found",
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("messages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOffsetAt(t *testing.T) {
+	t.Parallel()
+	text := []byte("ab\n資産:😀 x\n")
+	cases := []struct {
+		p    position
+		want int
+	}{
+		{position{0, 1}, 1},
+		{position{1, 0}, 3},
+		{position{1, 3}, 10},
+		{position{1, 5}, 14},
+		{position{5, 0}, len(text)},
+	}
+	for _, c := range cases {
+		if got := offsetAt(text, c.p); got != c.want {
+			t.Errorf("offsetAt(%v) = %d; want %d", c.p, got, c.want)
+		}
+		if c.want < len(text) {
+			if got := positionAt(text, c.want); got != c.p {
+				t.Errorf("positionAt(%d) = %v; want %v", c.want, got, c.p)
+			}
+		}
+	}
+}
+
+func lineRange(line, start, end int) map[string]interface{} {
+	return map[string]interface{}{
+		"start": map[string]interface{}{"line": float64(line), "character": float64(start)},
+		"end":   map[string]interface{}{"line": float64(line), "character": float64(end)},
+	}
+}
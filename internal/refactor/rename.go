@@ -0,0 +1,216 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package refactor implements source-preserving changes to keeper
+// files.
+package refactor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/parser"
+	"go.felesatra.moe/keeper/kpr/printer"
+	"go.felesatra.moe/keeper/kpr/scanner"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// A Source is the source of a keeper file.
+type Source struct {
+	Filename string
+	Src      []byte
+}
+
+// ErrAccountExists is returned when renaming to an existing account
+// without merging.
+var ErrAccountExists = errors.New("account already exists")
+
+// RenameAccount renames an account and its subaccounts in the
+// sources, returning the new sources of the files that were changed.
+// Only the account names are replaced; the rest of the source,
+// including formatting and comments, is unchanged.
+//
+// The new account cannot be under the old account.
+// If the new account or one of its subaccounts is already used, an
+// error wrapping ErrAccountExists is returned unless merge is true.
+// When merging, the declarations of both accounts are kept, so their
+// metadata is combined.
+func RenameAccount(srcs []Source, old, new journal.Account, merge bool) ([]Source, error) {
+	switch {
+	case old == new:
+		return nil, fmt.Errorf("rename account: %s is the same as the new name", old)
+	case !validAccount(old):
+		return nil, fmt.Errorf("rename account: invalid account %q", old)
+	case !validAccount(new):
+		return nil, fmt.Errorf("rename account: invalid account %q", new)
+	case new.Under(old):
+		return nil, fmt.Errorf("rename account: %s is under %s", new, old)
+	}
+	var fset *token.FileSet
+	var existsErr error
+	found := false
+	rename := func(v *ast.BasicValue) *ast.BasicValue {
+		a := journal.Account(v.Value)
+		if a == old || a.Under(old) {
+			found = true
+			v2 := *v
+			v2.Value = string(new) + strings.TrimPrefix(v.Value, string(old))
+			return &v2
+		}
+		if !merge && existsErr == nil && (a == new || a.Under(new)) {
+			existsErr = fmt.Errorf("rename account: %w: %s at %s", ErrAccountExists, a, fset.Position(v.Pos()))
+		}
+		return v
+	}
+	var changed []Source
+	for _, s := range srcs {
+		fset = token.NewFileSet()
+		f, err := parser.ParseBytes(fset, s.Filename, s.Src, parser.Lossless)
+		if err != nil {
+			return nil, fmt.Errorf("rename account: %w", err)
+		}
+		modified := false
+		for i, e := range f.Entries {
+			if e2 := renameEntry(e, rename); e2 != e {
+				f.Entries[i] = e2
+				modified = true
+			}
+		}
+		if existsErr != nil {
+			return nil, existsErr
+		}
+		if !modified {
+			continue
+		}
+		var b bytes.Buffer
+		if err := printer.Rewrite(&b, fset, f); err != nil {
+			return nil, fmt.Errorf("rename account: %w", err)
+		}
+		changed = append(changed, Source{Filename: s.Filename, Src: b.Bytes()})
+	}
+	if !found {
+		return nil, fmt.Errorf("rename account: account %s not found", old)
+	}
+	return changed, nil
+}
+
+// validAccount returns whether the account is a valid account name.
+func validAccount(a journal.Account) bool {
+	src := []byte(a)
+	var s scanner.Scanner
+	s.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	_, tok, lit := s.Scan()
+	if tok != token.ACCTNAME || lit != string(a) {
+		return false
+	}
+	_, tok, _ = s.Scan()
+	return tok == token.EOF
+}
+
+// renameEntry returns a copy of an entry with its account names
+// replaced by the rename function, which returns the value unchanged
+// for accounts that are not renamed.  The entry is returned if no
+// accounts are renamed.
+func renameEntry(e ast.Entry, rename func(*ast.BasicValue) *ast.BasicValue) ast.Entry {
+	changed := false
+	acct := func(v *ast.BasicValue) *ast.BasicValue {
+		if v == nil || v.Kind != token.ACCTNAME {
+			return v
+		}
+		v2 := rename(v)
+		if v2 != v {
+			changed = true
+		}
+		return v2
+	}
+	lines := func(l []ast.LineNode) []ast.LineNode {
+		var l2 []ast.LineNode
+		for i, n := range l {
+			var n2 ast.LineNode
+			switch n := n.(type) {
+			case *ast.SplitLine:
+				if a := acct(n.Account); a != n.Account {
+					c := *n
+					c.Account = a
+					n2 = &c
+				}
+			case *ast.MatchLine:
+				if a := acct(n.Value); a != n.Value {
+					c := *n
+					c.Value = a
+					n2 = &c
+				}
+			}
+			if n2 == nil {
+				continue
+			}
+			if l2 == nil {
+				l2 = append([]ast.LineNode(nil), l...)
+			}
+			l2[i] = n2
+		}
+		if l2 == nil {
+			return l
+		}
+		return l2
+	}
+	var e2 ast.Entry
+	switch e := e.(type) {
+	case *ast.Transaction:
+		c := *e
+		c.Splits = lines(e.Splits)
+		e2 = &c
+	case *ast.Recurring:
+		c := *e
+		c.Splits = lines(e.Splits)
+		e2 = &c
+	case *ast.Rule:
+		c := *e
+		c.Lines = lines(e.Lines)
+		e2 = &c
+	case *ast.SingleBalance:
+		c := *e
+		c.Account = acct(e.Account)
+		e2 = &c
+	case *ast.MultiBalance:
+		c := *e
+		c.Account = acct(e.Account)
+		e2 = &c
+	case *ast.DisableAccount:
+		c := *e
+		c.Account = acct(e.Account)
+		e2 = &c
+	case *ast.DeclareAccount:
+		c := *e
+		c.Account = acct(e.Account)
+		e2 = &c
+	case *ast.Pad:
+		c := *e
+		c.Account = acct(e.Account)
+		c.Source = acct(e.Source)
+		e2 = &c
+	case *ast.Budget:
+		c := *e
+		c.Account = acct(e.Account)
+		e2 = &c
+	}
+	if !changed {
+		return e
+	}
+	return e2
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package refactor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/journal"
+)
+
+func TestRenameAccount(t *testing.T) {
+	t.Parallel()
+	srcs := []Source{
+		{Filename: "accounts.kpr", Src: []byte(`# Food accounts
+account Expenses:Food
+meta "budget" "yes"
+end
+account Assets:Cash
+end
+disable 2001-03-01 Expenses:Food:Lunch # no more lunch
+`)},
+		{Filename: "2001.kpr", Src: []byte(`tx 2001-02-03   "Buy food"   # trailing
+Expenses:Food:Lunch   1.2 USD # tasty
+Assets:Cash        -1.20 USD
+end
+
+# Check
+treebal 2001-02-04 Expenses:Food   1.20 USD
+pad 2001-02-05 Assets:Cash   Equity:Opening
+`)},
+		{Filename: "other.kpr", Src: []byte(`tx 2001-02-03 "Salary"
+Income:Salary  -1 USD
+Assets:Cash
+end
+`)},
+	}
+	got, err := RenameAccount(srcs, "Expenses:Food", "Expenses:Groceries", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Source{
+		{Filename: "accounts.kpr", Src: []byte(`# Food accounts
+account Expenses:Groceries
+meta "budget" "yes"
+end
+account Assets:Cash
+end
+disable 2001-03-01 Expenses:Groceries:Lunch # no more lunch
+`)},
+		{Filename: "2001.kpr", Src: []byte(`tx 2001-02-03   "Buy food"   # trailing
+Expenses:Groceries:Lunch   1.2 USD # tasty
+Assets:Cash        -1.20 USD
+end
+
+# Check
+treebal 2001-02-04 Expenses:Groceries   1.20 USD
+pad 2001-02-05 Assets:Cash   Equity:Opening
+`)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sources mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenameAccount_exists(t *testing.T) {
+	t.Parallel()
+	srcs := []Source{
+		{Filename: "a.kpr", Src: []byte(`tx 2001-02-03 "Buy food"
+Expenses:Food 1.2 USD
+Expenses:Groceries:Fruit 1 USD
+Assets:Cash
+end
+`)},
+	}
+	_, err := RenameAccount(srcs, "Expenses:Food", "Expenses:Groceries", false)
+	if !errors.Is(err, ErrAccountExists) {
+		t.Errorf("Got error %v; want %v", err, ErrAccountExists)
+	}
+	got, err := RenameAccount(srcs, "Expenses:Food", "Expenses:Groceries", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Source{
+		{Filename: "a.kpr", Src: []byte(`tx 2001-02-03 "Buy food"
+Expenses:Groceries 1.2 USD
+Expenses:Groceries:Fruit 1 USD
+Assets:Cash
+end
+`)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sources mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenameAccount_errors(t *testing.T) {
+	t.Parallel()
+	srcs := []Source{
+		{Filename: "a.kpr", Src: []byte(`disable 2001-02-03 Expenses:Food
+`)},
+	}
+	cases := []struct {
+		desc     string
+		old, new journal.Account
+	}{
+		{"same", "Expenses:Food", "Expenses:Food"},
+		{"invalid", "Expenses:Food", "Expenses Groceries"},
+		{"not found", "Expenses:Drink", "Expenses:Groceries"},
+		{"under old", "Expenses:Food", "Expenses:Food:Groceries"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			if _, err := RenameAccount(srcs, c.old, c.new, false); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
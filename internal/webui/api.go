@@ -0,0 +1,630 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/internal/config"
+	"go.felesatra.moe/keeper/internal/jsonfmt"
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
+	"go.felesatra.moe/keeper/reports"
+)
+
+// This file implements the JSON API, which provides the data of the
+// HTML pages under /api/v1.
+//
+// Amounts are objects with the number as an exact decimal string, the
+// unit symbol and the unit scale.  Balances are arrays of amounts.
+//
+// Date ranges are given with the period query parameter, which
+// accepts the formats of period.Parse, and the start and end query
+// parameters, which are dates overriding the ends of the period.  An
+// end date given alone moves the default period to the one containing
+// it.
+//
+// Fractions, such as the change between periods and the part of a
+// budget used, are decimal strings rounded to four places, or null if
+// undefined.
+//
+// Errors are returned as an object with an error message.
+
+func (h handler) addAPI(m *http.ServeMux) {
+	m.HandleFunc("/api/v1/", h.handleAPINotFound)
+	m.HandleFunc("/api/v1/accounts", h.handleAPIAccounts)
+	m.HandleFunc("/api/v1/trial", h.handleAPITrial)
+	m.HandleFunc("/api/v1/ledger", h.handleAPILedger)
+	m.HandleFunc("/api/v1/statements/", h.handleAPIStatement)
+	m.HandleFunc("/api/v1/compare", h.handleAPICompare)
+	m.HandleFunc("/api/v1/budget", h.handleAPIBudget)
+	m.HandleFunc("/api/v1/gains", h.handleAPIGains)
+	m.HandleFunc("/api/v1/errors", h.handleAPIErrors)
+}
+
+// apiFraction returns a fraction for the API, or nil if the fraction
+// is nil.
+func apiFraction(r *big.Rat) *string {
+	if r == nil {
+		return nil
+	}
+	s := r.FloatString(4)
+	return &s
+}
+
+// apiDate returns a date for the API, or the empty string for the
+// zero date.
+func apiDate(d civil.Date) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.String()
+}
+
+func (h handler) handleAPINotFound(w http.ResponseWriter, req *http.Request) {
+	writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown API path %s", req.URL.Path))
+}
+
+func (h handler) handleAPIAccounts(w http.ResponseWriter, req *http.Request) {
+	type account struct {
+		Account  string            `json:"account"`
+		Disabled string            `json:"disabled,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+		Balance  []jsonfmt.Amount  `json:"balance"`
+	}
+	type result struct {
+		End      string    `json:"end,omitempty"`
+		Accounts []account `json:"accounts"`
+	}
+	p, err := h.apiRange(req, period.Period{})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	j, err := h.compileEnding(p.End)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	r := result{End: apiDate(p.End), Accounts: []account{}}
+	for _, a := range sortedAccounts(j) {
+		ai := j.Accounts[a]
+		e := account{
+			Account:  string(a),
+			Metadata: ai.Metadata,
+			Balance:  jsonfmt.Balance(j.Balances[a]),
+		}
+		if ai.Disabled != nil {
+			e.Disabled = ai.Disabled.EntryDate.String()
+		}
+		r.Accounts = append(r.Accounts, e)
+	}
+	writeAPI(w, r)
+}
+
+func (h handler) handleAPITrial(w http.ResponseWriter, req *http.Request) {
+	type row struct {
+		Account string           `json:"account"`
+		Debit   []jsonfmt.Amount `json:"debit"`
+		Credit  []jsonfmt.Amount `json:"credit"`
+	}
+	type result struct {
+		End   string `json:"end,omitempty"`
+		Rows  []row  `json:"rows"`
+		Total row    `json:"total"`
+	}
+	p, err := h.apiRange(req, period.Period{})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	j, err := h.compileEnding(p.End)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	t := reports.NewTrialBalance(j)
+	r := result{
+		End:  apiDate(p.End),
+		Rows: []row{},
+		Total: row{
+			Account: "Total",
+			Debit:   jsonfmt.Balance(&t.Total.Debit),
+			Credit:  jsonfmt.Balance(&t.Total.Credit),
+		},
+	}
+	for _, tr := range t.Rows {
+		var debit, credit journal.Balance
+		for _, p := range tr.Pairs {
+			if p.Debit != nil {
+				debit.Add(p.Debit)
+			}
+			if p.Credit != nil {
+				credit.Add(p.Credit)
+			}
+		}
+		r.Rows = append(r.Rows, row{
+			Account: string(tr.Account),
+			Debit:   jsonfmt.Balance(&debit),
+			Credit:  jsonfmt.Balance(&credit),
+		})
+	}
+	writeAPI(w, r)
+}
+
+func (h handler) handleAPILedger(w http.ResponseWriter, req *http.Request) {
+	type row struct {
+		Date        string           `json:"date"`
+		Description string           `json:"description"`
+		Ref         string           `json:"ref"`
+		RuleRef     string           `json:"rule_ref,omitempty"`
+		Debit       *jsonfmt.Amount  `json:"debit"`
+		Credit      *jsonfmt.Amount  `json:"credit"`
+		Balance     []jsonfmt.Amount `json:"balance"`
+	}
+	type result struct {
+		Account string `json:"account"`
+		Start   string `json:"start,omitempty"`
+		End     string `json:"end,omitempty"`
+		Rows    []row  `json:"rows"`
+	}
+	a := getQueryAccount(req)
+	if a == "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("missing account parameter"))
+		return
+	}
+	p, err := h.apiRange(req, period.Period{})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	j, err := h.compileEnding(p.End)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	l := reports.NewAccountLedger(j, a)
+	r := result{
+		Account: string(a),
+		Start:   apiDate(p.Start),
+		End:     apiDate(p.End),
+		Rows:    []row{},
+	}
+	for _, lr := range l.Rows {
+		// The running balance includes rows before the start.
+		if p.Start.IsValid() && lr.Date.Before(p.Start) {
+			continue
+		}
+		r.Rows = append(r.Rows, row{
+			Date:        lr.Date.String(),
+			Description: lr.Description,
+			Ref:         lr.Ref,
+			RuleRef:     lr.RuleRef,
+			Debit:       jsonfmt.NewAmount(lr.Pair.Debit),
+			Credit:      jsonfmt.NewAmount(lr.Pair.Credit),
+			Balance:     jsonfmt.Balance(&lr.Balance),
+		})
+	}
+	writeAPI(w, r)
+}
+
+type apiStmtRow struct {
+	Account string           `json:"account"`
+	Balance []jsonfmt.Amount `json:"balance"`
+}
+
+type apiStmtSection struct {
+	Title string           `json:"title"`
+	Rows  []apiStmtRow     `json:"rows"`
+	Total []jsonfmt.Amount `json:"total"`
+}
+
+type apiStmtTotal struct {
+	Title   string           `json:"title"`
+	Balance []jsonfmt.Amount `json:"balance"`
+}
+
+type apiStmt struct {
+	Title    string           `json:"title"`
+	Period   string           `json:"period"`
+	Start    string           `json:"start"`
+	End      string           `json:"end"`
+	Sections []apiStmtSection `json:"sections"`
+	Totals   []apiStmtTotal   `json:"totals"`
+}
+
+func (s *apiStmt) addSection(title string, sec *reports.StatementSection) {
+	as := apiStmtSection{
+		Title: title,
+		Rows:  []apiStmtRow{},
+		Total: jsonfmt.Balance(&sec.Total),
+	}
+	for _, r := range sec.Rows {
+		as.Rows = append(as.Rows, apiStmtRow{
+			Account: string(r.Account),
+			Balance: jsonfmt.Balance(&r.Balance),
+		})
+	}
+	s.Sections = append(s.Sections, as)
+}
+
+func (s *apiStmt) addTotal(title string, b *journal.Balance) {
+	s.Totals = append(s.Totals, apiStmtTotal{Title: title, Balance: jsonfmt.Balance(b)})
+}
+
+func (h handler) handleAPIStatement(w http.ResponseWriter, req *http.Request) {
+	kind := strings.TrimPrefix(req.URL.Path, "/api/v1/statements/")
+	switch kind {
+	case "income", "balance", "cash", "capital":
+	default:
+		h.handleAPINotFound(w, req)
+		return
+	}
+	p, j, c, ok := h.compileAPIStmt(w, req)
+	if !ok {
+		return
+	}
+	s := apiStmt{
+		Period:   p.String(),
+		Start:    p.Start.String(),
+		End:      p.End.String(),
+		Sections: []apiStmtSection{},
+		Totals:   []apiStmtTotal{},
+	}
+	switch kind {
+	case "income":
+		r := reports.NewIncomeStatement(j, c, p.Start, p.End)
+		s.Title = "Income Statement"
+		s.addSection("Income", &r.Income)
+		s.addSection("Expenses", &r.Expenses)
+		s.addTotal("Net Profit", &r.NetProfit)
+	case "balance":
+		r := reports.NewBalanceSheet(j, c, p.End)
+		s.Title = "Balance Sheet"
+		s.addSection("Assets", &r.Assets)
+		s.addSection("Liabilities", &r.Liabilities)
+		s.addSection("Equity", &r.Equity)
+		s.addTotal("Liabilities & Equity", &r.LiabilitiesEquity)
+	case "cash":
+		r := reports.NewCashFlow(j, c, p.Start, p.End)
+		s.Title = "Cash Flow"
+		s.addSection("Starting Balances", &r.Starting)
+		s.addSection("Inflow", &r.Inflows)
+		s.addSection("Outflow", &r.Outflows)
+		s.addSection("Ending Balances", &r.Ending)
+	case "capital":
+		r := reports.NewCapitalStatement(j, c, p.Start, p.End)
+		s.Title = "Capital Statement"
+		s.addSection("Starting Balances", &r.Starting)
+		s.addSection("Increases", &r.Increases)
+		s.addSection("Decreases", &r.Decreases)
+		s.addSection("Ending Balances", &r.Ending)
+	}
+	writeAPI(w, s)
+}
+
+// compileAPIStmt compiles the journal for the period of a statement
+// request, which defaults to the current month.  If there is an
+// error, it is written and ok is false.
+func (h handler) compileAPIStmt(w http.ResponseWriter, req *http.Request) (p period.Period, j *journal.Journal, c *config.Config, ok bool) {
+	c, err := h.config()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return p, nil, nil, false
+	}
+	def := period.Containing(period.Month, civil.DateOf(time.Now()), c.Period.FiscalStart())
+	p, err = h.apiRange(req, def)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return p, nil, nil, false
+	}
+	j, err = h.compileEnding(p.End)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return p, nil, nil, false
+	}
+	if err := c.SetAccounts(j.Accounts); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return p, nil, nil, false
+	}
+	return p, j, c, true
+}
+
+func (h handler) handleAPICompare(w http.ResponseWriter, req *http.Request) {
+	type row struct {
+		Account  string           `json:"account,omitempty"`
+		Amounts  []jsonfmt.Amount `json:"amounts"`
+		Variance *jsonfmt.Amount  `json:"variance"`
+		Change   *string          `json:"change"`
+	}
+	type section struct {
+		Title  string `json:"title"`
+		Rows   []row  `json:"rows"`
+		Totals []row  `json:"totals"`
+	}
+	type result struct {
+		Report   string    `json:"report"`
+		Title    string    `json:"title"`
+		Periods  []string  `json:"periods"`
+		Sections []section `json:"sections"`
+	}
+	report := req.URL.Query().Get("report")
+	switch report {
+	case "":
+		report = "income"
+	case "income", "balance":
+	default:
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown report %q", report))
+		return
+	}
+	n, err := getQueryInt(req, "n", 12, maxPeriods)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	p, j, c, ok := h.compileAPIStmt(w, req)
+	if !ok {
+		return
+	}
+	ps := period.Series(p, n)
+	res := result{Report: report, Periods: []string{}, Sections: []section{}}
+	var cr *reports.Comparative
+	if report == "balance" {
+		res.Title = "Comparative Balance Sheet"
+		cr = reports.NewComparativeBalanceSheet(j, c, ps)
+	} else {
+		res.Title = "Comparative Income Statement"
+		cr = reports.NewComparativeIncomeStatement(j, c, ps)
+	}
+	for _, p := range cr.Periods {
+		res.Periods = append(res.Periods, p.String())
+	}
+	rows := func(rs []reports.ComparativeRow) []row {
+		out := []row{}
+		for _, r := range rs {
+			ar := row{
+				Account:  string(r.Account),
+				Amounts:  []jsonfmt.Amount{},
+				Variance: jsonfmt.NewAmount(r.Variance),
+				Change:   apiFraction(r.Change),
+			}
+			for _, a := range r.Amounts {
+				ar.Amounts = append(ar.Amounts, *jsonfmt.NewAmount(a))
+			}
+			out = append(out, ar)
+		}
+		return out
+	}
+	for _, s := range cr.Sections {
+		res.Sections = append(res.Sections, section{
+			Title:  s.Title,
+			Rows:   rows(s.Rows),
+			Totals: rows(s.Totals),
+		})
+	}
+	writeAPI(w, res)
+}
+
+func (h handler) handleAPIBudget(w http.ResponseWriter, req *http.Request) {
+	type row struct {
+		Account   string          `json:"account"`
+		Budgeted  *jsonfmt.Amount `json:"budgeted"`
+		Actual    *jsonfmt.Amount `json:"actual"`
+		Remaining *jsonfmt.Amount `json:"remaining"`
+		Used      *string         `json:"used"`
+		Over      bool            `json:"over"`
+	}
+	type result struct {
+		Period string `json:"period"`
+		Start  string `json:"start"`
+		End    string `json:"end"`
+		Rows   []row  `json:"rows"`
+	}
+	p, j, c, ok := h.compileAPIStmt(w, req)
+	if !ok {
+		return
+	}
+	br := reports.NewBudgetReport(j, c, p.Start, p.End)
+	res := result{
+		Period: p.String(),
+		Start:  p.Start.String(),
+		End:    p.End.String(),
+		Rows:   []row{},
+	}
+	for i := range br.Rows {
+		r := &br.Rows[i]
+		res.Rows = append(res.Rows, row{
+			Account:   string(r.Account),
+			Budgeted:  jsonfmt.NewAmount(r.Budgeted),
+			Actual:    jsonfmt.NewAmount(r.Actual),
+			Remaining: jsonfmt.NewAmount(r.Remaining),
+			Used:      apiFraction(r.Used),
+			Over:      r.Over(),
+		})
+	}
+	writeAPI(w, res)
+}
+
+func (h handler) handleAPIGains(w http.ResponseWriter, req *http.Request) {
+	type row struct {
+		Account  string          `json:"account"`
+		Amount   *jsonfmt.Amount `json:"amount"`
+		Acquired string          `json:"acquired"`
+		Disposed string          `json:"disposed"`
+		Proceeds *jsonfmt.Amount `json:"proceeds"`
+		Cost     *jsonfmt.Amount `json:"cost"`
+		Gain     *jsonfmt.Amount `json:"gain"`
+		LongTerm bool            `json:"long_term"`
+		Ref      string          `json:"ref"`
+	}
+	type total struct {
+		Unit      string          `json:"unit"`
+		Amount    *jsonfmt.Amount `json:"amount"`
+		Proceeds  *jsonfmt.Amount `json:"proceeds"`
+		Cost      *jsonfmt.Amount `json:"cost"`
+		Gain      *jsonfmt.Amount `json:"gain"`
+		ShortTerm *jsonfmt.Amount `json:"short_term"`
+		LongTerm  *jsonfmt.Amount `json:"long_term"`
+	}
+	type result struct {
+		Period string  `json:"period"`
+		Start  string  `json:"start"`
+		End    string  `json:"end"`
+		Base   string  `json:"base"`
+		Rows   []row   `json:"rows"`
+		Totals []total `json:"totals"`
+	}
+	c, err := h.config()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	def := period.Containing(period.Year, civil.DateOf(time.Now()), c.Period.FiscalStart())
+	p, err := h.apiRange(req, def)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	j, err := h.compile()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	g, err := reports.NewCapitalGains(j, c.BaseUnit(j), p.Start, p.End)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	res := result{
+		Period: p.String(),
+		Start:  p.Start.String(),
+		End:    p.End.String(),
+		Base:   g.Base.Symbol,
+		Rows:   []row{},
+		Totals: []total{},
+	}
+	for _, r := range g.Rows {
+		res.Rows = append(res.Rows, row{
+			Account:  string(r.Account),
+			Amount:   jsonfmt.NewAmount(r.Amount),
+			Acquired: r.Acquired.String(),
+			Disposed: r.Disposed.String(),
+			Proceeds: jsonfmt.NewAmount(r.Proceeds),
+			Cost:     jsonfmt.NewAmount(r.Cost),
+			Gain:     jsonfmt.NewAmount(r.Gain),
+			LongTerm: r.LongTerm,
+			Ref:      r.Ref,
+		})
+	}
+	for _, t := range g.Totals {
+		res.Totals = append(res.Totals, total{
+			Unit:      t.Unit.Symbol,
+			Amount:    jsonfmt.NewAmount(t.Amount),
+			Proceeds:  jsonfmt.NewAmount(t.Proceeds),
+			Cost:      jsonfmt.NewAmount(t.Cost),
+			Gain:      jsonfmt.NewAmount(t.Gain),
+			ShortTerm: jsonfmt.NewAmount(t.ShortTerm),
+			LongTerm:  jsonfmt.NewAmount(t.LongTerm),
+		})
+	}
+	writeAPI(w, res)
+}
+
+func (h handler) handleAPIErrors(w http.ResponseWriter, req *http.Request) {
+	type result struct {
+		Diagnostics []jsonfmt.Diagnostic `json:"diagnostics"`
+	}
+	var diags journal.DiagnosticList
+	j, err := h.compile()
+	if err != nil {
+		// Compile errors are reported as diagnostics.
+		if !errors.As(err, &diags) {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+	} else {
+		diags = j.Diagnostics
+	}
+	writeAPI(w, result{Diagnostics: jsonfmt.Diagnostics(diags)})
+}
+
+// apiRange returns the date range in the request query, or def if
+// none is given.  If only the end date is given, the start date is
+// the start of the period of the default kind containing the end
+// date.
+func (h handler) apiRange(req *http.Request, def period.Period) (period.Period, error) {
+	q := req.URL.Query()
+	p := def
+	c, err := h.config()
+	if err != nil {
+		return p, err
+	}
+	if v := q.Get("period"); v != "" {
+		p, err = period.Parse(v, c.Period.FiscalStart())
+		if err != nil {
+			return p, err
+		}
+	} else if v := q.Get("end"); v != "" && q.Get("start") == "" && def.Start.IsValid() {
+		if d, err := civil.ParseDate(v); err == nil {
+			p.Start = period.Containing(def.Kind, d, c.Period.FiscalStart()).Start
+		}
+	}
+	for _, k := range []string{"start", "end"} {
+		v := q.Get(k)
+		if v == "" {
+			continue
+		}
+		d, err := civil.ParseDate(v)
+		if err != nil {
+			return p, fmt.Errorf("invalid %s date %q", k, v)
+		}
+		if k == "start" {
+			p.Start = d
+		} else {
+			p.End = d
+		}
+		p.Kind = period.Custom
+	}
+	if p.Start.IsValid() && p.End.IsValid() && p.End.Before(p.Start) {
+		return p, fmt.Errorf("end %s is before start %s", p.End, p.Start)
+	}
+	return p, nil
+}
+
+func writeAPI(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
+
+func writeAPIError(w http.ResponseWriter, code int, err error) {
+	b, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(b, '\n'))
+}
@@ -0,0 +1,323 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/journal"
+)
+
+const apiTestJournal = `unit USD 100
+tx 2020-01-02 "Paycheck"
+Income:Salary -1,000.00 USD
+Assets:Bank
+end
+tx 2020-02-03 "Groceries"
+Expenses:Food 12.34 USD
+Assets:Bank
+end
+balance 2020-02-04 Assets:Bank 1 USD
+budget 2020-01 Expenses:Food 50 USD monthly
+`
+
+func newAPITestHandler(t *testing.T) http.Handler {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "test.kpr")
+	if err := os.WriteFile(p, []byte(apiTestJournal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler("", &journal.CompileArgs{Inputs: journal.Files(p)})
+}
+
+// getAPI gets a JSON API path, checking the status code.
+func getAPI(t *testing.T, h http.Handler, path string, code int) interface{} {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	if w.Code != code {
+		t.Fatalf("Got status %d; want %d; body %s", w.Code, code, w.Body)
+	}
+	if got, want := w.Header().Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("Got content type %q; want %q", got, want)
+	}
+	var v interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+type object = map[string]interface{}
+
+func usd(n string) object {
+	return object{"number": n, "unit": "USD", "scale": 100.0}
+}
+
+func TestAPI_accounts(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/accounts?end=2020-01-31", http.StatusOK)
+	want := object{
+		"end": "2020-01-31",
+		"accounts": []interface{}{
+			object{"account": "Assets:Bank", "balance": []interface{}{usd("1000.00")}},
+			object{"account": "Income:Salary", "balance": []interface{}{usd("-1000.00")}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPI_ledger(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/ledger?account=Assets:Bank&start=2020-02-01", http.StatusOK)
+	want := object{
+		"account": "Assets:Bank",
+		"start":   "2020-02-01",
+		"rows": []interface{}{
+			object{
+				"date":        "2020-02-03",
+				"description": "Groceries",
+				"ref":         "test.kpr:6:1",
+				"debit":       nil,
+				"credit":      usd("-12.34"),
+				"balance":     []interface{}{usd("987.66")},
+			},
+			object{
+				"date":        "2020-02-04",
+				"description": "(balance error, declared 1.00 USD, diff 986.66 USD)",
+				"ref":         "test.kpr:10:1",
+				"debit":       nil,
+				"credit":      nil,
+				"balance":     []interface{}{usd("987.66")},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPI_income_statement(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/statements/income?period=2020-Q1", http.StatusOK)
+	want := object{
+		"title":  "Income Statement",
+		"period": "2020-Q1",
+		"start":  "2020-01-01",
+		"end":    "2020-03-31",
+		"sections": []interface{}{
+			object{
+				"title": "Income",
+				"rows": []interface{}{
+					object{"account": "Income:Salary", "balance": []interface{}{usd("1000.00")}},
+				},
+				"total": []interface{}{usd("1000.00")},
+			},
+			object{
+				"title": "Expenses",
+				"rows": []interface{}{
+					object{"account": "Expenses:Food", "balance": []interface{}{usd("12.34")}},
+				},
+				"total": []interface{}{usd("12.34")},
+			},
+		},
+		"totals": []interface{}{
+			object{"title": "Net Profit", "balance": []interface{}{usd("987.66")}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPI_statement_end_only(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/statements/balance?end=2019-12-31", http.StatusOK).(object)
+	if got, want := got["start"], "2019-12-01"; got != want {
+		t.Errorf("Got start %v; want %v", got, want)
+	}
+	if got, want := got["end"], "2019-12-31"; got != want {
+		t.Errorf("Got end %v; want %v", got, want)
+	}
+}
+
+func TestAPI_compare(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/compare?period=2020-02&n=2", http.StatusOK)
+	want := object{
+		"report":  "income",
+		"title":   "Comparative Income Statement",
+		"periods": []interface{}{"2020-01", "2020-02"},
+		"sections": []interface{}{
+			object{
+				"title": "Income",
+				"rows": []interface{}{
+					object{
+						"account":  "Income:Salary",
+						"amounts":  []interface{}{usd("1000.00"), usd("0.00")},
+						"variance": usd("-1000.00"),
+						"change":   "-1.0000",
+					},
+				},
+				"totals": []interface{}{
+					object{
+						"amounts":  []interface{}{usd("1000.00"), usd("0.00")},
+						"variance": usd("-1000.00"),
+						"change":   "-1.0000",
+					},
+				},
+			},
+			object{
+				"title": "Expenses",
+				"rows": []interface{}{
+					object{
+						"account":  "Expenses:Food",
+						"amounts":  []interface{}{usd("0.00"), usd("12.34")},
+						"variance": usd("12.34"),
+						"change":   nil,
+					},
+				},
+				"totals": []interface{}{
+					object{
+						"amounts":  []interface{}{usd("0.00"), usd("12.34")},
+						"variance": usd("12.34"),
+						"change":   nil,
+					},
+				},
+			},
+			object{
+				"title": "Net Profit",
+				"rows":  []interface{}{},
+				"totals": []interface{}{
+					object{
+						"amounts":  []interface{}{usd("1000.00"), usd("-12.34")},
+						"variance": usd("-1012.34"),
+						"change":   "-1.0123",
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPI_budget(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/budget?period=2020-02", http.StatusOK)
+	want := object{
+		"period": "2020-02",
+		"start":  "2020-02-01",
+		"end":    "2020-02-29",
+		"rows": []interface{}{
+			object{
+				"account":   "Expenses:Food",
+				"budgeted":  usd("50.00"),
+				"actual":    usd("12.34"),
+				"remaining": usd("37.66"),
+				"used":      "0.2468",
+				"over":      false,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPI_gains(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/gains?period=2020", http.StatusOK)
+	want := object{
+		"period": "2020",
+		"start":  "2020-01-01",
+		"end":    "2020-12-31",
+		"base":   "USD",
+		"rows":   []interface{}{},
+		"totals": []interface{}{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPI_errors(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	got := getAPI(t, h, "/api/v1/errors", http.StatusOK)
+	want := object{
+		"diagnostics": []interface{}{
+			object{
+				"severity": "error",
+				"code":     "balance",
+				"message":  "balance of Assets:Bank is 987.66 USD, declared 1.00 USD (diff 986.66 USD)",
+				"file":     "test.kpr",
+				"line":     10.0,
+				"column":   1.0,
+				"account":  "Assets:Bank",
+				"balance": object{
+					"declared": []interface{}{usd("1.00")},
+					"actual":   []interface{}{usd("987.66")},
+					"diff":     []interface{}{usd("986.66")},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPI_bad_requests(t *testing.T) {
+	t.Parallel()
+	h := newAPITestHandler(t)
+	cases := []struct {
+		path string
+		code int
+	}{
+		{"/api/v1/ledger", http.StatusBadRequest},
+		{"/api/v1/trial?end=2020-13-01", http.StatusBadRequest},
+		{"/api/v1/statements/income?start=2020-02-01&end=2020-01-01", http.StatusBadRequest},
+		{"/api/v1/statements/other", http.StatusNotFound},
+		{"/api/v1/compare?report=other", http.StatusBadRequest},
+		{"/api/v1/compare?n=0", http.StatusBadRequest},
+		{"/api/v1/compare?n=1000000", http.StatusBadRequest},
+		{"/api/v1/compare?n=x", http.StatusBadRequest},
+		{"/api/v1/gains?period=last", http.StatusBadRequest},
+		{"/api/v1/other", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		got := getAPI(t, h, c.path, c.code)
+		if _, ok := got.(object)["error"]; !ok {
+			t.Errorf("%s: got %v; want error", c.path, got)
+		}
+	}
+}
@@ -20,18 +20,21 @@ import (
 	"fmt"
 	"html/template"
 	"log/slog"
+	"math/big"
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/civil"
 	"github.com/piquette/finance-go"
 	"go.felesatra.moe/keeper/internal/config"
 	"go.felesatra.moe/keeper/internal/findat"
-	"go.felesatra.moe/keeper/internal/month"
 	"go.felesatra.moe/keeper/internal/webui/templates"
 	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
 	"go.felesatra.moe/keeper/reports"
 )
 
@@ -39,6 +42,8 @@ func NewHandler(configPath string, a *journal.CompileArgs) http.Handler {
 	h := handler{
 		configPath: configPath,
 		a:          a,
+		cache:      journal.NewCache(),
+		configs:    &configCache{},
 	}
 	m := http.NewServeMux()
 	m.HandleFunc("/", h.handleIndex)
@@ -49,13 +54,21 @@ func NewHandler(configPath string, a *journal.CompileArgs) http.Handler {
 	m.HandleFunc("/capital", h.handleCapital)
 	m.HandleFunc("/balance", h.handleBalance)
 	m.HandleFunc("/cash", h.handleCash)
+	m.HandleFunc("/compare", h.handleCompare)
+	m.HandleFunc("/budget", h.handleBudget)
+	m.HandleFunc("/gains", h.handleGains)
 	m.HandleFunc("/ledger", h.handleLedger)
+	h.addAPI(m)
 	return m
 }
 
 type handler struct {
 	configPath string
 	a          *journal.CompileArgs
+	// The journal is compiled once and shared by requests until
+	// the files change.
+	cache   *journal.Cache
+	configs *configCache
 }
 
 func (h handler) handleIndex(w http.ResponseWriter, req *http.Request) {
@@ -95,194 +108,196 @@ func (h handler) handleTrial(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h handler) handleIncome(w http.ResponseWriter, req *http.Request) {
-	end := month.LastDay(getQueryMonth(req))
-	j, err := h.compileEnding(end)
+	p, j, c, err := h.compileStmt(req)
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
-	c, err := h.config()
-	if err != nil {
-		http.Error(w, err.Error(), 500)
-		return
-	}
 
-	a := sortedAccounts(j)
-	b := j.Balances
+	r := reports.NewIncomeStatement(j, c, p.Start, p.End)
 	s := stmt{
 		StmtData: &templates.StmtData{
-			Title: "Income Statement",
-			Month: month.Format(end),
+			Title:  "Income Statement",
+			Period: p.String(),
+			Start:  p.Start.String(),
+			End:    p.End.String(),
 		},
 	}
-
 	s.addSection("Income")
-	// Income is credit balance.
-	b.Neg()
-	for _, a := range a {
-		if c.IsIncome(a) {
-			s.addAccount(a, b[a])
-		}
-	}
-	var income journal.Balance
-	income.Set(&s.bal)
-	s.addTotal("Total Income")
-
+	s.addStmtSection(&r.Income, "Total Income")
 	s.addSection("Expenses")
-	// Expenses are debit balance.
-	b.Neg()
-	for _, a := range a {
-		if c.IsExpenses(a) {
-			s.addAccount(a, b[a])
-		}
-	}
-	var expenses journal.Balance
-	expenses.Set(&s.bal)
-	s.addTotal("Total Expenses")
-
-	expenses.Neg()
-	income.AddBal(&expenses)
+	s.addStmtSection(&r.Expenses, "Total Expenses")
 	s.addSection("Net Profit")
-	s.addBalanceRows(templates.StmtRow{Description: "Total Net Profit"}, &income)
+	s.addBalanceRows(templates.StmtRow{Description: "Total Net Profit"}, &r.NetProfit)
 	h.execute(w, templates.Stmt, s.StmtData)
 }
 
 func (h handler) handleCapital(w http.ResponseWriter, req *http.Request) {
-	end := month.LastDay(getQueryMonth(req))
-	j, err := h.compileEnding(end)
-	if err != nil {
-		h.writeError(w, err)
-		return
-	}
-	c, err := h.config()
+	p, j, c, err := h.compileStmt(req)
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	start := month.FirstDay(end)
-	a := equityAccounts(c, sortedAccounts(j))
-	delta := accountFlows(j.Entries, a, start)
-	type fl struct {
-		a  journal.Account
-		am *journal.Amount
-	}
-	var infl, outfl []fl
-	for _, a := range delta.Accounts() {
-		for _, am := range delta[a].Amounts() {
-			switch am.Number.Sign() {
-			case -1:
-				outfl = append(outfl, fl{a, am})
-			case 1:
-				infl = append(infl, fl{a, am})
-			}
-		}
-	}
-
+	r := reports.NewCapitalStatement(j, c, p.Start, p.End)
 	s := stmt{
 		StmtData: &templates.StmtData{
-			Title: "Capital Statement",
-			Month: month.Format(end),
+			Title:  "Capital Statement",
+			Period: p.String(),
+			Start:  p.Start.String(),
+			End:    p.End.String(),
 		},
 	}
 	s.addSection("Starting Balances")
-	starting := j.BalancesEnding(start.AddDays(-1))
-	// Use positive equity
-	starting.Neg()
-	for _, a := range a {
-		s.addAccount(a, starting[a])
-	}
-	s.addTotal("Total Starting")
-
+	s.addStmtSection(&r.Starting, "Total Starting")
 	s.addSection("Increases")
-	for _, v := range infl {
-		s.addAccountAmount(v.a, v.am)
-	}
-	s.addTotal("Total Increases")
-
+	s.addStmtSection(&r.Increases, "Total Increases")
 	s.addSection("Decreases")
-	for _, v := range outfl {
-		s.addAccountAmount(v.a, v.am)
-	}
-	s.addTotal("Total Decreases")
-
+	s.addStmtSection(&r.Decreases, "Total Decreases")
 	s.addSection("Ending Balances")
-	// Use positive equity
-	j.Balances.Neg()
-	for _, a := range a {
-		s.addAccount(a, j.Balances[a])
-	}
-	s.addTotal("Total Ending")
-
+	s.addStmtSection(&r.Ending, "Total Ending")
 	h.execute(w, templates.Stmt, s.StmtData)
 }
 
 func (h handler) handleBalance(w http.ResponseWriter, req *http.Request) {
-	end := month.LastDay(getQueryMonth(req))
-	j, err := h.compileEnding(end)
+	p, j, c, err := h.compileStmt(req)
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
-	c, err := h.config()
+
+	r := reports.NewBalanceSheet(j, c, p.End)
+	s := stmt{
+		StmtData: &templates.StmtData{
+			Title:  "Balance Sheet",
+			Period: p.String(),
+			Start:  p.Start.String(),
+			End:    p.End.String(),
+		},
+		cfg:    c,
+		finC:   findat.NewClient(),
+		prices: j.Prices,
+		date:   p.End,
+		base:   c.BaseUnit(j),
+	}
+	s.addSection("Assets")
+	s.addStmtSection(&r.Assets, "Total Assets")
+	s.addSection("Liabilities")
+	s.addStmtSection(&r.Liabilities, "Total Liabilities")
+	s.addSection("Equity")
+	s.addStmtSection(&r.Equity, "Total Equity")
+	s.addRows(templates.StmtRow{})
+	s.addBalanceRows(templates.StmtRow{Description: "Total Liabilities & Equity"}, &r.LiabilitiesEquity)
+	h.execute(w, templates.Stmt, s.StmtData)
+}
+
+func (h handler) handleCash(w http.ResponseWriter, req *http.Request) {
+	p, j, c, err := h.compileStmt(req)
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	a := sortedAccounts(j)
-	b := j.Balances
+	r := reports.NewCashFlow(j, c, p.Start, p.End)
 	s := stmt{
 		StmtData: &templates.StmtData{
-			Title: "Balance Sheet",
-			Month: month.Format(end),
+			Title:  "Cash Flow",
+			Period: p.String(),
+			Start:  p.Start.String(),
+			End:    p.End.String(),
 		},
-		cfg:  c,
-		finC: findat.NewClient(),
 	}
+	s.addSection("Starting Balances")
+	s.addStmtSection(&r.Starting, "Total Starting")
+	s.addSection("Inflow")
+	s.addStmtSection(&r.Inflows, "Total Inflow")
+	s.addSection("Outflow")
+	s.addStmtSection(&r.Outflows, "Total Outflow")
+	s.addSection("Ending Balances")
+	s.addStmtSection(&r.Ending, "Total Ending")
+	h.execute(w, templates.Stmt, s.StmtData)
+}
 
-	s.addSection("Assets")
-	// Assets are debit balance.
-	for _, a := range a {
-		if c.IsAssets(a) {
-			s.addAccount(a, b[a])
-		}
+func (h handler) handleCompare(w http.ResponseWriter, req *http.Request) {
+	n, err := getQueryInt(req, "n", 12, maxPeriods)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, j, c, err := h.compileStmt(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	ps := period.Series(p, n)
+	d := templates.CompareData{
+		Report: req.URL.Query().Get("report"),
+		Period: p.String(),
+		Count:  n,
 	}
-	s.addTotal("Total Assets")
+	var r *reports.Comparative
+	switch d.Report {
+	case "balance":
+		d.Title = "Comparative Balance Sheet"
+		r = reports.NewComparativeBalanceSheet(j, c, ps)
+	default:
+		d.Report = "income"
+		d.Title = "Comparative Income Statement"
+		r = reports.NewComparativeIncomeStatement(j, c, ps)
+	}
+	for _, p := range r.Periods {
+		d.Periods = append(d.Periods, p.String())
+	}
+	d.Rows = makeCompareRows(r)
+	h.execute(w, templates.Compare, d)
+}
 
-	s.addSection("Liabilities")
-	// Liabilities are credit balance.
-	b.Neg()
-	for _, a := range a {
-		if c.IsLiabilities(a) {
-			s.addAccount(a, b[a])
+func (h handler) handleBudget(w http.ResponseWriter, req *http.Request) {
+	p, j, c, err := h.compileStmt(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	r := reports.NewBudgetReport(j, c, p.Start, p.End)
+	d := templates.BudgetData{
+		Period: p.String(),
+		Start:  p.Start.String(),
+		End:    p.End.String(),
+	}
+	for _, r := range r.Rows {
+		d.Rows = append(d.Rows, templates.BudgetRow{
+			Account:   r.Account,
+			Budgeted:  r.Budgeted,
+			Actual:    r.Actual,
+			Remaining: r.Remaining,
+			Used:      formatPercent(r.Used),
+			Over:      r.Over(),
+		})
+	}
+	h.execute(w, templates.Budget, d)
+}
+
+// getQueryPeriod returns the period in the request query.  For
+// compatibility, a month may be given instead.  The default is the
+// current month.
+func getQueryPeriod(req *http.Request, fiscalStart time.Month) period.Period {
+	q := req.URL.Query()
+	if v := q["period"]; len(v) > 0 {
+		if p, err := period.Parse(v[0], fiscalStart); err == nil {
+			return p
 		}
 	}
-	var liabilities journal.Balance
-	liabilities.Set(&s.bal)
-	s.addTotal("Total Liabilities")
-
-	s.addSection("Equity")
-	// Equity is credit balance.
-	for _, a := range a {
-		if c.IsEquity(a) || c.IsIncome(a) || c.IsExpenses(a) || c.IsTrading(a) {
-			s.addAccount(a, b[a])
+	if v := q["month"]; len(v) > 0 {
+		if p, err := period.Parse(v[0], fiscalStart); err == nil && p.Kind == period.Month {
+			return p
 		}
 	}
-	var equity journal.Balance
-	equity.Set(&s.bal)
-	s.addTotal("Total Equity")
-
-	equity.AddBal(&liabilities)
-	s.addRows(templates.StmtRow{})
-	s.addBalanceRows(templates.StmtRow{Description: "Total Liabilities & Equity"}, &equity)
-
-	h.execute(w, templates.Stmt, s.StmtData)
+	return period.Containing(period.Month, civil.DateOf(time.Now()), fiscalStart)
 }
 
-func (h handler) handleCash(w http.ResponseWriter, req *http.Request) {
-	end := month.LastDay(getQueryMonth(req))
-	j, err := h.compileEnding(end)
+func (h handler) handleGains(w http.ResponseWriter, req *http.Request) {
+	y := getQueryYear(req)
+	j, err := h.compile()
 	if err != nil {
 		h.writeError(w, err)
 		return
@@ -292,72 +307,44 @@ func (h handler) handleCash(w http.ResponseWriter, req *http.Request) {
 		h.writeError(w, err)
 		return
 	}
-
-	start := month.FirstDay(end)
-	a := cashAccounts(c, sortedAccounts(j))
-	delta := accountFlows(j.Entries, a, start)
-	// We want amounts to represent flow away from cash accounts.
-	delta.Neg()
-	type fl struct {
-		a  journal.Account
-		am *journal.Amount
-	}
-	var infl, outfl []fl
-	for _, a := range delta.Accounts() {
-		for _, am := range delta[a].Amounts() {
-			switch am.Number.Sign() {
-			case -1:
-				outfl = append(outfl, fl{a, am})
-			case 1:
-				infl = append(infl, fl{a, am})
-			}
-		}
-	}
-
-	s := stmt{
-		StmtData: &templates.StmtData{
-			Title: "Cash Flow",
-			Month: month.Format(end),
-		},
-	}
-	s.addSection("Starting Balances")
-	starting := j.BalancesEnding(start.AddDays(-1))
-	for _, a := range a {
-		s.addAccount(a, starting[a])
+	g, err := reports.NewCapitalGains(j, c.BaseUnit(j),
+		civil.Date{Year: y, Month: time.January, Day: 1},
+		civil.Date{Year: y, Month: time.December, Day: 31})
+	if err != nil {
+		h.writeError(w, err)
+		return
 	}
-	s.addTotal("Total Starting")
+	h.execute(w, templates.Gains, templates.GainsData{Year: y, Gains: g})
+}
 
-	s.addSection("Inflow")
-	for _, v := range infl {
-		s.addAccountAmount(v.a, v.am)
-	}
-	s.addTotal("Total Inflow")
+// maxPeriods is the maximum number of periods in a comparative
+// statement.
+const maxPeriods = 120
 
-	s.addSection("Outflow")
-	for _, v := range outfl {
-		s.addAccountAmount(v.a, v.am)
+// getQueryInt returns an integer between 1 and max in the request
+// query, or the default if it is not given.
+func getQueryInt(req *http.Request, key string, def, max int) (int, error) {
+	v := req.URL.Query()[key]
+	if len(v) == 0 {
+		return def, nil
 	}
-	s.addTotal("Total Outflow")
-
-	s.addSection("Ending Balances")
-	for _, a := range a {
-		s.addAccount(a, j.Balances[a])
+	n, err := strconv.Atoi(v[0])
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("invalid %s %q (must be between 1 and %d)", key, v[0], max)
 	}
-	s.addTotal("Total Ending")
-
-	h.execute(w, templates.Stmt, s.StmtData)
+	return n, nil
 }
 
-func getQueryMonth(req *http.Request) civil.Date {
-	v := req.URL.Query()["month"]
+func getQueryYear(req *http.Request) int {
+	v := req.URL.Query()["year"]
 	if len(v) == 0 {
-		return civil.DateOf(time.Now())
+		return time.Now().Year()
 	}
-	d, err := month.Parse(v[0])
+	y, err := strconv.Atoi(v[0])
 	if err != nil {
-		return civil.DateOf(time.Now())
+		return time.Now().Year()
 	}
-	return d
+	return y
 }
 
 func (h handler) handleLedger(w http.ResponseWriter, req *http.Request) {
@@ -373,27 +360,78 @@ func (h handler) handleLedger(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h handler) compile() (*journal.Journal, error) {
-	return journal.Compile(h.a)
+	return h.cache.Compile(h.a)
 }
 
+// compileEnding returns the journal with only the entries up to the
+// date, derived from the full journal.
 func (h handler) compileEnding(d civil.Date) (*journal.Journal, error) {
-	a2 := *h.a
-	a2.Ending = d
-	return journal.Compile(&a2)
+	j, err := h.compile()
+	if err != nil || !d.IsValid() {
+		return j, err
+	}
+	return j.Ending(d), nil
+}
+
+// compileStmt compiles the journal for the statement period in the
+// request, and loads the config for categorizing the accounts in the
+// journal.
+func (h handler) compileStmt(req *http.Request) (period.Period, *journal.Journal, *config.Config, error) {
+	c, err := h.config()
+	if err != nil {
+		return period.Period{}, nil, nil, err
+	}
+	p := getQueryPeriod(req, c.Period.FiscalStart())
+	j, err := h.compileEnding(p.End)
+	if err != nil {
+		return period.Period{}, nil, nil, err
+	}
+	if err := c.SetAccounts(j.Accounts); err != nil {
+		return period.Period{}, nil, nil, err
+	}
+	return p, j, c, nil
 }
 
 func (h handler) config() (*config.Config, error) {
-	c := &config.Config{}
 	if h.configPath == "" {
-		return c, nil
+		return &config.Config{}, nil
 	}
-	f, err := os.Open(h.configPath)
+	return h.configs.load(h.configPath)
+}
+
+// A configCache caches a loaded config file until the file's
+// modification time or size changes.
+type configCache struct {
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	c       *config.Config
+}
+
+// load returns a copy of the config in the file, loading it again if
+// it has changed.
+func (cc *configCache) load(path string) (*config.Config, error) {
+	fi, err := os.Stat(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	err = config.Load(c, f)
-	return c, err
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.c == nil || !fi.ModTime().Equal(cc.modTime) || fi.Size() != cc.size {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		c := &config.Config{}
+		if err := config.Load(c, f); err != nil {
+			return nil, err
+		}
+		cc.c, cc.modTime, cc.size = c, fi.ModTime(), fi.Size()
+	}
+	// Callers set the accounts of the config, so return a copy.
+	c := *cc.c
+	return &c, nil
 }
 
 func (h handler) writeError(w http.ResponseWriter, err error) {
@@ -462,7 +500,8 @@ func makeLedgerData(l *reports.AccountLedger) templates.LedgerData {
 	lastRef := ""
 	for _, r := range l.Rows {
 		r2 := templates.LedgerRow{
-			Pair: r.Pair,
+			RuleRef: r.RuleRef,
+			Pair:    r.Pair,
 		}
 		// The ledger template groups based on the date field.
 		// We deduplicate splits/rows from the same
@@ -485,21 +524,57 @@ func makeLedgerData(l *reports.AccountLedger) templates.LedgerData {
 	return d
 }
 
-func makeStmtRows(a []journal.Account, b journal.Balances) ([]templates.StmtRow, *journal.Balance) {
-	t := new(journal.Balance)
-	var r []templates.StmtRow
-	for _, a := range a {
-		for i, amt := range b[a].Amounts() {
-			e := templates.StmtRow{Amount: amt}
+func makeCompareRows(c *reports.Comparative) []templates.CompareRow {
+	var rows []templates.CompareRow
+	add := func(desc string, account bool, r reports.ComparativeRow) {
+		rows = append(rows, templates.CompareRow{
+			Description: desc,
+			Account:     account,
+			Amounts:     r.Amounts,
+			Variance:    r.Variance,
+			Change:      formatChange(r.Change),
+		})
+	}
+	for _, s := range c.Sections {
+		rows = append(rows, templates.CompareRow{Description: s.Title, Section: true})
+		last := journal.Account("")
+		for _, r := range s.Rows {
+			// Only show the account name on its first row.
+			desc := ""
+			if r.Account != last {
+				desc = string(r.Account)
+				last = r.Account
+			}
+			add(desc, desc != "", r)
+		}
+		for i, r := range s.Totals {
+			desc := ""
 			if i == 0 {
-				e.Description = string(a)
-				e.Account = true
+				desc = "Total " + s.Title
 			}
-			r = append(r, e)
-			t.Add(amt)
+			add(desc, false, r)
 		}
 	}
-	return r, t
+	return rows
+}
+
+// formatChange formats a fractional change as a signed percentage.
+func formatChange(r *big.Rat) string {
+	s := formatPercent(r)
+	if r != nil && r.Sign() > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// formatPercent formats a fraction as a percentage.
+func formatPercent(r *big.Rat) string {
+	if r == nil {
+		return ""
+	}
+	var p big.Rat
+	p.Mul(r, big.NewRat(100, 1))
+	return p.FloatString(1) + "%"
 }
 
 // balanceUnits returns all of the units in the balances.
@@ -523,11 +598,13 @@ func balanceUnits(b ...journal.Balance) []journal.Unit {
 // A stmt helps construct StmtData and add rows.
 type stmt struct {
 	*templates.StmtData
-	// Tracks the running total
-	bal journal.Balance
 
 	cfg  *config.Config
 	finC *findat.Client
+	// Used for converting amounts to the base unit as of date.
+	prices *journal.PriceDB
+	date   civil.Date
+	base   journal.Unit
 }
 
 func (s *stmt) addRows(r ...templates.StmtRow) {
@@ -538,12 +615,21 @@ func (s *stmt) addRows(r ...templates.StmtRow) {
 }
 
 // Adds unit conversion.
+// Historical prices are used if available.  Otherwise, live quotes
+// are used if the statement is for the current date or later.
 func (s *stmt) addConversion(r *templates.StmtRow) {
-	if s.cfg == nil || s.finC == nil || r.Amount == nil {
+	if s.cfg == nil || r.Amount == nil {
 		return
 	}
 	sym := r.Amount.Unit.Symbol
-	if sym == s.cfg.BaseUnitSymbol() {
+	if sym == s.base.Symbol {
+		return
+	}
+	if a, ok := s.prices.Convert(r.Amount, s.base, s.date); ok {
+		r.Amount2 = a
+		return
+	}
+	if s.finC == nil || s.date.Before(civil.DateOf(time.Now())) {
 		return
 	}
 	q, err := s.finC.GetQuote(sym)
@@ -552,7 +638,10 @@ func (s *stmt) addConversion(r *templates.StmtRow) {
 			"symbol", sym, "error", err)
 		return
 	}
-	r.Amount2 = convertAmount(r.Amount, q)
+	if q.CurrencyID != "" && q.CurrencyID != s.base.Symbol {
+		return
+	}
+	r.Amount2 = convertAmount(r.Amount, q, s.base)
 }
 
 // Adds a stmtRow with a balance.
@@ -575,43 +664,26 @@ func (s *stmt) addSection(desc string) {
 	})
 }
 
-// Adds rows for an account.
-// The account balance is added to a running total.
-func (s *stmt) addAccount(a journal.Account, b *journal.Balance) {
-	s.addBalanceRows(templates.StmtRow{
-		Description: string(a),
-		Account:     true,
-	}, b)
-	s.bal.AddBal(b)
-}
-
-// Like addAccount but with amount.
-func (s *stmt) addAccountAmount(a journal.Account, am *journal.Amount) {
-	s.addRows(templates.StmtRow{
-		Description: string(a),
-		Amount:      am,
-		Account:     true,
-	})
-	s.bal.Add(am)
-}
-
-// Add the current balance as a total.
-func (s *stmt) addTotal(desc string) {
-	s.addBalanceRows(templates.StmtRow{Description: desc}, &s.bal)
-	s.bal.Clear()
+// Adds rows for the accounts in a statement section, followed by the
+// section total.
+func (s *stmt) addStmtSection(sec *reports.StatementSection, total string) {
+	for _, r := range sec.Rows {
+		s.addBalanceRows(templates.StmtRow{
+			Description: string(r.Account),
+			Account:     true,
+		}, &r.Balance)
+	}
+	s.addBalanceRows(templates.StmtRow{Description: total}, &sec.Total)
 }
 
-func convertAmount(a *journal.Amount, q *finance.Quote) *journal.Amount {
-	a2 := &journal.Amount{Unit: journal.Unit{
-		Symbol: "USD",
-		Scale:  100,
-	}}
+func convertAmount(a *journal.Amount, q *finance.Quote, base journal.Unit) *journal.Amount {
+	a2 := &journal.Amount{Unit: base}
 	f := newFloat().SetFloat64(q.RegularMarketPrice)
 	defer floatPool.Put(f)
 	f2 := newFloat().SetInt(&a.Number)
 	defer floatPool.Put(f2)
 	f.Mul(f, f2)
-	f2.SetUint64(100)
+	f2.SetUint64(base.Scale)
 	f.Mul(f, f2)
 	f2.SetUint64(a.Unit.Scale)
 	f.Quo(f, f2)
@@ -622,105 +694,6 @@ func convertAmount(a *journal.Amount, q *finance.Quote) *journal.Amount {
 	return a2
 }
 
-// accountFlows returns where the balances of the given accounts
-// flowed to or from.
-func accountFlows(e []journal.Entry, a []journal.Account, start civil.Date) journal.Balances {
-	delta := accountDeltas(e, a, start)
-	for _, a := range a {
-		delta.Delete(a)
-	}
-	return delta
-}
-
-// accountDeltas returns the accumulated balance changes of all
-// transactions that affect the given accounts.
-func accountDeltas(e []journal.Entry, a []journal.Account, start civil.Date) journal.Balances {
-	e = filterEntries(e, newAccountPred(a).match)
-	delta := make(journal.Balances)
-	for _, e := range e {
-		t, ok := e.(*journal.Transaction)
-		if !ok {
-			continue
-		}
-		if e.Date().Before(start) {
-			continue
-		}
-		for _, s := range t.Splits {
-			delta.Add(s.Account, s.Amount)
-		}
-	}
-	return delta
-}
-
-// Filter entries related to the given account.
-func accountEntries(e []journal.Entry, a journal.Account) []journal.Entry {
-	return filterEntries(e, func(a2 journal.Account) bool {
-		return a2 == a
-	})
-}
-
-func filterEntries(e []journal.Entry, f func(journal.Account) bool) []journal.Entry {
-	var e2 []journal.Entry
-	for _, e := range e {
-		switch e := e.(type) {
-		case *journal.Transaction:
-			for _, s := range e.Splits {
-				if f(s.Account) {
-					e2 = append(e2, e)
-					break
-				}
-			}
-		case *journal.BalanceAssert:
-			if f(e.Account) {
-				e2 = append(e2, e)
-			}
-		case *journal.DisableAccount:
-			if f(e.Account) {
-				e2 = append(e2, e)
-			}
-		default:
-			panic(fmt.Sprintf("unknown entry %T", e))
-		}
-	}
-	return e2
-}
-
-// An accountPred can be used in filterEntries to match entries
-// matching any listed account.
-type accountPred map[journal.Account]bool
-
-func newAccountPred(a []journal.Account) accountPred {
-	p := make(accountPred)
-	for _, a := range a {
-		p[a] = true
-	}
-	return p
-}
-
-func (p accountPred) match(a journal.Account) bool {
-	return p[a]
-}
-
-func equityAccounts(c *config.Config, a []journal.Account) []journal.Account {
-	var new []journal.Account
-	for _, a := range a {
-		if c.IsEquity(a) || c.IsIncome(a) || c.IsExpenses(a) {
-			new = append(new, a)
-		}
-	}
-	return new
-}
-
-func cashAccounts(c *config.Config, a []journal.Account) []journal.Account {
-	var new []journal.Account
-	for _, a := range a {
-		if c.IsCash(a) {
-			new = append(new, a)
-		}
-	}
-	return new
-}
-
 func sortedAccounts(j *journal.Journal) []journal.Account {
 	var new []journal.Account
 	for a := range j.Accounts {
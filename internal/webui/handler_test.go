@@ -15,6 +15,7 @@
 package webui
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -32,7 +33,7 @@ func TestConvertAmount(t *testing.T) {
 		Scale:  1000,
 	}}
 	a.Number.SetInt64(54321)
-	got := convertAmount(a, q)
+	got := convertAmount(a, q, journal.Unit{Symbol: "USD", Scale: 100})
 	want := &journal.Amount{Unit: journal.Unit{
 		Symbol: "USD",
 		Scale:  100,
@@ -42,3 +43,21 @@ func TestConvertAmount(t *testing.T) {
 		t.Errorf("amount mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestFormatChange(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		r    *big.Rat
+		want string
+	}{
+		{nil, ""},
+		{big.NewRat(1, 8), "+12.5%"},
+		{big.NewRat(-1, 3), "-33.3%"},
+		{new(big.Rat), "0.0%"},
+	}
+	for _, c := range cases {
+		if got := formatChange(c.r); got != c.want {
+			t.Errorf("formatChange(%v) = %q; want %q", c.r, got, c.want)
+		}
+	}
+}
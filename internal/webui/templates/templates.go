@@ -80,8 +80,11 @@ type TrialRow struct {
 var Stmt = extendBase("stmt.html")
 
 type StmtData struct {
-	Title string
-	Month string // YYYY-MM
+	Title  string
+	Period string
+	// Start and End are the dates of the period (YYYY-MM-DD).
+	Start string
+	End   string
 	Rows  []StmtRow
 }
 
@@ -96,6 +99,54 @@ type StmtRow struct {
 	Amount2 *journal.Amount
 }
 
+var Compare = extendBase("compare.html")
+
+type CompareData struct {
+	Title string
+	// Report is the statement shown, income or balance.
+	Report  string
+	Period  string
+	Count   int
+	Periods []string
+	Rows    []CompareRow
+}
+
+type CompareRow struct {
+	Description string
+	// Indicates the row is a section header, giving it emphasis.
+	Section bool
+	// Indicates the description is an account name and makes it a
+	// link to the account's ledger page.
+	Account  bool
+	Amounts  []*journal.Amount
+	Variance *journal.Amount
+	// Change is the formatted percentage change.
+	Change string
+}
+
+var Budget = extendBase("budget.html")
+
+type BudgetData struct {
+	Period string
+	// Start and End are the dates of the period (YYYY-MM-DD).
+	Start string
+	End   string
+	Rows  []BudgetRow
+}
+
+func (BudgetData) Title() string { return "Budget" }
+
+type BudgetRow struct {
+	Account   journal.Account
+	Budgeted  *journal.Amount
+	Actual    *journal.Amount
+	Remaining *journal.Amount
+	// Used is the formatted percentage of the budget used.
+	Used string
+	// Indicates the account is over budget.
+	Over bool
+}
+
 var Ledger = extendBase("ledger.html")
 
 type LedgerData struct {
@@ -111,10 +162,21 @@ type LedgerRow struct {
 	Date        string
 	Description string
 	Ref         string
-	Pair        reports.Pair[*journal.Amount]
-	Balance     *journal.Amount
+	// RuleRef is the location of the rule that added the split.
+	RuleRef string
+	Pair    reports.Pair[*journal.Amount]
+	Balance *journal.Amount
 }
 
+var Gains = extendBase("gains.html")
+
+type GainsData struct {
+	Year  int
+	Gains *reports.CapitalGains
+}
+
+func (GainsData) Title() string { return "Capital Gains" }
+
 func clone(t *template.Template) *template.Template {
 	return template.Must(t.Clone())
 }
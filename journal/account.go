@@ -14,7 +14,11 @@
 
 package journal
 
-import "strings"
+import (
+	"strings"
+
+	"go.felesatra.moe/keeper/kpr/token"
+)
 
 // Account is a bookkeeping account.
 // Accounts are colon separated paths, like "Income:Salary".
@@ -60,6 +64,16 @@ type AccountInfo struct {
 	// disabled the account.  Otherwise this is nil.
 	Disabled *DisableAccount
 	Metadata map[string]string
+	// Booking is the booking method for lots in the account, set
+	// with the "booking" metadata key.
+	Booking BookingMethod
+	// PartialBalance is whether balance assertions for the
+	// account only assert the listed units, set with the
+	// "balance" metadata key.
+	PartialBalance bool
+	// DeclPos is the position of the first account entry
+	// declaring the account, if any.
+	DeclPos token.Position
 }
 
 func newAccountInfo() *AccountInfo {
@@ -60,6 +60,17 @@ func (a *Amount) String() string {
 	return decFormat(&a.Number, a.Unit.Scale) + " " + a.Unit.Symbol
 }
 
+// Decimal returns the number of the amount as a decimal string
+// without digit grouping or the unit, e.g., "-1234.50".
+func (a *Amount) Decimal() string {
+	r := newRat()
+	defer ratPool.Put(r)
+	r2 := newRat()
+	defer ratPool.Put(r2)
+	r.Quo(r.SetInt(&a.Number), r2.SetUint64(a.Unit.Scale))
+	return r.FloatString(log10(a.Unit.Scale))
+}
+
 // Unit describes a unit, e.g., currency or commodity.
 type Unit struct {
 	// Symbol for the unit.
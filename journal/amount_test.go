@@ -20,6 +20,23 @@ import (
 	"github.com/google/go-cmp/cmp"
 )
 
+func TestAmount_Decimal(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		a    *Amount
+		want string
+	}{
+		{amnt(-123450, Unit{Symbol: "USD", Scale: 100}), "-1234.50"},
+		{amnt(1234, Unit{Symbol: "JPY", Scale: 1}), "1234"},
+		{amnt(5, Unit{Symbol: "BTC", Scale: 1000}), "0.005"},
+	}
+	for _, c := range cases {
+		if got := c.a.Decimal(); got != c.want {
+			t.Errorf("Decimal(%s) = %q; want %q", c.a, got, c.want)
+		}
+	}
+}
+
 func TestBalance_Add(t *testing.T) {
 	t.Parallel()
 	u := Unit{Symbol: "USD", Scale: 100}
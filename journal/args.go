@@ -27,6 +27,15 @@ type CompileArgs struct {
 	// If set, only consider entries up to and including the
 	// specified date.
 	Ending civil.Date
+	// If set, transactions for the occurrences of recurring
+	// entries that are due up to and including the specified date
+	// are compiled along with the other entries.  See
+	// Journal.Due.
+	Forecast civil.Date
+	// Today is the current date, used for warning about
+	// transactions dated in the future.  If not set, the system
+	// date is used.
+	Today civil.Date
 }
 
 // A CompileInput defines an input source for Compile.
@@ -58,9 +67,15 @@ func Bytes(filename string, src []byte) CompileInput {
 
 type inputFile struct {
 	filename string
+	// fullName is whether the filename is used as is for
+	// position information instead of its base name.
+	fullName bool
 }
 
 func (o inputFile) Filename() string {
+	if o.fullName {
+		return o.filename
+	}
 	return filepath.Base(o.filename)
 }
 
@@ -80,3 +95,14 @@ func Files(filename ...string) []CompileInput {
 	}
 	return i
 }
+
+// Paths is like Files, but positions use the paths as given instead
+// of their base names, so files with the same name in different
+// directories can be told apart.
+func Paths(filename ...string) []CompileInput {
+	var i []CompileInput
+	for _, f := range filename {
+		i = append(i, inputFile{filename: f, fullName: true})
+	}
+	return i
+}
@@ -0,0 +1,185 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// An Interval is a period of time that something repeats every.
+type Interval int
+
+const (
+	Weekly Interval = iota + 1
+	Monthly
+	Quarterly
+	Yearly
+)
+
+var intervalNames = map[string]Interval{
+	"weekly":    Weekly,
+	"monthly":   Monthly,
+	"quarterly": Quarterly,
+	"yearly":    Yearly,
+}
+
+func (i Interval) String() string {
+	for k, v := range intervalNames {
+		if v == i {
+			return k
+		}
+	}
+	return fmt.Sprintf("Interval(%d)", int(i))
+}
+
+// Add returns the date n intervals after the date.  If the day does
+// not exist in the resulting month, the last day of the month is
+// used.
+func (i Interval) Add(d civil.Date, n int) civil.Date {
+	switch i {
+	case Weekly:
+		return d.AddDays(7 * n)
+	case Monthly:
+		return addMonths(d, n)
+	case Quarterly:
+		return addMonths(d, 3*n)
+	case Yearly:
+		return addMonths(d, 12*n)
+	default:
+		panic(fmt.Sprintf("unknown interval %d", i))
+	}
+}
+
+// addMonths adds months to a date, clamping the day to the end of
+// the month.
+func addMonths(d civil.Date, n int) civil.Date {
+	first := civil.DateOf(time.Date(d.Year, d.Month+time.Month(n), 1, 0, 0, 0, 0, time.UTC))
+	last := civil.DateOf(time.Date(first.Year, first.Month+1, 0, 0, 0, 0, 0, time.UTC))
+	if d.Day > last.Day {
+		return last
+	}
+	first.Day = d.Day
+	return first
+}
+
+// A Budget describes the amount budgeted for an account tree for each
+// interval, starting on a date.  A budget lasts until a later budget
+// for the same account and unit.
+type Budget struct {
+	EntryPos  token.Position
+	EntryDate civil.Date
+	Account   Account
+	Amount    *Amount
+	Interval  Interval
+}
+
+func (b *Budget) Position() token.Position {
+	return b.EntryPos
+}
+
+func (b *Budget) Date() civil.Date {
+	return b.EntryDate
+}
+
+// Budgets is a collection of budgets, sorted by date.
+type Budgets []*Budget
+
+func sortBudgets(b Budgets) {
+	sort.SliceStable(b, func(i, j int) bool {
+		return b[i].EntryDate.Before(b[j].EntryDate)
+	})
+}
+
+// A BudgetKey identifies the budgets for an account and unit.
+type BudgetKey struct {
+	Account Account
+	Unit    Unit
+}
+
+// Keys returns the accounts and units with budgets, sorted by
+// account and unit.
+func (b Budgets) Keys() []BudgetKey {
+	seen := make(map[BudgetKey]bool)
+	var keys []BudgetKey
+	for _, b := range b {
+		k := BudgetKey{b.Account, b.Amount.Unit}
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Account != keys[j].Account {
+			return keys[i].Account < keys[j].Account
+		}
+		return keys[i].Unit.Symbol < keys[j].Unit.Symbol
+	})
+	return keys
+}
+
+// Budgeted returns the amount budgeted for an account and unit
+// between the start and end dates, inclusive.  Intervals that are
+// partly in the date range are prorated by day.
+func (b Budgets) Budgeted(k BudgetKey, start, end civil.Date) *Amount {
+	var bs []*Budget
+	for _, b := range b {
+		if b.Account == k.Account && b.Amount.Unit == k.Unit {
+			bs = append(bs, b)
+		}
+	}
+	total := new(big.Rat)
+	for i, b := range bs {
+		// The last day that the budget applies.
+		last := end
+		if i+1 < len(bs) {
+			last = minDate(last, bs[i+1].EntryDate.AddDays(-1))
+		}
+		for n := 0; ; n++ {
+			is, ie := b.Interval.Add(b.EntryDate, n), b.Interval.Add(b.EntryDate, n+1).AddDays(-1)
+			if is.After(last) {
+				break
+			}
+			from, to := maxDate(is, start), minDate(ie, last)
+			if to.Before(from) {
+				continue
+			}
+			r := big.NewRat(int64(to.DaysSince(from)+1), int64(ie.DaysSince(is)+1))
+			total.Add(total, r.Mul(r, new(big.Rat).SetInt(&b.Amount.Number)))
+		}
+	}
+	a := &Amount{Unit: k.Unit}
+	roundRat(&a.Number, total)
+	return a
+}
+
+func minDate(a, b civil.Date) civil.Date {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxDate(a, b civil.Date) civil.Date {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
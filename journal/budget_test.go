@@ -0,0 +1,86 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInterval_Add(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		i    Interval
+		d    civil.Date
+		n    int
+		want civil.Date
+	}{
+		{Weekly, civil.Date{2020, 1, 29}, 1, civil.Date{2020, 2, 5}},
+		{Monthly, civil.Date{2020, 1, 31}, 1, civil.Date{2020, 2, 29}},
+		{Monthly, civil.Date{2020, 1, 31}, 2, civil.Date{2020, 3, 31}},
+		{Quarterly, civil.Date{2020, 11, 30}, 1, civil.Date{2021, 2, 28}},
+		{Yearly, civil.Date{2020, 2, 29}, 1, civil.Date{2021, 2, 28}},
+		{Monthly, civil.Date{2020, 1, 15}, -1, civil.Date{2019, 12, 15}},
+	}
+	for _, c := range cases {
+		if got := c.i.Add(c.d, c.n); got != c.want {
+			t.Errorf("%s.Add(%s, %d) = %s; want %s", c.i, c.d, c.n, got, c.want)
+		}
+	}
+}
+
+func TestBudgets_Budgeted(t *testing.T) {
+	t.Parallel()
+	j, err := Compile(&CompileArgs{
+		Inputs: []CompileInput{Bytes("testfile", []byte(`unit USD 100
+budget 2020-01 Expenses:Food 310 USD monthly
+budget 2020-03-01 Expenses:Food 600 USD monthly
+budget 2020-01-01 Expenses:Fun 70 USD weekly
+`))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usd := Unit{Symbol: "USD", Scale: 100}
+	food := BudgetKey{Account: "Expenses:Food", Unit: usd}
+	fun := BudgetKey{Account: "Expenses:Fun", Unit: usd}
+	if diff := cmp.Diff([]BudgetKey{food, fun}, j.Budgets.Keys()); diff != "" {
+		t.Errorf("keys mismatch (-want +got):\n%s", diff)
+	}
+	cases := []struct {
+		desc       string
+		k          BudgetKey
+		start, end civil.Date
+		want       string
+	}{
+		{"month", food, civil.Date{2020, 1, 1}, civil.Date{2020, 1, 31}, "310.00 USD"},
+		{"partial month", food, civil.Date{2020, 1, 1}, civil.Date{2020, 1, 10}, "100.00 USD"},
+		{"before budget", food, civil.Date{2019, 12, 1}, civil.Date{2019, 12, 31}, "0.00 USD"},
+		{"superseded", food, civil.Date{2020, 2, 1}, civil.Date{2020, 3, 31}, "910.00 USD"},
+		{"weekly", fun, civil.Date{2020, 1, 1}, civil.Date{2020, 1, 31}, "310.00 USD"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			got := j.Budgets.Budgeted(c.k, c.start, c.end).String()
+			if got != c.want {
+				t.Errorf("Got %s; want %s", got, c.want)
+			}
+		})
+	}
+}
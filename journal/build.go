@@ -16,6 +16,8 @@ package journal
 
 import (
 	"fmt"
+	"math/big"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -36,14 +38,33 @@ type builder struct {
 	fset     *token.FileSet
 	units    map[string]Unit
 	accounts AccountMap
-	errs     scanner.ErrorList
+	prices   []*Price
+	budgets  Budgets
+	// Recurring entries, in file order.
+	recurring []*Recurring
+	// Rules, in file order.
+	rules []*Rule
+	errs  scanner.ErrorList
+	// Used to annotate errors in included files.
+	includes includeChain
+
+	// Warnings found while building.
+	warnings DiagnosticList
+	// Date for warning about transactions in the future, if set.
+	today civil.Date
+	// Positions of unit declarations, by symbol.
+	unitPos map[string]token.Position
+	// Units that are used, by symbol.
+	usedUnits map[string]bool
 }
 
 func newBuilder(fset *token.FileSet) *builder {
 	return &builder{
-		fset:     fset,
-		units:    make(map[string]Unit),
-		accounts: make(AccountMap),
+		fset:      fset,
+		units:     make(map[string]Unit),
+		accounts:  make(AccountMap),
+		unitPos:   make(map[string]token.Position),
+		usedUnits: make(map[string]bool),
 	}
 }
 
@@ -73,6 +94,36 @@ func (b *builder) build(t ...ast.Entry) ([]Entry, error) {
 			b.addUnit(n)
 		case *ast.DeclareAccount:
 			b.buildDeclareAccount(n)
+		case *ast.Price:
+			p, err := b.buildPrice(n)
+			if err != nil {
+				continue
+			}
+			b.prices = append(b.prices, p)
+		case *ast.Budget:
+			e, err := b.buildBudget(n)
+			if err != nil {
+				continue
+			}
+			b.budgets = append(b.budgets, e)
+		case *ast.Rule:
+			e, err := b.buildRule(n)
+			if err != nil {
+				continue
+			}
+			b.rules = append(b.rules, e)
+		case *ast.Recurring:
+			e, err := b.buildRecurring(n)
+			if err != nil {
+				continue
+			}
+			b.recurring = append(b.recurring, e)
+		case *ast.Pad:
+			e, err := b.buildPad(n)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, e)
 		case *ast.DisableAccount:
 			e, err := b.buildDisableAccount(n)
 			if err != nil {
@@ -97,7 +148,11 @@ func (b *builder) nodePos(e ast.Node) token.Position {
 }
 
 func (b *builder) errorf(pos token.Pos, format string, v ...interface{}) {
-	b.errs.Add(b.fset.Position(pos), fmt.Sprintf(format, v...))
+	b.errorAt(b.fset.Position(pos), format, v...)
+}
+
+func (b *builder) errorAt(p token.Position, format string, v ...interface{}) {
+	b.errs.Add(p, b.includes.annotate(p, fmt.Sprintf(format, v...)))
 }
 
 func (b *builder) buildSingleBalance(n *ast.SingleBalance) (*BalanceAssert, error) {
@@ -105,11 +160,9 @@ func (b *builder) buildSingleBalance(n *ast.SingleBalance) (*BalanceAssert, erro
 	if err != nil {
 		return a, err
 	}
-	amount, err := b.buildAmount(n.Amount)
-	if err != nil {
+	if err := b.addBalanceAmount(a, n.Amount, n.Tolerance); err != nil {
 		return a, err
 	}
-	a.Declared.Add(amount)
 	return a, nil
 }
 
@@ -120,15 +173,41 @@ func (b *builder) buildMultiBalance(n *ast.MultiBalance) (*BalanceAssert, error)
 	}
 	for _, n := range n.Amounts {
 		line := n.(*ast.AmountLine)
-		amount, err := b.buildAmount(line.Amount)
-		if err != nil {
+		if err := b.addBalanceAmount(e, line.Amount, line.Tolerance); err != nil {
 			return e, err
 		}
-		e.Declared.Add(amount)
 	}
 	return e, nil
 }
 
+// addBalanceAmount adds an amount with an optional tolerance to a
+// balance assertion.
+func (b *builder) addBalanceAmount(e *BalanceAssert, n *ast.Amount, tol *ast.BasicValue) error {
+	a, err := b.buildAmount(n)
+	if err != nil {
+		return err
+	}
+	e.Declared.Add(a)
+	e.Listed = append(e.Listed, a.Unit)
+	if tol == nil {
+		return nil
+	}
+	assertKind(tol, token.TILDE)
+	t, err := b.buildAmount(&ast.Amount{
+		Decimal: &ast.BasicValue{
+			ValuePos: tol.ValuePos + 1,
+			Kind:     token.DECIMAL,
+			Value:    strings.TrimPrefix(tol.Value, "~"),
+		},
+		Unit: n.Unit,
+	})
+	if err != nil {
+		return err
+	}
+	e.Tolerance.Add(t)
+	return nil
+}
+
 func (b *builder) buildBalanceHeader(n *ast.BalanceHeader) (*BalanceAssert, error) {
 	assertKind(n.Date, token.DATE)
 	assertKind(n.Account, token.ACCTNAME)
@@ -136,6 +215,7 @@ func (b *builder) buildBalanceHeader(n *ast.BalanceHeader) (*BalanceAssert, erro
 	a := &BalanceAssert{
 		EntryPos: b.nodePos(n),
 		Account:  Account(n.Account.Value),
+		Partial:  n.Partial.IsValid(),
 	}
 	switch n.Token {
 	case token.BALANCE:
@@ -161,6 +241,7 @@ func (b *builder) buildTransaction(n *ast.Transaction) (*Transaction, error) {
 	t := &Transaction{
 		EntryPos:    b.nodePos(n),
 		Description: parseString(n.Description.Value),
+		Tags:        buildTags(n.Tags),
 	}
 	var err error
 	t.EntryDate, err = civil.ParseDate(n.Date.Value)
@@ -168,47 +249,306 @@ func (b *builder) buildTransaction(n *ast.Transaction) (*Transaction, error) {
 		b.errorf(n.Date.Pos(), "%s", err)
 		return t, err
 	}
+	if err := b.buildSplits(t, n, n.Splits); err != nil {
+		return t, err
+	}
+	b.checkTransaction(t)
+	return t, nil
+}
+
+func (b *builder) buildRecurring(n *ast.Recurring) (*Recurring, error) {
+	assertKind(n.Date, token.DATE)
+	assertKind(n.Interval, token.INTERVAL)
+	assertKind(n.Description, token.STRING)
+
+	t := &Transaction{
+		EntryPos:    b.nodePos(n),
+		Description: parseString(n.Description.Value),
+		Tags:        buildTags(n.Tags),
+	}
+	r := &Recurring{
+		EntryPos: t.EntryPos,
+		Interval: intervalNames[n.Interval.Value],
+		Count:    1,
+		Template: t,
+	}
+	if r.Interval == 0 {
+		panic(fmt.Sprintf("unknown interval %s", n.Interval.Value))
+	}
+	var err error
+	r.EntryDate, err = civil.ParseDate(n.Date.Value)
+	if err != nil {
+		b.errorf(n.Date.Pos(), "%s", err)
+		return r, err
+	}
+	t.EntryDate = r.EntryDate
+	if n.Count != nil {
+		assertKind(n.Count, token.DECIMAL)
+		r.Count, err = strconv.Atoi(strings.ReplaceAll(n.Count.Value, ",", ""))
+		if err != nil || r.Count < 1 {
+			b.errorf(n.Count.Pos(), "invalid recurring count %s", n.Count.Value)
+			return r, fmt.Errorf("invalid recurring count %s", n.Count.Value)
+		}
+	}
+	if err := b.buildSplits(t, n, n.Splits); err != nil {
+		return r, err
+	}
+	return r, nil
+}
 
+func (b *builder) buildRule(n *ast.Rule) (*Rule, error) {
+	assertKind(n.Description, token.STRING)
+	r := &Rule{
+		EntryPos:    b.nodePos(n),
+		Description: parseString(n.Description.Value),
+	}
+	var empty bool
+	var s *RuleSplit
+	for _, n := range n.Lines {
+		switch n := n.(type) {
+		case *ast.MatchLine:
+			if err := b.buildMatch(r, n); err != nil {
+				return r, err
+			}
+		case *ast.MetadataLine:
+			if s == nil {
+				b.errorf(n.Pos(), "rule metadata before first split")
+				return r, fmt.Errorf("rule metadata before first split")
+			}
+			s.Metadata = addMetadata(s.Metadata, n)
+		case *ast.SplitLine:
+			assertKind(n.Account, token.ACCTNAME)
+			r.Splits = append(r.Splits, RuleSplit{
+				Account: Account(n.Account.Value),
+				Tags:    buildTags(n.Tags),
+			})
+			s = &r.Splits[len(r.Splits)-1]
+			switch {
+			case n.Lot != nil:
+				b.errorf(n.Lot.Pos(), "lot annotation in rule")
+				return r, fmt.Errorf("lot annotation in rule")
+			case n.Percent != nil:
+				assertKind(n.Percent, token.PERCENT)
+				if r.Account == "" {
+					b.errorf(n.Percent.Pos(), "percentage in rule without account match")
+					return r, fmt.Errorf("percentage in rule without account match")
+				}
+				p := strings.ReplaceAll(strings.TrimSuffix(n.Percent.Value, "%"), ",", "")
+				s.Ratio = new(big.Rat)
+				if _, err := fmt.Sscan(p, s.Ratio); err != nil {
+					b.errorf(n.Percent.Pos(), "%s", err)
+					return r, err
+				}
+				s.Ratio.Quo(s.Ratio, big.NewRat(100, 1))
+			case n.Amount != nil:
+				a, err := b.buildAmount(n.Amount)
+				if err != nil {
+					return r, err
+				}
+				s.Amount = a
+			default:
+				if empty {
+					b.errorf(n.Pos(), "more than one split missing amount")
+					return r, fmt.Errorf("more than one split missing amount")
+				}
+				empty = true
+			}
+		default:
+			panic(fmt.Sprintf("unexpected line node %T", n))
+		}
+	}
+	if len(r.Splits) == 0 {
+		b.errorf(n.Pos(), "rule without splits")
+		return r, fmt.Errorf("rule without splits")
+	}
+	if r.Account == "" && !hasRuleAmount(r.Splits) {
+		// The unit of the split missing an amount can't be inferred.
+		b.errorf(n.Pos(), "rule without account match needs a split amount")
+		return r, fmt.Errorf("rule without account match needs a split amount")
+	}
+	return r, nil
+}
+
+// hasRuleAmount returns whether any of the rule splits has an amount.
+func hasRuleAmount(s []RuleSplit) bool {
+	for _, s := range s {
+		if s.Amount != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// buildMatch adds a match condition to a rule.
+func (b *builder) buildMatch(r *Rule, n *ast.MatchLine) error {
+	v := n.Value
+	switch v.Kind {
+	case token.ACCTNAME:
+		if r.Account != "" {
+			b.errorf(n.Pos(), "more than one account match")
+			return fmt.Errorf("more than one account match")
+		}
+		if len(r.Splits) > 0 {
+			b.errorf(n.Pos(), "match after split")
+			return fmt.Errorf("match after split")
+		}
+		r.Account = Account(v.Value)
+	case token.STRING:
+		if r.Pattern != nil {
+			b.errorf(n.Pos(), "more than one description match")
+			return fmt.Errorf("more than one description match")
+		}
+		re, err := regexp.Compile(parseString(v.Value))
+		if err != nil {
+			b.errorf(v.Pos(), "%s", err)
+			return err
+		}
+		r.Pattern = re
+	case token.TAG:
+		r.Tags = append(r.Tags, buildTags([]*ast.BasicValue{v})...)
+	default:
+		panic(fmt.Sprintf("unexpected match kind %s", v.Kind))
+	}
+	return nil
+}
+
+// buildSplits builds the splits of a transaction entry node n and
+// balances the transaction.
+func (b *builder) buildSplits(t *Transaction, n ast.Node, splits []ast.LineNode) error {
+	var nsplits int
+	for _, n := range splits {
+		if _, ok := n.(*ast.SplitLine); ok {
+			nsplits++
+		}
+	}
 	var empty *Split
-	var bal Balance
-	t.Splits = make([]Split, len(n.Splits))
-	for i, n := range n.Splits {
+	var s *Split
+	t.Splits = make([]Split, 0, nsplits)
+	for _, n := range splits {
+		if m, ok := n.(*ast.MetadataLine); ok {
+			// Metadata before the first split belongs to
+			// the transaction.
+			if s == nil {
+				t.Metadata = addMetadata(t.Metadata, m)
+			} else {
+				s.Metadata = addMetadata(s.Metadata, m)
+			}
+			continue
+		}
 		n := n.(*ast.SplitLine)
 		assertKind(n.Account, token.ACCTNAME)
-		s := &t.Splits[i]
+		t.Splits = append(t.Splits, Split{})
+		s = &t.Splits[len(t.Splits)-1]
 		s.Account = Account(n.Account.Value)
+		s.Tags = buildTags(n.Tags)
+		if n.Percent != nil {
+			b.errorf(n.Percent.Pos(), "percentage outside of rule")
+			return fmt.Errorf("percentage outside of rule")
+		}
 		if n.Amount == nil {
 			if empty != nil {
 				b.errorf(n.Pos(), "more than one split missing amount")
-				return t, fmt.Errorf("more than one split missing amount")
+				return fmt.Errorf("more than one split missing amount")
 			}
 			empty = s
 			continue
 		}
 		a, err := b.buildAmount(n.Amount)
 		if err != nil {
-			return t, err
+			return err
 		}
 		s.Amount = a
-		bal.Add(a)
+		if n.Lot != nil {
+			s.Lot, err = b.buildLot(n.Lot, a)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if needsBooking(t) {
+		// The transaction is balanced after booking.
+		return nil
+	}
+	if err := balanceTransaction(t); err != nil {
+		b.errorf(n.Pos(), "%s", err)
+		return err
+	}
+	return nil
+}
+
+// needsBooking returns whether the transaction has splits whose cost
+// is only known after booking against the lot inventory.
+func needsBooking(t *Transaction) bool {
+	for _, s := range t.Splits {
+		if s.Lot != nil && s.Lot.Cost == nil && s.Booked == nil && !s.Amount.Zero() {
+			return true
+		}
+	}
+	return false
+}
+
+// balanceTransaction checks that the transaction balances, inferring
+// the amount of the split missing an amount, if any.
+func balanceTransaction(t *Transaction) error {
+	var empty *Split
+	var bal Balance
+	for i := range t.Splits {
+		s := &t.Splits[i]
+		if s.Amount == nil {
+			empty = s
+			continue
+		}
+		if !s.addWeight(&bal) {
+			panic(fmt.Sprintf("split %v not booked", s))
+		}
 	}
 	switch empty {
 	case nil:
 		if !bal.Empty() {
-			b.errorf(n.Pos(), "transaction doesn't balance (off by %s)", &bal)
-			return t, fmt.Errorf("transaction doesn't balance (off by %s)", &bal)
+			return fmt.Errorf("transaction doesn't balance (off by %s)", &bal)
 		}
 	default:
 		amounts := bal.Amounts()
 		if len(amounts) != 1 {
-			b.errorf(n.Pos(), "cannot infer missing split amount with balance %s", &bal)
-			return t, fmt.Errorf("cannot infer missing split amount with balance %s", &bal)
+			return fmt.Errorf("cannot infer missing split amount with balance %s", &bal)
 		}
 		a := amounts[0]
 		a.Neg()
 		empty.Amount = a
 	}
-	return t, nil
+	return nil
+}
+
+func (b *builder) buildLot(n *ast.Lot, a *Amount) (*LotSpec, error) {
+	l := &LotSpec{}
+	if n.Cost != nil {
+		var err error
+		l.Cost, l.CostUnit, err = b.buildRate(n.Cost)
+		if err != nil {
+			return nil, err
+		}
+		if l.Cost.Sign() <= 0 {
+			b.errorf(n.Cost.Pos(), "lot cost must be positive")
+			return nil, fmt.Errorf("lot cost must be positive")
+		}
+		if l.CostUnit == a.Unit {
+			b.errorf(n.Cost.Unit.Pos(), "lot cost of unit %s in itself", a.Unit)
+			return nil, fmt.Errorf("lot cost of unit %s in itself", a.Unit)
+		}
+	} else if a.Sign() > 0 {
+		b.errorf(n.Pos(), "cannot add lot of %s without cost", a.Unit)
+		return nil, fmt.Errorf("cannot add lot of %s without cost", a.Unit)
+	}
+	if n.Date != nil {
+		assertKind(n.Date, token.DATE)
+		var err error
+		l.Date, err = civil.ParseDate(n.Date.Value)
+		if err != nil {
+			b.errorf(n.Date.Pos(), "%s", err)
+			return nil, err
+		}
+	}
+	return l, nil
 }
 
 func (b *builder) buildAmount(n *ast.Amount) (*Amount, error) {
@@ -225,15 +565,9 @@ func (b *builder) buildAmount(n *ast.Amount) (*Amount, error) {
 		return nil, err
 	}
 
-	sym := n.Unit.Value
-	if !validateUnit(sym) {
-		b.errorf(n.Unit.Pos(), "bad unit %s", sym)
-		return nil, fmt.Errorf("bad unit %s", sym)
-	}
-	u, ok := b.units[sym]
-	if !ok {
-		b.errorf(n.Unit.Pos(), "undeclared unit %s", sym)
-		return nil, fmt.Errorf("undeclared unit %s", sym)
+	u, err := b.lookupUnit(n.Unit)
+	if err != nil {
+		return nil, err
 	}
 
 	r2 := newRat()
@@ -251,6 +585,100 @@ func (b *builder) buildAmount(n *ast.Amount) (*Amount, error) {
 	return a, nil
 }
 
+// lookupUnit returns the declared unit for a unit symbol node.
+func (b *builder) lookupUnit(n *ast.BasicValue) (Unit, error) {
+	sym := n.Value
+	if !validateUnit(sym) {
+		b.errorf(n.Pos(), "bad unit %s", sym)
+		return Unit{}, fmt.Errorf("bad unit %s", sym)
+	}
+	u, ok := b.units[sym]
+	if !ok {
+		b.errorf(n.Pos(), "undeclared unit %s", sym)
+		return Unit{}, fmt.Errorf("undeclared unit %s", sym)
+	}
+	b.usedUnits[sym] = true
+	return u, nil
+}
+
+func (b *builder) buildPrice(n *ast.Price) (*Price, error) {
+	assertKind(n.Date, token.DATE)
+	assertKind(n.Unit, token.USYMBOL)
+	assertKind(n.Amount.Decimal, token.DECIMAL)
+	assertKind(n.Amount.Unit, token.USYMBOL)
+	p := &Price{
+		EntryPos: b.nodePos(n),
+	}
+	var err error
+	p.EntryDate, err = civil.ParseDate(n.Date.Value)
+	if err != nil {
+		b.errorf(n.Date.Pos(), "%s", err)
+		return p, err
+	}
+	if p.Unit, err = b.lookupUnit(n.Unit); err != nil {
+		return p, err
+	}
+	if p.Rate, p.Quote, err = b.buildRate(n.Amount); err != nil {
+		return p, err
+	}
+	if p.Unit == p.Quote {
+		b.errorf(n.Amount.Unit.Pos(), "price of unit %s in itself", p.Unit)
+		return p, fmt.Errorf("price of unit %s in itself", p.Unit)
+	}
+	if p.Rate.Sign() <= 0 {
+		b.errorf(n.Amount.Decimal.Pos(), "price must be positive")
+		return p, fmt.Errorf("price must be positive")
+	}
+	return p, nil
+}
+
+func (b *builder) buildBudget(n *ast.Budget) (*Budget, error) {
+	assertKind(n.Date, token.DATE)
+	assertKind(n.Account, token.ACCTNAME)
+	assertKind(n.Interval, token.INTERVAL)
+	e := &Budget{
+		EntryPos: b.nodePos(n),
+		Account:  Account(n.Account.Value),
+		Interval: intervalNames[n.Interval.Value],
+	}
+	if e.Interval == 0 {
+		panic(fmt.Sprintf("unknown interval %s", n.Interval.Value))
+	}
+	d := n.Date.Value
+	if len(d) == len("2006-01") {
+		// Months start on the first day.
+		d += "-01"
+	}
+	var err error
+	e.EntryDate, err = civil.ParseDate(d)
+	if err != nil {
+		b.errorf(n.Date.Pos(), "%s", err)
+		return e, err
+	}
+	e.Amount, err = b.buildAmount(n.Amount)
+	if err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+// buildRate builds an unscaled amount of a unit, such as a price or
+// cost.
+func (b *builder) buildRate(n *ast.Amount) (*big.Rat, Unit, error) {
+	assertKind(n.Decimal, token.DECIMAL)
+	u, err := b.lookupUnit(n.Unit)
+	if err != nil {
+		return nil, u, err
+	}
+	r := new(big.Rat)
+	s := strings.Replace(n.Decimal.Value, ",", "", -1)
+	if _, err := fmt.Sscan(s, r); err != nil {
+		b.errorf(n.Decimal.Pos(), "%s", err)
+		return nil, u, err
+	}
+	return r, u, nil
+}
+
 func (b *builder) buildDisableAccount(n *ast.DisableAccount) (*DisableAccount, error) {
 	assertKind(n.Date, token.DATE)
 	assertKind(n.Account, token.ACCTNAME)
@@ -267,22 +695,95 @@ func (b *builder) buildDisableAccount(n *ast.DisableAccount) (*DisableAccount, e
 	return e, nil
 }
 
+// buildPad builds the transaction for a pad entry.  The splits are
+// added when the journal is compiled.
+func (b *builder) buildPad(n *ast.Pad) (*Transaction, error) {
+	assertKind(n.Date, token.DATE)
+	assertKind(n.Account, token.ACCTNAME)
+	assertKind(n.Source, token.ACCTNAME)
+	p := &Pad{
+		EntryPos: b.nodePos(n),
+		Account:  Account(n.Account.Value),
+		Source:   Account(n.Source.Value),
+	}
+	t := &Transaction{
+		EntryPos:    p.EntryPos,
+		Description: "Padding",
+		Pad:         p,
+	}
+	var err error
+	p.EntryDate, err = civil.ParseDate(n.Date.Value)
+	if err != nil {
+		b.errorf(n.Date.Pos(), "%s", err)
+		return t, err
+	}
+	t.EntryDate = p.EntryDate
+	if p.Account == p.Source {
+		b.errorf(n.Source.Pos(), "pad %s from itself", p.Account)
+		return t, fmt.Errorf("pad %s from itself", p.Account)
+	}
+	return t, nil
+}
+
 func (b *builder) buildDeclareAccount(n *ast.DeclareAccount) {
 	assertKind(n.Account, token.ACCTNAME)
 	a := Account(n.Account.Value)
 	ai := b.accounts[a]
 	if ai == nil {
 		ai = newAccountInfo()
+		ai.DeclPos = b.nodePos(n)
 		b.accounts[a] = ai
 	}
 	for _, n := range n.Metadata {
 		m := n.(*ast.MetadataLine)
 		assertKind(m.Key, token.STRING)
 		assertKind(m.Val, token.STRING)
-		ai.Metadata[parseString(m.Key.Value)] = parseString(m.Val.Value)
+		k, v := parseString(m.Key.Value), parseString(m.Val.Value)
+		if k == bookingKey {
+			bm, ok := bookingMethods[v]
+			if !ok {
+				b.errorf(m.Val.Pos(), "unknown booking method %q", v)
+				continue
+			}
+			ai.Booking = bm
+		}
+		if k == balanceKey {
+			switch v {
+			case "full":
+				ai.PartialBalance = false
+			case "partial":
+				ai.PartialBalance = true
+			default:
+				b.errorf(m.Val.Pos(), "unknown balance mode %q", v)
+				continue
+			}
+		}
+		ai.Metadata[k] = v
 	}
 }
 
+// addMetadata adds a metadata line to a metadata map, allocating the
+// map if needed.
+func addMetadata(md map[string]string, m *ast.MetadataLine) map[string]string {
+	assertKind(m.Key, token.STRING)
+	assertKind(m.Val, token.STRING)
+	if md == nil {
+		md = make(map[string]string)
+	}
+	md[parseString(m.Key.Value)] = parseString(m.Val.Value)
+	return md
+}
+
+// buildTags returns tag names without the leading #.
+func buildTags(n []*ast.BasicValue) []string {
+	var tags []string
+	for _, n := range n {
+		assertKind(n, token.TAG)
+		tags = append(tags, strings.TrimPrefix(n.Value, "#"))
+	}
+	return tags
+}
+
 func (b *builder) addUnit(n *ast.UnitDecl) {
 	assertKind(n.Unit, token.USYMBOL)
 	assertKind(n.Scale, token.DECIMAL)
@@ -315,6 +816,9 @@ func (b *builder) addUnit(n *ast.UnitDecl) {
 		b.errorf(n.Unit.Pos(), "unit %s redeclared with different scale", unit)
 		return
 	}
+	if _, ok := b.units[unit]; !ok {
+		b.unitPos[unit] = b.nodePos(n)
+	}
 	b.units[unit] = u
 }
 
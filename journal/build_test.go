@@ -46,6 +46,7 @@ end
 			EntryPos:  token.Position{Offset: 13, Line: 2, Column: 1},
 			Account:   "Some:account",
 			Declared:  new(balFac).add(u, -120).bal(),
+			Listed:    []Unit{u},
 		},
 		&Transaction{
 			EntryDate:   civil.Date{2001, 2, 3},
@@ -63,6 +64,7 @@ end
 	want2 := AccountMap{
 		"Some:account": {
 			Metadata: map[string]string{"nilou": "nahida"},
+			DeclPos:  token.Position{Offset: 122, Line: 7, Column: 1},
 		},
 	}
 	if diff := cmpdiff(want2, b.accounts); diff != "" {
@@ -92,6 +94,7 @@ end
 				"mir":    "jakuri",
 				"nilou":  "kokomi",
 			},
+			DeclPos: token.Position{Line: 1, Column: 1},
 		},
 	}
 	if diff := cmpdiff(want, b.accounts); diff != "" {
@@ -99,6 +102,42 @@ end
 	}
 }
 
+func TestBuildEntries_tx_metadata_and_tags(t *testing.T) {
+	t.Parallel()
+	const input = `unit USD 100
+tx 2001-02-03 "Dinner" #food #trip/2001
+meta "payee" "Bistro"
+Expenses:Food 30 USD #work
+meta "receipt" "r1.pdf"
+Assets:Cash
+end
+`
+	_, got, err := parseAndBuild(inputBytes{"", []byte(input)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := Unit{Symbol: "USD", Scale: 100}
+	s1 := split("Expenses:Food", 3000, u)
+	s1.Metadata = map[string]string{"receipt": "r1.pdf"}
+	s1.Tags = []string{"work"}
+	want := []Entry{
+		&Transaction{
+			EntryDate:   civil.Date{2001, 2, 3},
+			EntryPos:    token.Position{Offset: 13, Line: 2, Column: 1},
+			Description: "Dinner",
+			Metadata:    map[string]string{"payee": "Bistro"},
+			Tags:        []string{"food", "trip/2001"},
+			Splits: []Split{
+				s1,
+				split("Assets:Cash", -3000, u),
+			},
+		},
+	}
+	if diff := cmpdiff(want, got); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestBuildEntries_unbalanced(t *testing.T) {
 	t.Parallel()
 	const input = `unit USD 100
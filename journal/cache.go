@@ -0,0 +1,136 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"crypto/sha256"
+	"slices"
+	"sync"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/parser"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// A Cache caches parsed files and the compiled journal between
+// compiles, for programs that compile the same journal repeatedly.
+// Files are identified by the hash of their contents, so only
+// changed files are parsed again.
+//
+// The positions of replaced versions of changed files are kept, so
+// the memory used by a cache grows slowly as files change.
+//
+// A Cache is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	fset  *token.FileSet
+	files map[cacheKey]*cachedFile
+	last  *cachedJournal
+}
+
+// NewCache returns a new, empty Cache.
+func NewCache() *Cache {
+	return &Cache{
+		fset:  token.NewFileSet(),
+		files: make(map[cacheKey]*cachedFile),
+	}
+}
+
+// Compile is like the Compile function, but uses the cache.  If the
+// arguments and the contents of all input and included files are the
+// same as for the last successful compile, the same Journal is
+// returned, so callers must not modify returned journals.
+func (c *Cache) Compile(a *CompileArgs) (*Journal, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return compileCached(a, c)
+}
+
+// A cacheKey identifies a parsed file.  See includeParser.parse.
+type cacheKey struct {
+	path, filename string
+}
+
+// A sourceHash is the hash of the source of a parsed file.
+type sourceHash struct {
+	key  cacheKey
+	hash [sha256.Size]byte
+}
+
+type cachedFile struct {
+	hash [sha256.Size]byte
+	file *ast.File
+}
+
+type cachedJournal struct {
+	ending   civil.Date
+	forecast civil.Date
+	today    civil.Date
+	sources  []sourceHash
+	journal  *Journal
+}
+
+// parse parses a file, returning the cached file if the source has
+// not changed.  Files with errors are not cached.
+func (c *Cache) parse(h sourceHash, src []byte) (*ast.File, error) {
+	if f, ok := c.files[h.key]; ok && f.hash == h.hash {
+		return f.file, nil
+	}
+	delete(c.files, h.key)
+	f, err := parser.ParseBytes(c.fset, h.key.filename, src, 0)
+	if err != nil {
+		return f, err
+	}
+	c.files[h.key] = &cachedFile{hash: h.hash, file: f}
+	return f, nil
+}
+
+// prune removes the files that were not parsed in a compile.
+func (c *Cache) prune(sources []sourceHash) {
+	used := make(map[cacheKey]bool, len(sources))
+	for _, h := range sources {
+		used[h.key] = true
+	}
+	for k := range c.files {
+		if !used[k] {
+			delete(c.files, k)
+		}
+	}
+}
+
+// lookup returns the last compiled journal if it was compiled with
+// the same arguments and sources, or nil otherwise.
+func (c *Cache) lookup(a *CompileArgs, today civil.Date, sources []sourceHash) *Journal {
+	l := c.last
+	if l == nil || l.ending != a.Ending || l.forecast != a.Forecast || l.today != today {
+		return nil
+	}
+	if !slices.Equal(l.sources, sources) {
+		return nil
+	}
+	return l.journal
+}
+
+// store stores a compiled journal for lookup.
+func (c *Cache) store(a *CompileArgs, today civil.Date, sources []sourceHash, j *Journal) {
+	c.last = &cachedJournal{
+		ending:   a.Ending,
+		forecast: a.Forecast,
+		today:    today,
+		sources:  sources,
+		journal:  j,
+	}
+}
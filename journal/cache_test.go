@@ -0,0 +1,170 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCache_Compile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.kpr": `unit USD 100
+include "food.kpr"
+`,
+		"food.kpr": `tx 2020-01-01 "Lunch"
+Expenses:Food 5 USD
+Assets:Cash
+end
+`,
+	})
+	main := filepath.Join(dir, "main.kpr")
+	a := &CompileArgs{
+		Inputs: Files(main),
+		Today:  civil.Date{2020, 2, 1},
+	}
+	c := NewCache()
+	j, err := c.Compile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j2, err := c.Compile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j2 != j {
+		t.Errorf("Got a new journal for unchanged files")
+	}
+	mainKey := cacheKey{path: main, filename: a.Inputs[0].Filename()}
+	mainFile := c.files[mainKey].file
+
+	writeFiles(t, dir, map[string]string{
+		"food.kpr": `tx 2020-01-01 "Lunch"
+Expenses:Food 7 USD
+Assets:Cash
+end
+`,
+	})
+	j3, err := c.Compile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j3 == j {
+		t.Errorf("Got the cached journal for changed files")
+	}
+	if got, want := j3.Balances["Expenses:Food"].String(), "7.00 USD"; got != want {
+		t.Errorf("Got balance %s; want %s", got, want)
+	}
+	if c.files[mainKey].file != mainFile {
+		t.Errorf("Unchanged file was parsed again")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "food.kpr")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Compile(a); err == nil {
+		t.Errorf("Expected error for missing included file")
+	}
+}
+
+func TestJournal_Ending(t *testing.T) {
+	t.Parallel()
+	const input = `unit USD 100
+unit VTI 1000
+account Assets:Broker
+meta "booking" "fifo"
+end
+tx 2020-01-01 "Buy"
+Assets:Broker 10 VTI {100 USD}
+Assets:Cash
+end
+tx 2020-02-01 "Buy"
+Assets:Broker 10 VTI {120 USD}
+Assets:Cash
+end
+balance 2020-02-02 Assets:Cash -2000 USD
+tx 2020-03-01 "Sell"
+Assets:Broker -15 VTI {}
+Assets:Cash 1800 USD
+Income:Gains
+end
+tx 2020-03-02 "Lunch"
+Expenses:Food 5 USD
+Assets:Cash
+end
+disable 2020-04-01 Income:Gains
+`
+	full, err := compileText(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []civil.Date{
+		{2019, 12, 31},
+		{2020, 1, 15},
+		{2020, 2, 2},
+		{2020, 3, 1},
+		{2020, 4, 1},
+	} {
+		d := d
+		t.Run(d.String(), func(t *testing.T) {
+			t.Parallel()
+			want, err := Compile(&CompileArgs{
+				Inputs: []CompileInput{Bytes("testfile", []byte(input))},
+				Ending: d,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := full.Ending(d)
+			if diff := cmpdiff(want.Balances, got.Balances); diff != "" {
+				t.Errorf("balances mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(endingSummary(want), endingSummary(got)); diff != "" {
+				t.Errorf("journal mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// endingSummary summarizes the parts of a journal that depend on
+// CompileArgs.Ending.
+func endingSummary(j *Journal) map[string][]string {
+	s := make(map[string][]string)
+	for _, e := range j.Entries {
+		s["entries"] = append(s["entries"], e.Date().String())
+	}
+	for a, ai := range j.Accounts {
+		if ai.Disabled != nil {
+			s["accounts"] = append(s["accounts"], string(a)+" disabled")
+		} else {
+			s["accounts"] = append(s["accounts"], string(a))
+		}
+	}
+	for a, inv := range j.Inventories {
+		s["lots "+string(a)] = formatLots(inv)
+	}
+	for _, b := range j.BalanceErrors {
+		s["balance errors"] = append(s["balance errors"], string(b.Account))
+	}
+	slices.Sort(s["accounts"])
+	return s
+}
@@ -0,0 +1,284 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.felesatra.moe/keeper/kpr/scanner"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// A Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Diagnostic codes.  Codes are stable identifiers for kinds of
+// diagnostics.
+const (
+	// Syntax errors and errors including files.
+	CodeSyntax = "syntax"
+	// Invalid entries, such as transactions that don't balance.
+	CodeInvalidEntry = "invalid-entry"
+	// Errors applying rules to transactions.
+	CodeRule = "rule"
+	// Entries using disabled accounts.
+	CodeDisabledAccount = "disabled-account"
+	// Errors booking lots.
+	CodeLot = "lot"
+	// Pad entries that conflict with another pad entry.
+	CodePad = "pad"
+	// Failed balance assertions, including disabling accounts
+	// with a nonzero balance.
+	CodeBalance = "balance"
+	// Errors reading inputs.
+	CodeInput = "input"
+
+	// Declared units that are never used.
+	CodeUnusedUnit = "unused-unit"
+	// Accounts used without a declaration.  This is only
+	// reported if any accounts are declared.
+	CodeUndeclaredAccount = "undeclared-account"
+	// Transactions dated after the current date.
+	CodeFutureDate = "future-date"
+	// Transactions with an empty description.
+	CodeEmptyDescription = "empty-description"
+)
+
+// A Diagnostic is an error or warning found when compiling a journal.
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	// Code identifies the kind of diagnostic.  See the Code
+	// constants.
+	Code string
+	Msg  string
+	// Account is the account the diagnostic is about, if any.
+	Account Account
+	// Balance is the failed balance assertion for CodeBalance
+	// diagnostics.
+	Balance *BalanceAssert
+	// Related contains other positions relevant to the
+	// diagnostic, such as the entry disabling an account.
+	Related []token.Position
+}
+
+// Error implements the error interface.
+func (d *Diagnostic) Error() string {
+	if d.Pos.Filename != "" || d.Pos.IsValid() {
+		return d.Pos.String() + ": " + d.Msg
+	}
+	return d.Msg
+}
+
+// A DiagnosticList is a list of diagnostics.
+type DiagnosticList []*Diagnostic
+
+// Error implements the error interface.
+func (l DiagnosticList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more diagnostics)", l[0], len(l)-1)
+}
+
+// Err returns the list as an error if it contains any errors, or nil
+// otherwise.
+func (l DiagnosticList) Err() error {
+	if l.HasErrors() {
+		return l
+	}
+	return nil
+}
+
+// HasErrors returns whether the list contains any diagnostics with
+// SeverityError.
+func (l DiagnosticList) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort sorts the list by severity, then position.
+func (l DiagnosticList) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i], l[j]
+		if a.Severity != b.Severity {
+			return a.Severity < b.Severity
+		}
+		p, q := a.Pos, b.Pos
+		if p.Filename != q.Filename {
+			return p.Filename < q.Filename
+		}
+		if p.Line != q.Line {
+			return p.Line < q.Line
+		}
+		return p.Column < q.Column
+	})
+}
+
+// add adds the diagnostics for an error.  Errors that are not
+// diagnostics are given the code.
+func (l *DiagnosticList) add(code string, err error) {
+	var dl DiagnosticList
+	if errors.As(err, &dl) {
+		*l = append(*l, dl...)
+		return
+	}
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		*l = append(*l, d)
+		return
+	}
+	var el scanner.ErrorList
+	if errors.As(err, &el) {
+		for _, e := range el {
+			*l = append(*l, &Diagnostic{
+				Pos:      e.Pos,
+				Severity: SeverityError,
+				Code:     code,
+				Msg:      e.Msg,
+			})
+		}
+		return
+	}
+	*l = append(*l, &Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Msg:      err.Error(),
+	})
+}
+
+// entryError returns an error diagnostic for the entry at a position.
+func entryError(pos token.Position, code string, related []token.Position, format string, v ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Pos:      pos,
+		Severity: SeverityError,
+		Code:     code,
+		Msg:      fmt.Sprintf(format, v...),
+		Related:  related,
+	}
+}
+
+// compileError returns the error for a failed compile, with the
+// diagnostics for the error and the warnings found so far.
+func compileError(warnings DiagnosticList, code string, err error) error {
+	var l DiagnosticList
+	l.add(code, err)
+	l = append(l, warnings...)
+	l.Sort()
+	return fmt.Errorf("compile journal: %w", l)
+}
+
+// balanceDiagnostics returns the diagnostics for failed balance
+// assertions.
+func balanceDiagnostics(errs []*BalanceAssert) DiagnosticList {
+	var l DiagnosticList
+	for _, e := range errs {
+		d := entryError(e.EntryPos, CodeBalance, nil,
+			"balance of %s is %v, declared %v (diff %v)", e.Account, &e.Actual, &e.Declared, &e.Diff)
+		d.Account = e.Account
+		d.Balance = e
+		l = append(l, d)
+	}
+	return l
+}
+
+// warnf adds a warning for the position and returns it.
+func (b *builder) warnf(pos token.Position, code string, format string, v ...interface{}) *Diagnostic {
+	d := &Diagnostic{
+		Pos:      pos,
+		Severity: SeverityWarning,
+		Code:     code,
+		Msg:      b.includes.annotate(pos, fmt.Sprintf(format, v...)),
+	}
+	b.warnings = append(b.warnings, d)
+	return d
+}
+
+// checkTransaction adds warnings for a transaction.
+func (b *builder) checkTransaction(t *Transaction) {
+	if t.Description == "" {
+		b.warnf(t.EntryPos, CodeEmptyDescription, "transaction has empty description")
+	}
+	if b.today.IsValid() && t.EntryDate.After(b.today) {
+		b.warnf(t.EntryPos, CodeFutureDate, "transaction dated in the future (%s)", t.EntryDate)
+	}
+}
+
+// checkAccountsDeclared adds warnings for the first use of each
+// undeclared account, if any accounts are declared.
+// Entries should be sorted.
+func (b *builder) checkAccountsDeclared(e []Entry) {
+	if len(b.accounts) == 0 {
+		return
+	}
+	seen := make(map[Account]bool)
+	check := func(pos token.Position, a Account) {
+		if seen[a] || b.accounts[a] != nil {
+			return
+		}
+		seen[a] = true
+		b.warnf(pos, CodeUndeclaredAccount, "account %s is not declared", a).Account = a
+	}
+	for _, e := range e {
+		switch e := e.(type) {
+		case *Transaction:
+			if p := e.Pad; p != nil {
+				check(e.EntryPos, p.Account)
+				check(e.EntryPos, p.Source)
+			}
+			for _, s := range e.Splits {
+				check(e.EntryPos, s.Account)
+			}
+		case *BalanceAssert:
+			check(e.EntryPos, e.Account)
+		case *DisableAccount:
+			check(e.EntryPos, e.Account)
+		}
+	}
+}
+
+// checkUnitsUsed adds warnings for declared units that are not used.
+func (b *builder) checkUnitsUsed() {
+	for sym, pos := range b.unitPos {
+		if !b.usedUnits[sym] {
+			b.warnf(pos, CodeUnusedUnit, "unit %s is not used", sym)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompile_diagnostics(t *testing.T) {
+	t.Parallel()
+	j, err := Compile(&CompileArgs{
+		Inputs: []CompileInput{Bytes("testfile", []byte(`unit USD 100
+unit JPY 1
+account Assets:Cash
+end
+tx 2000-01-01 ""
+Assets:Cash 1 USD
+Income:Salary
+end
+tx 2000-02-01 "Later"
+Assets:Cash 1 USD
+Income:Salary
+end
+balance 2000-01-31 Assets:Cash 2 USD
+`))},
+		Today: civil.Date{2000, 1, 31},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := formatDiagnostics(j.Diagnostics)
+	want := []string{
+		"error testfile:13:1 balance",
+		"warning testfile:2:1 unused-unit",
+		"warning testfile:5:1 empty-description",
+		"warning testfile:5:1 undeclared-account",
+		"warning testfile:9:1 future-date",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diagnostics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompile_diagnostics_error(t *testing.T) {
+	t.Parallel()
+	_, err := compileText(`unit USD 100
+unit JPY 1
+disable 2000-01-01 Assets:Cash
+tx 2000-01-02 "Buy"
+Assets:Cash 1 USD
+Income:Salary
+end
+`)
+	var l DiagnosticList
+	if !errors.As(err, &l) {
+		t.Fatalf("Got error %v; want DiagnosticList", err)
+	}
+	got := formatDiagnostics(l)
+	want := []string{
+		"error testfile:4:1 disabled-account",
+		"warning testfile:2:1 unused-unit",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diagnostics mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := fmt.Sprint(l[0].Related), "[testfile:3:1]"; got != want {
+		t.Errorf("Got related positions %s; want %s", got, want)
+	}
+}
+
+func TestCompile_diagnostics_multiple_errors(t *testing.T) {
+	t.Parallel()
+	_, err := compileText(`unit USD 100
+disable 2000-01-01 Assets:Cash
+tx 2000-01-02 "Buy"
+Assets:Cash 1 USD
+Income:Salary
+end
+pad 2000-01-03 Assets:Bank Equity:Opening
+pad 2000-01-04 Assets:Bank Equity:Opening
+balance 2000-01-05 Assets:Cash 0 USD
+`)
+	var l DiagnosticList
+	if !errors.As(err, &l) {
+		t.Fatalf("Got error %v; want DiagnosticList", err)
+	}
+	got := formatDiagnostics(l)
+	want := []string{
+		"error testfile:3:1 disabled-account",
+		"error testfile:8:1 pad",
+		"error testfile:9:1 disabled-account",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diagnostics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompile_diagnostics_syntax(t *testing.T) {
+	t.Parallel()
+	_, err := compileText(`unit USD 100
+tx 2000-01-02 "Buy"
+Assets:Cash 1 XYZ
+Income:Salary
+end
+balance
+`)
+	var l DiagnosticList
+	if !errors.As(err, &l) {
+		t.Fatalf("Got error %v; want DiagnosticList", err)
+	}
+	got := formatDiagnostics(l)
+	want := []string{
+		"error testfile:6:1 syntax",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diagnostics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func formatDiagnostics(l DiagnosticList) []string {
+	var s []string
+	for _, d := range l {
+		s = append(s, fmt.Sprintf("%s %s %s", d.Severity, d.Pos, d.Code))
+	}
+	return s
+}
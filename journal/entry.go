@@ -27,9 +27,9 @@ import (
 //
 // Entry types:
 //
-//  - [Transaction]
-//  - [BalanceAssert]
-//  - [DisableAccount]
+//   - [Transaction]
+//   - [BalanceAssert]
+//   - [DisableAccount]
 type Entry interface {
 	Date() civil.Date
 	Position() token.Position
@@ -43,10 +43,21 @@ type BalanceAssert struct {
 	EntryDate civil.Date
 	Account   Account
 	// Whether this is a balance assertion for the account tree.
-	Tree     bool
+	Tree bool
+	// Whether this assertion only asserts the listed units, set
+	// with the partial keyword.
+	Partial  bool
 	Declared Balance
-	Actual   Balance
-	Diff     Balance // Actual - Declared
+	// Listed contains the units listed in the assertion, including
+	// units declared as zero.
+	Listed []Unit
+	// Tolerance contains the allowed difference for each unit.
+	Tolerance Balance
+	Actual    Balance
+	// Diff is Actual - Declared, excluding differences within the
+	// tolerance and, for partial assertions or accounts with
+	// partial balance assertions, units that are not listed.
+	Diff Balance
 }
 
 func (b *BalanceAssert) Position() token.Position {
@@ -57,6 +68,12 @@ func (b *BalanceAssert) Date() civil.Date {
 	return b.EntryDate
 }
 
+// includes returns whether the balance assertion includes the
+// balance of the account.
+func (b *BalanceAssert) includes(a Account) bool {
+	return a == b.Account || b.Tree && a.Under(b.Account)
+}
+
 func (b *BalanceAssert) sortKey() int64 {
 	return dateKey(b.EntryDate) + 1
 }
@@ -69,7 +86,19 @@ type Transaction struct {
 	EntryPos    token.Position
 	EntryDate   civil.Date
 	Description string
-	Splits      []Split
+	// Metadata contains the transaction metadata, or nil if none.
+	Metadata map[string]string
+	// Tags contains the transaction tags without the leading #.
+	Tags   []string
+	Splits []Split
+	// Pad is the pad entry that the transaction was synthesized
+	// for, or nil.
+	Pad *Pad
+}
+
+// HasTag returns whether the transaction has the tag.
+func (t *Transaction) HasTag(tag string) bool {
+	return hasTag(t.Tags, tag)
 }
 
 func (t *Transaction) Position() token.Position {
@@ -91,6 +120,54 @@ func (*Transaction) entry() {}
 type Split struct {
 	Account Account
 	Amount  *Amount
+	// Lot is the lot annotation of the split, or nil.
+	Lot *LotSpec
+	// Booked contains the lots added or reduced by the split if it
+	// has a lot annotation.  Reduced lots have negative amounts.
+	// This is filled in when the journal is compiled.
+	Booked []*Lot
+	// Metadata contains the split metadata, or nil if none.
+	Metadata map[string]string
+	// Tags contains the split tags without the leading #.
+	Tags []string
+	// Rule is the rule that added the split, or nil if the split
+	// is from the transaction entry.
+	Rule *Rule
+}
+
+// HasTag returns whether the split has the tag.  Tags on the
+// transaction are not included.
+func (s *Split) HasTag(tag string) bool {
+	return hasTag(s.Tags, tag)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// addWeight adds the amount of the split used for balancing the
+// transaction to the balance.  Splits with a lot annotation are
+// weighed at cost.  This returns false if the weight of the split is
+// not known yet because it is waiting to be booked.
+func (s *Split) addWeight(b *Balance) bool {
+	switch {
+	case s.Lot == nil:
+		b.Add(s.Amount)
+	case s.Lot.Cost != nil:
+		b.Add(costAmount(s.Amount, s.Lot.Cost, s.Lot.CostUnit))
+	case s.Booked != nil || s.Amount.Zero():
+		for _, l := range s.Booked {
+			b.Add(l.TotalCost())
+		}
+	default:
+		return false
+	}
+	return true
 }
 
 // A DisableAccount entry represents an account closing.
@@ -114,6 +191,24 @@ func (c *DisableAccount) sortKey() int64 {
 
 func (*DisableAccount) entry() {}
 
+// A Pad describes a pad entry, which pads an account from a source
+// account so that the next balance assertion for the account passes.
+// Pad entries are compiled into transactions with the padding.
+type Pad struct {
+	EntryPos  token.Position
+	EntryDate civil.Date
+	Account   Account
+	Source    Account
+}
+
+func (p *Pad) Position() token.Position {
+	return p.EntryPos
+}
+
+func (p *Pad) Date() civil.Date {
+	return p.EntryDate
+}
+
 func sortEntries(e []Entry) {
 	type pair struct {
 		k int64
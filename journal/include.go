@@ -0,0 +1,235 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/parser"
+	"go.felesatra.moe/keeper/kpr/scanner"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// SourceFiles returns the paths of the files and the files included
+// by them, recursively, in the order that they are parsed.
+func SourceFiles(paths ...string) ([]string, error) {
+	p := newIncludeParser(token.NewFileSet())
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("source files: %s", err)
+		}
+		p.parse(path, path, src)
+	}
+	if err := p.errs.Err(); err != nil {
+		return nil, fmt.Errorf("source files: %w", err)
+	}
+	return p.paths, nil
+}
+
+// An includeChain maps the filenames of included files to the
+// position of the include entry that included them.
+type includeChain map[string]token.Position
+
+// annotate appends the include chain for the position to the message.
+func (c includeChain) annotate(pos token.Position, msg string) string {
+	for {
+		p, ok := c[pos.Filename]
+		if !ok {
+			return msg
+		}
+		msg = fmt.Sprintf("%s (included from %s)", msg, p)
+		pos = p
+	}
+}
+
+// An includeParser parses files, resolving include entries.
+type includeParser struct {
+	fset  *token.FileSet
+	chain includeChain
+	// Paths of files that have been parsed.
+	seen map[string]bool
+	// Paths of files currently being parsed, for cycle detection.
+	stack []string
+	// Paths of parsed files, in order.
+	paths []string
+	errs  scanner.ErrorList
+	// If set, used for parsing files.
+	cache *Cache
+	// Hashes of the sources of parsed files, in order.  Only set
+	// if cache is set.
+	sources []sourceHash
+}
+
+func newIncludeParser(fset *token.FileSet) *includeParser {
+	return &includeParser{
+		fset:  fset,
+		chain: make(includeChain),
+		seen:  make(map[string]bool),
+	}
+}
+
+// parse parses the file source and returns the entries, with include
+// entries replaced by the entries of the included files.
+//
+// path is the path used for resolving includes and detecting cycles,
+// and filename is used for position information.
+func (p *includeParser) parse(path, filename string, src []byte) []ast.Entry {
+	key := includeKey(path)
+	p.seen[key] = true
+	p.paths = append(p.paths, path)
+	p.stack = append(p.stack, key)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
+	f, err := p.parseFile(path, filename, src)
+	if err != nil {
+		if el, ok := err.(scanner.ErrorList); ok {
+			for _, e := range el {
+				p.errs.Add(e.Pos, p.chain.annotate(e.Pos, e.Msg))
+			}
+		} else {
+			p.errs.Add(token.Position{Filename: filename}, err.Error())
+		}
+		return nil
+	}
+	var e []ast.Entry
+	for _, n := range f.Entries {
+		if n, ok := n.(*ast.Include); ok {
+			e = append(e, p.include(path, filename, n)...)
+			continue
+		}
+		e = append(e, n)
+	}
+	return e
+}
+
+// parseFile parses a file, using the cache if set.
+func (p *includeParser) parseFile(path, filename string, src []byte) (*ast.File, error) {
+	if p.cache == nil {
+		return parser.ParseBytes(p.fset, filename, src, 0)
+	}
+	h := sourceHash{
+		key:  cacheKey{path: path, filename: filename},
+		hash: sha256.Sum256(src),
+	}
+	p.sources = append(p.sources, h)
+	return p.cache.parse(h, src)
+}
+
+// include parses the files included by an include entry.
+func (p *includeParser) include(path, filename string, n *ast.Include) []ast.Entry {
+	assertKind(n.Path, token.STRING)
+	pos := p.fset.Position(n.Pos())
+	pattern := parseString(n.Path.Value)
+	if pattern == "" {
+		p.errorf(pos, "empty include path")
+		return nil
+	}
+	paths, err := resolveInclude(filepath.Dir(path), pattern)
+	if err != nil {
+		p.errorf(pos, "include %q: %s", pattern, err)
+		return nil
+	}
+	var e []ast.Entry
+	for _, path2 := range paths {
+		key := includeKey(path2)
+		if i := p.stackIndex(key); i >= 0 {
+			p.errorf(pos, "include cycle: %s", strings.Join(append(p.stack[i:], key), " -> "))
+			continue
+		}
+		if p.seen[key] {
+			p.errorf(pos, "file %s included more than once", path2)
+			continue
+		}
+		src, err := os.ReadFile(path2)
+		if err != nil {
+			p.errorf(pos, "include %q: %s", pattern, err)
+			continue
+		}
+		filename2 := path2
+		if !filepath.IsAbs(pattern) {
+			filename2 = includeFilename(filename, path, path2)
+		}
+		p.chain[filename2] = pos
+		e = append(e, p.parse(path2, filename2, src)...)
+	}
+	return e
+}
+
+func (p *includeParser) errorf(pos token.Position, format string, v ...interface{}) {
+	p.errs.Add(pos, p.chain.annotate(pos, fmt.Sprintf(format, v...)))
+}
+
+func (p *includeParser) stackIndex(key string) int {
+	for i, k := range p.stack {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveInclude resolves an include path or glob pattern relative to
+// the directory of the including file.
+// Glob patterns may match no files.
+func resolveInclude(dir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	if !strings.ContainsAny(pattern, `*?[\`) {
+		return []string{pattern}, nil
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// includeFilename returns the filename used for position information
+// for a file included by a relative path.
+// This is the included path relative to the including file, joined to
+// the including file's filename.
+func includeFilename(filename, path, included string) string {
+	rel, err := filepath.Rel(filepath.Dir(path), included)
+	if err != nil {
+		return included
+	}
+	return filepath.Join(filepath.Dir(filename), rel)
+}
+
+// includeKey returns a key for identifying a file for cycle
+// detection.
+func includeKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// inputPath returns the path used for resolving includes in an input.
+func inputPath(i CompileInput) string {
+	if i, ok := i.(inputFile); ok {
+		return i.filename
+	}
+	return i.Filename()
+}
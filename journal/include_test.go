@@ -0,0 +1,164 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompile_include(t *testing.T) {
+	t.Parallel()
+	d := t.TempDir()
+	writeFiles(t, d, map[string]string{
+		"main.kpr": `unit USD 100
+include "2020/*.kpr"
+`,
+		"2020/01.kpr": `tx 2020-01-02 "Paycheck"
+Income:Salary -12 USD
+Assets:Bank
+end
+`,
+		"2020/02.kpr": `tx 2020-02-02 "Paycheck"
+Income:Salary -12 USD
+Assets:Bank
+end
+`,
+	})
+	j, err := Compile(&CompileArgs{
+		Inputs: Files(filepath.Join(d, "main.kpr")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := j.Balances["Assets:Bank"].String(), "24.00 USD"; got != want {
+		t.Errorf("Got balance %s; want %s", got, want)
+	}
+	if got, want := j.Entries[1].Position().Filename, filepath.Join("2020", "02.kpr"); got != want {
+		t.Errorf("Got filename %s; want %s", got, want)
+	}
+}
+
+func TestCompile_include_paths(t *testing.T) {
+	t.Parallel()
+	d := t.TempDir()
+	writeFiles(t, d, map[string]string{
+		"2020/jan.kpr": `unit USD 100
+include "food.kpr"
+`,
+		"2020/food.kpr": `tx 2020-01-02 "Lunch"
+Expenses:Food 12 JPY
+Assets:Cash
+end
+`,
+	})
+	main := filepath.Join(d, "2020", "jan.kpr")
+	_, err := Compile(&CompileArgs{
+		Inputs: Paths(main),
+	})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	want := filepath.Join(d, "2020", "food.kpr") + ":2:18: undeclared unit JPY (included from " + main + ":2:1)"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Got error %q; want containing %q", err, want)
+	}
+}
+
+func TestSourceFiles(t *testing.T) {
+	t.Parallel()
+	d := t.TempDir()
+	writeFiles(t, d, map[string]string{
+		"main.kpr":    `include "2020/*.kpr"` + "\n",
+		"2020/01.kpr": `include "../other.kpr"` + "\n",
+		"2020/02.kpr": "",
+		"other.kpr":   "",
+	})
+	got, err := SourceFiles(filepath.Join(d, "main.kpr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(d, "main.kpr"),
+		filepath.Join(d, "2020/01.kpr"),
+		filepath.Join(d, "other.kpr"),
+		filepath.Join(d, "2020/02.kpr"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompile_include_cycle(t *testing.T) {
+	t.Parallel()
+	d := t.TempDir()
+	writeFiles(t, d, map[string]string{
+		"main.kpr": `include "a.kpr"
+`,
+		"a.kpr": `include "main.kpr"
+`,
+	})
+	_, err := Compile(&CompileArgs{
+		Inputs: Files(filepath.Join(d, "main.kpr")),
+	})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Got error %q; want include cycle", err)
+	}
+}
+
+func TestCompile_include_error_chain(t *testing.T) {
+	t.Parallel()
+	d := t.TempDir()
+	writeFiles(t, d, map[string]string{
+		"main.kpr": `unit USD 100
+include "a.kpr"
+`,
+		"a.kpr": `tx 2020-01-02 "Paycheck"
+Income:Salary -12 JPY
+Assets:Bank
+end
+`,
+	})
+	_, err := Compile(&CompileArgs{
+		Inputs: Files(filepath.Join(d, "main.kpr")),
+	})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	const want = "a.kpr:2:19: undeclared unit JPY (included from main.kpr:2:1)"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Got error %q; want containing %q", err, want)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, s := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
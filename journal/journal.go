@@ -30,22 +30,97 @@ in a transaction can omit the amount, which will be inferred as the
 remaining amount needed to balance the transaction.  This inference
 does not work if more than one unit is unbalanced.
 
+Splits with a lot annotation are weighed at cost when balancing the
+transaction.  Positive amounts with a lot annotation add a lot to the
+account's lot inventory, using the transaction date if the annotation
+has no date.  Negative amounts with a lot annotation reduce lots in the
+account's inventory that match the annotation's cost and date, if
+given.  If the annotation has no cost, the split is weighed at the
+cost of the reduced lots.  Lots are reduced according to the
+account's booking method, which is set with the "booking" account
+metadata key to one of "fifo" (the default), "lifo", "specific", or
+"average".  Splits without a lot annotation do not affect lots.
+
 Balance assertions apply at the end of the day, to match how balances
 are handled in practice.
 
+A balance assertion passes if the difference for each unit is within
+the unit's tolerance, which is zero by default.  Balance assertions
+assert all units in the account, including units that are not listed,
+which must be zero.  If the assertion has the partial keyword or the
+"balance" account metadata key is set to "partial", only the listed
+units are asserted and pad entries only pad the listed units.
+
 Tree balance assertions apply to a tree of accounts.
 
+Price entries are collected into a price database for converting
+amounts between units as of a given date.  Price entries do not
+affect balances.
+
+Recurring entries are collected into the recurring entries of the
+journal.  Recurring entries do not affect balances unless
+transactions are generated from them with CompileArgs.Forecast.
+Recurring entries are checked like transactions, but their
+transactions are generated whole, so a split amount omitted in a
+recurring entry is the same for each occurrence.
+
+Rule entries add splits to matching transactions, including
+transactions generated from recurring entries.  A rule matches
+transactions with a split for the account or its sub-accounts, a
+description matching the regular expression, and all of the tags,
+ignoring conditions that are not given.  Percentages are of the
+amount of the matched split and are rounded to the unit's scale.
+The splits added by a rule must balance, and one of them can omit
+the amount as in transactions.  Rules are applied in file order and
+do not match splits added by rules.  Rules do not apply to pad
+entries.
+
+Budget entries are collected into the budgets of the journal.  A
+budget applies to the account and its sub-accounts, and lasts until
+the next budget entry for the same account and unit.  Budget entries
+do not affect balances.
+
+Pad entries are compiled into transactions dated on the pad entry
+that move the amount needed for the next balance assertion for the
+account to pass from the source account.  These transactions have
+the pad entry in Transaction.Pad.  Pad entries that are not
+followed by a balance assertion or where no padding is needed do not
+produce a transaction.  Another pad entry for the same account
+before the balance assertion is an error.  Because the transaction is
+dated on the pad entry, it also affects balance assertions between
+the pad entry and the balance assertion it pads for, such as
+assertions for the source account or tree balance assertions for a
+parent account.
+
 Disabled accounts prevent transactions from posting to that account.
 Disable account entries also assert that the account balance is zero.
+
+Include entries are resolved relative to the directory of the
+including file, and are replaced by the entries of the included
+files.  Include paths may be glob patterns, which are expanded in
+sorted order.  A file cannot be included more than once, and errors in
+included files are annotated with the chain of include entries.
+
+# Diagnostics
+
+Errors and warnings are reported as diagnostics with a stable code.
+Warnings do not prevent compiling a journal.  Warnings are reported
+for declared units that are never used, transactions with an empty
+description, and transactions dated after CompileArgs.Today.  If any
+accounts are declared with account entries, the first use of each
+account that is not declared is also reported.
 */
 package journal
 
 import (
+	"cmp"
 	"fmt"
+	"math/big"
+	"slices"
+	"time"
 
 	"cloud.google.com/go/civil"
 	"go.felesatra.moe/keeper/kpr/ast"
-	"go.felesatra.moe/keeper/kpr/parser"
 	"go.felesatra.moe/keeper/kpr/token"
 )
 
@@ -53,48 +128,109 @@ import (
 // Balance assertion errors are stored in the returned Journal rather
 // than returned as errors here, to enable the caller to inspect the
 // transactions to identify the error.
+//
+// If compiling fails, the returned error wraps a DiagnosticList with
+// the errors and any warnings found.  Otherwise, warnings and balance
+// assertion errors are stored in Journal.Diagnostics.
 func Compile(a *CompileArgs) (*Journal, error) {
+	return compileCached(a, nil)
+}
+
+// compileCached compiles a Journal, using the cache if it is not nil.
+func compileCached(a *CompileArgs, c *Cache) (*Journal, error) {
 	// Compiling a journal happens in stages:
 	//  1. Parse inputs into ast entries
 	//  2. Convert ast entries into journal entries ("building")
-	//  3. Sort entries by date
+	//  3. Apply rules and sort entries by date
 	//  4. Go through entries adding up balances and checking things ("compiling")
-	//  5. Fill in account metadata
+	//  5. Fill in account metadata, units, prices, budgets, and
+	//     recurring entries
+	today := a.Today
+	if !today.IsValid() {
+		today = civil.DateOf(time.Now())
+	}
 	fset := token.NewFileSet()
-	e, err := parseEntries(fset, a.Inputs...)
+	if c != nil {
+		fset = c.fset
+	}
+	p := newIncludeParser(fset)
+	p.cache = c
+	e, err := parseInputs(p, a.Inputs...)
 	if err != nil {
-		return nil, fmt.Errorf("compile journal: %s", err)
+		return nil, compileError(nil, CodeSyntax, err)
+	}
+	if c != nil {
+		c.prune(p.sources)
+		if j := c.lookup(a, today, p.sources); j != nil {
+			return j, nil
+		}
 	}
 	b := newBuilder(fset)
+	b.includes = p.chain
+	b.today = today
 	e2, err := b.build(e...)
 	if err != nil {
-		return nil, fmt.Errorf("compile journal: %s", err)
+		return nil, compileError(b.warnings, CodeInvalidEntry, err)
+	}
+	b.checkUnitsUsed()
+	if d := a.Forecast; d.IsValid() {
+		for _, t := range dueTransactions(b.recurring, e2, d) {
+			e2 = append(e2, t)
+		}
+	}
+	if err := b.applyRules(e2); err != nil {
+		return nil, compileError(b.warnings, CodeRule, err)
 	}
 	sortEntries(e2)
+	b.checkAccountsDeclared(e2)
 	if d := a.Ending; d.IsValid() {
 		e2 = entriesEnding(e2, d)
 	}
-	j, err := compile(e2)
-	if err != nil {
-		return nil, fmt.Errorf("compile journal: %s", err)
-	}
+	j := newJournal()
+	// Account metadata is needed for booking lots.
 	copyAccountMetadata(b, j)
+	if err := j.addEntries(e2); err != nil {
+		return nil, compileError(b.warnings, CodeInvalidEntry, err)
+	}
+	j.Diagnostics = append(balanceDiagnostics(j.BalanceErrors), b.warnings...)
+	j.Diagnostics.Sort()
+	j.Units = b.units
+	j.UnitPos = b.unitPos
+	j.Prices = newPriceDB(b.prices, a.Ending)
+	j.Budgets = b.budgets
+	sortBudgets(j.Budgets)
+	j.Recurring = b.recurring
+	j.Rules = b.rules
+	if c != nil {
+		c.store(a, today, p.sources, j)
+	}
 	return j, nil
 }
 
 // parseEntries parses inputs into ast entries.
+// Include entries are replaced with the entries of the included files.
 func parseEntries(fset *token.FileSet, inputs ...CompileInput) ([]ast.Entry, error) {
+	return parseInputs(newIncludeParser(fset), inputs...)
+}
+
+// parseInputs is like parseEntries, but uses the given parser, which
+// keeps the include chain for annotating errors.
+func parseInputs(p *includeParser, inputs ...CompileInput) ([]ast.Entry, error) {
 	var e []ast.Entry
 	for _, i := range inputs {
 		src, err := i.Src()
 		if err != nil {
-			return nil, fmt.Errorf("build entries: %s", err)
-		}
-		f, err := parser.ParseBytes(fset, i.Filename(), src, 0)
-		if err != nil {
-			return nil, fmt.Errorf("build entries: %s", err)
+			return nil, &Diagnostic{
+				Pos:      token.Position{Filename: i.Filename()},
+				Severity: SeverityError,
+				Code:     CodeInput,
+				Msg:      err.Error(),
+			}
 		}
-		e = append(e, f.Entries...)
+		e = append(e, p.parse(inputPath(i), i.Filename(), src)...)
+	}
+	if err := p.errs.Err(); err != nil {
+		return nil, fmt.Errorf("build entries: %w", err)
 	}
 	return e, nil
 }
@@ -103,10 +239,8 @@ func parseEntries(fset *token.FileSet, inputs ...CompileInput) ([]ast.Entry, err
 // Entries should be sorted.
 func compile(e []Entry) (*Journal, error) {
 	j := newJournal()
-	for _, e := range e {
-		if err := j.addEntry(e); err != nil {
-			return nil, err
-		}
+	if err := j.addEntries(e); err != nil {
+		return nil, err
 	}
 	return j, nil
 }
@@ -124,6 +258,9 @@ func copyAccountMetadata(b *builder, j *Journal) {
 			j.Accounts[a] = ai2
 		}
 		ai2.Metadata = ai.Metadata
+		ai2.Booking = ai.Booking
+		ai2.PartialBalance = ai.PartialBalance
+		ai2.DeclPos = ai.DeclPos
 	}
 }
 
@@ -140,13 +277,50 @@ type Journal struct {
 	Balances Balances
 	// BalanceErrors contains the balance assertion entries that failed.
 	BalanceErrors []*BalanceAssert
+	// Diagnostics contains the warnings and the balance assertion
+	// errors, sorted by severity and position.
+	Diagnostics DiagnosticList
+	// Units contains all declared units, keyed by symbol.
+	Units map[string]Unit
+	// UnitPos contains the positions of the first declaration of
+	// each unit, keyed by symbol.
+	UnitPos map[string]token.Position
+	// Prices contains the prices from price entries.
+	Prices *PriceDB
+	// Budgets contains the budgets from budget entries.  Budgets
+	// are not limited by CompileArgs.Ending.
+	Budgets Budgets
+	// Recurring contains the recurring entries, in file order.
+	// Recurring entries are not limited by CompileArgs.Ending.
+	Recurring []*Recurring
+	// Rules contains the rules, in file order.
+	Rules []*Rule
+	// Inventories contains the final lot inventory for all
+	// accounts with lots.
+	Inventories Inventories
+	// Pad entries waiting for a balance assertion, by account.
+	pads map[Account]*pendingPad
+	// Balance assertions waiting for pad entries to be filled
+	// before they are checked.
+	waiting []*BalanceAssert
+}
+
+// A pendingPad is the transaction for a pad entry waiting for a
+// balance assertion.
+type pendingPad struct {
+	t *Transaction
+	// affected contains the balance assertions after the pad entry
+	// that include the pad or source account.
+	affected []*BalanceAssert
 }
 
 // newJournal makes a new Journal.
 func newJournal() *Journal {
 	return &Journal{
-		Accounts: make(AccountMap),
-		Balances: make(Balances),
+		Accounts:    make(AccountMap),
+		Balances:    make(Balances),
+		Inventories: make(Inventories),
+		pads:        make(map[Account]*pendingPad),
 	}
 }
 
@@ -169,6 +343,102 @@ func (j *Journal) BalancesEnding(d civil.Date) Balances {
 	return b
 }
 
+// Ending returns a view of the journal with only the entries up to
+// and including the given date, as if compiled with
+// CompileArgs.Ending set to the date.  This is cheaper than compiling
+// the journal again.  The view shares entries and other data with j.
+//
+// Unlike compiling with CompileArgs.Ending, transactions for pad
+// entries on or before the date are kept even if the balance
+// assertion they pad for is after the date.
+func (j *Journal) Ending(d civil.Date) *Journal {
+	e := entriesEnding(j.Entries, d)
+	j2 := &Journal{
+		Entries:     slices.Clip(e),
+		Accounts:    make(AccountMap),
+		Balances:    j.BalancesEnding(d),
+		Units:       j.Units,
+		UnitPos:     j.UnitPos,
+		Prices:      j.Prices.ending(d),
+		Budgets:     j.Budgets,
+		Recurring:   j.Recurring,
+		Rules:       j.Rules,
+		Inventories: make(Inventories),
+		pads:        make(map[Account]*pendingPad),
+	}
+	addAccount := func(a Account) {
+		if _, ok := j2.Accounts[a]; ok {
+			return
+		}
+		ai := *j.Accounts[a]
+		if ai.Disabled != nil && ai.Disabled.EntryDate.After(d) {
+			ai.Disabled = nil
+		}
+		j2.Accounts[a] = &ai
+	}
+	for a, ai := range j.Accounts {
+		if ai.DeclPos.IsValid() {
+			addAccount(a)
+		}
+	}
+	for _, e := range e {
+		switch e := e.(type) {
+		case *Transaction:
+			if p := e.Pad; p != nil {
+				addAccount(p.Account)
+				addAccount(p.Source)
+			}
+			for i := range e.Splits {
+				s := &e.Splits[i]
+				addAccount(s.Account)
+				if s.Lot == nil {
+					continue
+				}
+				// Booking succeeded when compiling, so it
+				// cannot fail here.
+				inv := j2.Inventories.get(s.Account)
+				inv.book(s, e.EntryDate, j.Accounts[s.Account].Booking)
+				if len(inv.Lots) == 0 {
+					delete(j2.Inventories, s.Account)
+				}
+			}
+		case *BalanceAssert:
+			addAccount(e.Account)
+		case *DisableAccount:
+			addAccount(e.Account)
+		}
+	}
+	for _, e := range j.BalanceErrors {
+		if !e.EntryDate.After(d) {
+			j2.BalanceErrors = append(j2.BalanceErrors, e)
+		}
+	}
+	for _, diag := range j.Diagnostics {
+		if b := diag.Balance; b == nil || !b.EntryDate.After(d) {
+			j2.Diagnostics = append(j2.Diagnostics, diag)
+		}
+	}
+	return j2
+}
+
+// addEntries adds entries to the journal.  Entries with errors are
+// skipped, and the errors for all of them are returned as a
+// DiagnosticList.
+func (j *Journal) addEntries(e []Entry) error {
+	var errs DiagnosticList
+	for _, e := range e {
+		if err := j.addEntry(e); err != nil {
+			errs.add(CodeInvalidEntry, err)
+		}
+	}
+	j.removeEmptyPads()
+	j.checkWaiting()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 func (j *Journal) addEntry(e Entry) error {
 	switch e := e.(type) {
 	case *Transaction:
@@ -183,42 +453,199 @@ func (j *Journal) addEntry(e Entry) error {
 }
 
 func (j *Journal) addTransaction(e *Transaction) error {
+	if e.Pad != nil {
+		return j.addPad(e)
+	}
 	for _, s := range e.Splits {
 		j.ensureAccount(s.Account)
-		if err := j.checkAccountDisabled(s.Account); err != nil {
-			return fmt.Errorf("add entry %T at %s: %s", e, e.Position(), err)
+		if err := j.checkAccountDisabled(e.EntryPos, s.Account); err != nil {
+			return err
 		}
+	}
+	if err := j.bookLots(e); err != nil {
+		return entryError(e.EntryPos, CodeLot, nil, "%s", err)
+	}
+	for _, s := range e.Splits {
 		j.Balances.Add(s.Account, s.Amount)
 	}
 	j.Entries = append(j.Entries, e)
 	return nil
 }
 
+// bookLots books the splits of a transaction that have lot
+// annotations against the lot inventories.  If the transaction could
+// not be balanced before booking, it is balanced here.
+func (j *Journal) bookLots(e *Transaction) error {
+	unbalanced := needsBooking(e)
+	for i := range e.Splits {
+		s := &e.Splits[i]
+		if s.Lot == nil {
+			continue
+		}
+		inv := j.Inventories.get(s.Account)
+		lots, err := inv.book(s, e.EntryDate, j.Accounts[s.Account].Booking)
+		if err != nil {
+			return fmt.Errorf("book lot for %s: %s", s.Account, err)
+		}
+		if len(inv.Lots) == 0 {
+			delete(j.Inventories, s.Account)
+		}
+		s.Booked = lots
+	}
+	if unbalanced {
+		return balanceTransaction(e)
+	}
+	return nil
+}
+
+// addPad adds a transaction for a pad entry.  The splits are added
+// by the next balance assertion for the account.
+func (j *Journal) addPad(e *Transaction) error {
+	p := e.Pad
+	for _, a := range []Account{p.Account, p.Source} {
+		j.ensureAccount(a)
+		if err := j.checkAccountDisabled(p.EntryPos, a); err != nil {
+			return err
+		}
+	}
+	if p2, ok := j.pads[p.Account]; ok {
+		e2 := p2.t
+		return entryError(p.EntryPos, CodePad, []token.Position{e2.EntryPos},
+			"previous pad for %s at %s not followed by balance assertion", p.Account, e2.EntryPos)
+	}
+	j.pads[p.Account] = &pendingPad{t: e}
+	j.Entries = append(j.Entries, e)
+	return nil
+}
+
+// fillPad adds the splits to the pending pad transaction for the
+// balance assertion's account, if any, so that the assertion passes.
+// The splits are also added to the actual balances of the balance
+// assertions affected by the pad.  e.Actual must be set.
+func (j *Journal) fillPad(e *BalanceAssert) {
+	p, ok := j.pads[e.Account]
+	if !ok {
+		return
+	}
+	delete(j.pads, e.Account)
+	t := p.t
+	var diff Balance
+	diff.Set(&e.Actual)
+	diff.Neg()
+	diff.AddBal(&e.Declared)
+	partial := e.Partial || j.Accounts[e.Account].PartialBalance
+	for _, a := range diff.Amounts() {
+		if partial && !slices.Contains(e.Listed, a.Unit) {
+			continue
+		}
+		neg := copyAmount(a)
+		neg.Neg()
+		t.Splits = append(t.Splits,
+			Split{Account: t.Pad.Account, Amount: a},
+			Split{Account: t.Pad.Source, Amount: neg})
+		j.Balances.Add(t.Pad.Account, a)
+		j.Balances.Add(t.Pad.Source, neg)
+		e.Actual.Add(a)
+		for _, b := range p.affected {
+			if b.includes(t.Pad.Account) {
+				b.Actual.Add(a)
+			}
+			if b.includes(t.Pad.Source) {
+				b.Actual.Add(neg)
+			}
+		}
+	}
+}
+
+// removeEmptyPads removes the transactions for pad entries that did
+// not need padding or were not followed by a balance assertion.
+func (j *Journal) removeEmptyPads() {
+	entries := j.Entries[:0]
+	for _, e := range j.Entries {
+		if t, ok := e.(*Transaction); ok && t.Pad != nil && len(t.Splits) == 0 {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	j.Entries = entries
+	clear(j.pads)
+}
+
 func (j *Journal) addBalanceAssert(e *BalanceAssert) error {
 	j.ensureAccount(e.Account)
-	if err := j.checkAccountDisabled(e.Account); err != nil {
-		return fmt.Errorf("add entry %T at %s: %s", e, e.Position(), err)
+	if err := j.checkAccountDisabled(e.EntryPos, e.Account); err != nil {
+		return err
 	}
 	if e.Tree {
 		addTreeBalance(&e.Actual, j.Balances, e.Account)
 	} else {
 		e.Actual.Set(j.Balances[e.Account])
 	}
+	j.fillPad(e)
+	j.Entries = append(j.Entries, e)
+	var waiting bool
+	for _, p := range j.pads {
+		if e.includes(p.t.Pad.Account) || e.includes(p.t.Pad.Source) {
+			p.affected = append(p.affected, e)
+			waiting = true
+		}
+	}
+	if waiting {
+		j.waiting = append(j.waiting, e)
+	} else {
+		j.checkBalance(e)
+	}
+	return nil
+}
+
+// checkBalance sets the difference for the balance assertion and
+// adds it to the balance errors if it fails.
+func (j *Journal) checkBalance(e *BalanceAssert) {
 	e.Diff.Set(&e.Declared)
 	e.Diff.Neg()
 	e.Diff.AddBal(&e.Actual)
-
-	j.Entries = append(j.Entries, e)
+	partial := e.Partial || j.Accounts[e.Account].PartialBalance
+	for _, a := range e.Diff.Amounts() {
+		if partial && !slices.Contains(e.Listed, a.Unit) || withinTolerance(a, e.Tolerance.Amount(a.Unit)) {
+			a.Neg()
+			e.Diff.Add(a)
+		}
+	}
 	if !e.Diff.Empty() {
 		j.BalanceErrors = append(j.BalanceErrors, e)
 	}
-	return nil
+}
+
+// checkWaiting checks the balance assertions that were waiting for
+// pad entries, after all pad entries have been filled.
+func (j *Journal) checkWaiting() {
+	if len(j.waiting) == 0 {
+		return
+	}
+	for _, e := range j.waiting {
+		j.checkBalance(e)
+	}
+	j.waiting = nil
+	slices.SortStableFunc(j.BalanceErrors, func(a, b *BalanceAssert) int {
+		return cmp.Compare(dateKey(a.EntryDate), dateKey(b.EntryDate))
+	})
+}
+
+// balanceKey is the account metadata key for the balance assertion
+// mode, either "full" or "partial".
+const balanceKey = "balance"
+
+// withinTolerance returns whether the absolute value of the
+// difference is not more than the tolerance.
+func withinTolerance(diff, tol *Amount) bool {
+	var n big.Int
+	return n.Abs(&diff.Number).Cmp(&tol.Number) <= 0
 }
 
 func (j *Journal) addDisableAccount(e *DisableAccount) error {
 	j.ensureAccount(e.Account)
-	if err := j.checkAccountDisabled(e.Account); err != nil {
-		return fmt.Errorf("add entry %T at %s: %s", e, e.Position(), err)
+	if err := j.checkAccountDisabled(e.EntryPos, e.Account); err != nil {
+		return err
 	}
 	if bal := j.Balances[e.Account]; bal != nil && !bal.Empty() {
 		ba := &BalanceAssert{
@@ -235,9 +662,14 @@ func (j *Journal) addDisableAccount(e *DisableAccount) error {
 	return nil
 }
 
-func (j *Journal) checkAccountDisabled(a Account) error {
+// checkAccountDisabled returns an error for the entry at the position
+// if the account is disabled.
+func (j *Journal) checkAccountDisabled(pos token.Position, a Account) error {
 	if e := j.Accounts[a].Disabled; e != nil {
-		return fmt.Errorf("account %s is disabled by entry %s", a, e)
+		d := entryError(pos, CodeDisabledAccount, []token.Position{e.EntryPos},
+			"account %s is disabled by entry at %s", a, e.EntryPos)
+		d.Account = a
+		return d
 	}
 	return nil
 }
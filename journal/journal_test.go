@@ -20,6 +20,7 @@ import (
 
 	"cloud.google.com/go/civil"
 	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/kpr/token"
 )
 
 func Example() {
@@ -317,6 +318,184 @@ func TestCompile_disable_nonempty_account(t *testing.T) {
 	}
 }
 
+func TestCompile_pad(t *testing.T) {
+	t.Parallel()
+	got, err := compileText(`unit USD 100
+pad 2000-01-01 Assets:Bank Equity:Opening
+tx 2000-01-10 "Deposit"
+Assets:Bank 100 USD
+Income:Salary
+end
+balance 2000-01-31 Assets:Bank 1000 USD
+pad 2000-02-01 Assets:Bank Equity:Opening
+balance 2000-02-29 Assets:Bank 1000 USD
+pad 2000-03-01 Assets:Cash Equity:Opening
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.BalanceErrors) > 0 {
+		t.Errorf("Got balance errors %v", got.BalanceErrors)
+	}
+	var pads []string
+	for _, e := range got.Entries {
+		t, ok := e.(*Transaction)
+		if !ok || t.Pad == nil {
+			continue
+		}
+		for _, s := range t.Splits {
+			pads = append(pads, fmt.Sprintf("%s %s %s", t.EntryDate, s.Account, s.Amount))
+		}
+	}
+	want := []string{
+		"2000-01-01 Assets:Bank 900.00 USD",
+		"2000-01-01 Equity:Opening -900.00 USD",
+	}
+	if diff := cmp.Diff(want, pads); diff != "" {
+		t.Errorf("pad splits mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := got.Balances["Equity:Opening"].String(), "-900.00 USD"; got != want {
+		t.Errorf("Got Equity:Opening balance %s; want %s", got, want)
+	}
+}
+
+func TestCompile_pad_affected_assertions(t *testing.T) {
+	t.Parallel()
+	got, err := compileText(`unit USD 100
+pad 2000-01-01 Assets:Bank:Checking Equity:Opening
+balance 2000-01-10 Equity:Opening -1000 USD
+treebal 2000-01-10 Assets:Bank 1000 USD
+balance 2000-01-20 Equity:Opening 0 USD
+balance 2000-01-31 Assets:Bank:Checking 1000 USD
+balance 2000-02-01 Assets:Bank:Checking 0 USD
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, e := range got.BalanceErrors {
+		errs = append(errs, fmt.Sprintf("%s %s %s", e.EntryDate, e.Account, e.Diff))
+	}
+	want := []string{
+		"2000-01-20 Equity:Opening -1,000.00 USD",
+		"2000-02-01 Assets:Bank:Checking 1,000.00 USD",
+	}
+	if diff := cmp.Diff(want, errs); diff != "" {
+		t.Errorf("balance errors mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompile_pad_twice(t *testing.T) {
+	t.Parallel()
+	_, err := compileText(`unit USD 100
+pad 2000-01-01 Assets:Bank Equity:Opening
+pad 2000-01-02 Assets:Bank Equity:Opening
+balance 2000-01-31 Assets:Bank 1000 USD
+`)
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestCompile_balance_tolerance(t *testing.T) {
+	t.Parallel()
+	got, err := compileText(`unit USD 100
+tx 2000-01-10 "Interest"
+Assets:Bank 100.01 USD
+Income:Interest
+end
+balance 2000-01-31 Assets:Bank 100 ~0.01 USD
+balance 2000-02-01 Assets:Bank 100 USD
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, e := range got.BalanceErrors {
+		errs = append(errs, fmt.Sprintf("%s %s", e.EntryDate, e.Diff))
+	}
+	want := []string{"2000-02-01 0.01 USD"}
+	if diff := cmp.Diff(want, errs); diff != "" {
+		t.Errorf("balance errors mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompile_balance_partial(t *testing.T) {
+	t.Parallel()
+	got, err := compileText(`unit USD 100
+unit AAPL 1
+account Assets:Broker
+meta "balance" "partial"
+end
+tx 2000-01-10 "Buy"
+Assets:Broker 100 USD
+Assets:Broker 2 AAPL
+Equity:Opening -100 USD
+Equity:Opening -2 AAPL
+end
+pad 2000-01-11 Assets:Broker Equity:Opening
+balance 2000-01-31 Assets:Broker 150 USD
+balance 2000-02-01 Assets:Broker 2 AAPL
+balance 2000-02-02 Assets:Broker 3 AAPL
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, e := range got.BalanceErrors {
+		errs = append(errs, fmt.Sprintf("%s %s", e.EntryDate, e.Diff))
+	}
+	want := []string{"2000-02-02 -1 AAPL"}
+	if diff := cmp.Diff(want, errs); diff != "" {
+		t.Errorf("balance errors mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := got.Balances["Assets:Broker"].String(), "2 AAPL, 150.00 USD"; got != want {
+		t.Errorf("Got Assets:Broker balance %s; want %s", got, want)
+	}
+}
+
+func TestCompile_balance_partial_keyword(t *testing.T) {
+	t.Parallel()
+	got, err := compileText(`unit USD 100
+unit AAPL 1
+tx 2000-01-10 "Buy"
+Assets:Broker 100 USD
+Assets:Broker 2 AAPL
+Equity:Opening -100 USD
+Equity:Opening -2 AAPL
+end
+pad 2000-01-11 Assets:Broker Equity:Opening
+balance 2000-01-31 Assets:Broker partial 150 USD
+balance 2000-02-01 Assets:Broker partial 2 AAPL
+balance 2000-02-02 Assets:Broker 2 AAPL
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, e := range got.BalanceErrors {
+		errs = append(errs, fmt.Sprintf("%s %s", e.EntryDate, e.Diff))
+	}
+	want := []string{"2000-02-02 150.00 USD"}
+	if diff := cmp.Diff(want, errs); diff != "" {
+		t.Errorf("balance errors mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := got.Balances["Assets:Broker"].String(), "2 AAPL, 150.00 USD"; got != want {
+		t.Errorf("Got Assets:Broker balance %s; want %s", got, want)
+	}
+}
+
+func TestCompile_balance_mode_unknown(t *testing.T) {
+	t.Parallel()
+	_, err := compileText(`account Assets:Broker
+meta "balance" "some"
+end
+`)
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
 func TestCompile_account_metadata(t *testing.T) {
 	t.Parallel()
 	got, err := compileText(`account Some:account
@@ -328,6 +507,7 @@ end`)
 	want := AccountMap{
 		"Some:account": &AccountInfo{
 			Metadata: map[string]string{"nilou": "nahida"},
+			DeclPos:  token.Position{Filename: "testfile", Line: 1, Column: 1},
 		},
 	}
 	if diff := cmp.Diff(want, got.Accounts); diff != "" {
@@ -0,0 +1,276 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"fmt"
+	"math/big"
+
+	"cloud.google.com/go/civil"
+)
+
+// A BookingMethod determines which lots are reduced when units are
+// removed from an account.
+type BookingMethod int
+
+const (
+	// FIFO reduces the oldest lots first.
+	FIFO BookingMethod = iota
+	// LIFO reduces the newest lots first.
+	LIFO
+	// Specific requires each reduction to identify the lots to
+	// reduce with a lot annotation.  Matching lots are reduced
+	// oldest first.
+	Specific
+	// Average keeps a single lot per unit and cost unit at the
+	// average cost of all additions.
+	Average
+)
+
+// bookingKey is the account metadata key for the booking method.
+const bookingKey = "booking"
+
+var bookingMethods = map[string]BookingMethod{
+	"fifo":     FIFO,
+	"lifo":     LIFO,
+	"specific": Specific,
+	"average":  Average,
+}
+
+func (m BookingMethod) String() string {
+	for k, v := range bookingMethods {
+		if v == m {
+			return k
+		}
+	}
+	return fmt.Sprintf("BookingMethod(%d)", int(m))
+}
+
+// A Lot is an amount of a unit held at a cost.
+type Lot struct {
+	Amount *Amount
+	// Cost is the cost of one Amount unit in CostUnit.
+	Cost     *big.Rat
+	CostUnit Unit
+	// Date is the acquisition date of the lot.
+	Date civil.Date
+}
+
+// TotalCost returns the cost of the whole lot, rounded to the nearest
+// fractional cost unit.
+func (l *Lot) TotalCost() *Amount {
+	return costAmount(l.Amount, l.Cost, l.CostUnit)
+}
+
+// costAmount returns the cost of an amount at a per unit cost.
+func costAmount(a *Amount, cost *big.Rat, u Unit) *Amount {
+	r := newRat()
+	defer ratPool.Put(r)
+	r2 := newRat()
+	defer ratPool.Put(r2)
+	r.SetInt(&a.Number)
+	r.Mul(r, cost)
+	r.Mul(r, r2.SetFrac(new(big.Int).SetUint64(u.Scale), new(big.Int).SetUint64(a.Unit.Scale)))
+	c := &Amount{Unit: u}
+	roundRat(&c.Number, r)
+	return c
+}
+
+// A LotSpec is a lot annotation on a split.
+type LotSpec struct {
+	// Cost is the cost of one unit in CostUnit, or nil if omitted.
+	Cost     *big.Rat
+	CostUnit Unit
+	// Date is the lot date, or the zero value if omitted.
+	Date civil.Date
+}
+
+// matches returns whether the lot matches the annotation.
+func (s *LotSpec) matches(l *Lot) bool {
+	if s.Cost != nil && (s.CostUnit != l.CostUnit || s.Cost.Cmp(l.Cost) != 0) {
+		return false
+	}
+	if s.Date.IsValid() && s.Date != l.Date {
+		return false
+	}
+	return true
+}
+
+// An Inventory holds the lots in an account, in the order that they
+// were added.
+type Inventory struct {
+	Lots []*Lot
+}
+
+// Balance returns the total amounts of all of the lots.
+func (inv *Inventory) Balance() *Balance {
+	b := new(Balance)
+	if inv == nil {
+		return b
+	}
+	for _, l := range inv.Lots {
+		b.Add(l.Amount)
+	}
+	return b
+}
+
+// Cost returns the total cost of all of the lots.
+func (inv *Inventory) Cost() *Balance {
+	b := new(Balance)
+	if inv == nil {
+		return b
+	}
+	for _, l := range inv.Lots {
+		b.Add(l.TotalCost())
+	}
+	return b
+}
+
+// book books a split with a lot annotation against the inventory.
+// This returns the lots added or reduced by the split.  Reduced lots
+// have negative amounts.
+func (inv *Inventory) book(s *Split, d civil.Date, m BookingMethod) ([]*Lot, error) {
+	switch s.Amount.Sign() {
+	case 0:
+		return nil, nil
+	case 1:
+		return inv.add(s, d, m)
+	default:
+		return inv.reduce(s, m)
+	}
+}
+
+func (inv *Inventory) add(s *Split, d civil.Date, m BookingMethod) ([]*Lot, error) {
+	if s.Lot.Cost == nil {
+		return nil, fmt.Errorf("cannot add lot of %s without cost", s.Amount.Unit)
+	}
+	l := &Lot{
+		Amount:   copyAmount(s.Amount),
+		Cost:     new(big.Rat).Set(s.Lot.Cost),
+		CostUnit: s.Lot.CostUnit,
+		Date:     d,
+	}
+	if s.Lot.Date.IsValid() {
+		l.Date = s.Lot.Date
+	}
+	if m == Average {
+		for _, l2 := range inv.Lots {
+			if l2.Amount.Unit == l.Amount.Unit && l2.CostUnit == l.CostUnit {
+				mergeLot(l2, l)
+				return []*Lot{l}, nil
+			}
+		}
+	}
+	inv.Lots = append(inv.Lots, copyLot(l))
+	return []*Lot{l}, nil
+}
+
+// mergeLot merges the second lot into the first lot at the average
+// cost.  The earlier date is kept.
+func mergeLot(l, l2 *Lot) {
+	var n big.Int
+	n.Add(&l.Amount.Number, &l2.Amount.Number)
+	var c1, c2 big.Rat
+	c1.Mul(l.Cost, c1.SetInt(&l.Amount.Number))
+	c2.Mul(l2.Cost, c2.SetInt(&l2.Amount.Number))
+	c1.Add(&c1, &c2)
+	l.Cost.Quo(&c1, c2.SetInt(&n))
+	l.Amount.Number.Set(&n)
+	if l2.Date.Before(l.Date) {
+		l.Date = l2.Date
+	}
+}
+
+func (inv *Inventory) reduce(s *Split, m BookingMethod) ([]*Lot, error) {
+	if m == Specific && s.Lot.Cost == nil && !s.Lot.Date.IsValid() {
+		return nil, fmt.Errorf("lot annotation must have cost or date for specific booking")
+	}
+	var candidates []int
+	for i, l := range inv.Lots {
+		if l.Amount.Unit != s.Amount.Unit {
+			continue
+		}
+		if m != Average && !s.Lot.matches(l) {
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+	if m == LIFO {
+		for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		}
+	}
+	var want big.Int
+	want.Neg(&s.Amount.Number)
+	var reduced []*Lot
+	for _, i := range candidates {
+		if want.Sign() == 0 {
+			break
+		}
+		l := inv.Lots[i]
+		r := copyLot(l)
+		if r.Amount.Number.Cmp(&want) > 0 {
+			r.Amount.Number.Set(&want)
+		}
+		want.Sub(&want, &r.Amount.Number)
+		r.Amount.Neg()
+		reduced = append(reduced, r)
+	}
+	if want.Sign() != 0 {
+		short := &Amount{Unit: s.Amount.Unit}
+		short.Number.Set(&want)
+		return nil, fmt.Errorf("not enough %s in matching lots (short by %s)", s.Amount.Unit, short)
+	}
+	for k, i := range candidates[:len(reduced)] {
+		n := &inv.Lots[i].Amount.Number
+		n.Add(n, &reduced[k].Amount.Number)
+	}
+	lots := inv.Lots[:0]
+	for _, l := range inv.Lots {
+		if !l.Amount.Zero() {
+			lots = append(lots, l)
+		}
+	}
+	inv.Lots = lots
+	return reduced, nil
+}
+
+func copyLot(l *Lot) *Lot {
+	return &Lot{
+		Amount:   copyAmount(l.Amount),
+		Cost:     new(big.Rat).Set(l.Cost),
+		CostUnit: l.CostUnit,
+		Date:     l.Date,
+	}
+}
+
+func copyAmount(a *Amount) *Amount {
+	a2 := &Amount{Unit: a.Unit}
+	a2.Number.Set(&a.Number)
+	return a2
+}
+
+// An Inventories maps accounts to their lot inventories.
+type Inventories map[Account]*Inventory
+
+// get returns the inventory for an account, adding it if needed.
+func (i Inventories) get(a Account) *Inventory {
+	inv, ok := i[a]
+	if !ok {
+		inv = new(Inventory)
+		i[a] = inv
+	}
+	return inv
+}
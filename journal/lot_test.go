@@ -0,0 +1,261 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const lotHeader = `unit USD 100
+unit VTI 1000
+tx 2020-01-01 "Buy"
+Assets:Broker 10 VTI {100 USD} [2020-01-01]
+Assets:Cash
+end
+tx 2020-02-01 "Buy"
+Assets:Broker 10 VTI {120 USD}
+Assets:Cash
+end
+`
+
+func TestCompile_lots(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		desc    string
+		booking string
+		sale    string
+		want    []string
+		gains   string
+	}{
+		{
+			desc:    "fifo",
+			booking: "fifo",
+			sale:    "Assets:Broker -15 VTI {}",
+			want:    []string{"5.000 VTI {120 USD} [2020-02-01]"},
+			gains:   "-200.00 USD",
+		},
+		{
+			desc:    "lifo",
+			booking: "lifo",
+			sale:    "Assets:Broker -15 VTI {}",
+			want:    []string{"5.000 VTI {100 USD} [2020-01-01]"},
+			gains:   "-100.00 USD",
+		},
+		{
+			desc:    "specific by date",
+			booking: "specific",
+			sale:    "Assets:Broker -5 VTI [2020-02-01]",
+			want: []string{
+				"10.000 VTI {100 USD} [2020-01-01]",
+				"5.000 VTI {120 USD} [2020-02-01]",
+			},
+			gains: "-1,200.00 USD",
+		},
+		{
+			desc:    "specific by cost",
+			booking: "specific",
+			sale:    "Assets:Broker -5 VTI {100 USD}",
+			want: []string{
+				"5.000 VTI {100 USD} [2020-01-01]",
+				"10.000 VTI {120 USD} [2020-02-01]",
+			},
+			gains: "-1,300.00 USD",
+		},
+		{
+			desc:    "average",
+			booking: "average",
+			sale:    "Assets:Broker -15 VTI {}",
+			want:    []string{"5.000 VTI {110 USD} [2020-01-01]"},
+			gains:   "-150.00 USD",
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			j, err := compileText(fmt.Sprintf(`%saccount Assets:Broker
+meta "booking" "%s"
+end
+tx 2020-03-01 "Sell"
+%s
+Assets:Cash 1800 USD
+Income:Gains
+end
+`, lotHeader, c.booking, c.sale))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.want, formatLots(j.Inventories["Assets:Broker"])); diff != "" {
+				t.Errorf("lots mismatch (-want +got):\n%s", diff)
+			}
+			if got := j.Balances["Income:Gains"].String(); got != c.gains {
+				t.Errorf("Got gains %s; want %s", got, c.gains)
+			}
+		})
+	}
+}
+
+func TestCompile_lots_booked(t *testing.T) {
+	t.Parallel()
+	j, err := compileText(lotHeader + `tx 2020-03-01 "Sell"
+Assets:Broker -15 VTI {}
+Assets:Cash 1800 USD
+Income:Gains
+end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := j.Entries[2].(*Transaction)
+	var got []string
+	for _, l := range tx.Splits[0].Booked {
+		got = append(got, formatLot(l))
+	}
+	want := []string{
+		"-10.000 VTI {100 USD} [2020-01-01]",
+		"-5.000 VTI {120 USD} [2020-02-01]",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("booked lots mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := tx.Splits[2].Amount.String(), "-200.00 USD"; got != want {
+		t.Errorf("Got inferred amount %s; want %s", got, want)
+	}
+}
+
+func TestCompile_lots_sold_out(t *testing.T) {
+	t.Parallel()
+	j, err := compileText(lotHeader + `tx 2020-03-01 "Sell"
+Assets:Broker -20 VTI {}
+Assets:Cash
+end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv, ok := j.Inventories["Assets:Broker"]; ok {
+		t.Errorf("Got inventory %v; want none", formatLots(inv))
+	}
+	if got, want := j.Balances["Assets:Cash"].String(), "0"; got != want {
+		t.Errorf("Got cash %s; want %s", got, want)
+	}
+}
+
+func TestCompile_lot_errors(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		desc string
+		text string
+		want string
+	}{
+		{
+			desc: "not enough",
+			text: lotHeader + `tx 2020-03-01 "Sell"
+Assets:Broker -25 VTI {}
+Assets:Cash
+end
+`,
+			want: "not enough VTI in matching lots (short by 5.000 VTI)",
+		},
+		{
+			desc: "no matching lot",
+			text: lotHeader + `tx 2020-03-01 "Sell"
+Assets:Broker -1 VTI {200 USD}
+Assets:Cash
+end
+`,
+			want: "not enough VTI in matching lots (short by 1.000 VTI)",
+		},
+		{
+			desc: "specific without annotation",
+			text: lotHeader + `account Assets:Broker
+meta "booking" "specific"
+end
+tx 2020-03-01 "Sell"
+Assets:Broker -1 VTI {}
+Assets:Cash
+end
+`,
+			want: "lot annotation must have cost or date for specific booking",
+		},
+		{
+			desc: "unknown booking method",
+			text: lotHeader + `account Assets:Broker
+meta "booking" "hifo"
+end
+`,
+			want: `unknown booking method "hifo"`,
+		},
+		{
+			desc: "new lot without cost",
+			text: lotHeader + `tx 2020-03-01 "Buy"
+Assets:Broker 1 VTI [2020-03-01]
+Assets:Cash -100 USD
+end
+`,
+			want: "cannot add lot of VTI without cost",
+		},
+		{
+			desc: "cost in same unit",
+			text: lotHeader + `tx 2020-03-01 "Buy"
+Assets:Broker 1 VTI {1 VTI}
+Assets:Cash -1 VTI
+end
+`,
+			want: "lot cost of unit VTI in itself",
+		},
+		{
+			desc: "unbalanced after booking",
+			text: lotHeader + `tx 2020-03-01 "Sell"
+Assets:Broker -1 VTI {}
+Assets:Cash 150 USD
+end
+`,
+			want: "transaction doesn't balance (off by 50.00 USD)",
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := compileText(c.text)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), c.want) {
+				t.Errorf("Got error %q; want %q", err, c.want)
+			}
+		})
+	}
+}
+
+func formatLots(inv *Inventory) []string {
+	if inv == nil {
+		return nil
+	}
+	var s []string
+	for _, l := range inv.Lots {
+		s = append(s, formatLot(l))
+	}
+	return s
+}
+
+func formatLot(l *Lot) string {
+	return fmt.Sprintf("%s {%s %s} [%s]", l.Amount, l.Cost.RatString(), l.CostUnit, l.Date)
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"math/big"
+	"sort"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// A Price describes the price of a unit in another unit on a date.
+type Price struct {
+	EntryPos  token.Position
+	EntryDate civil.Date
+	// Unit is the unit being priced.
+	Unit Unit
+	// Quote is the unit that the price is in.
+	Quote Unit
+	// Rate is the amount of Quote for one Unit.
+	Rate *big.Rat
+}
+
+func (p *Price) Position() token.Position {
+	return p.EntryPos
+}
+
+func (p *Price) Date() civil.Date {
+	return p.EntryDate
+}
+
+// A PriceDB is a database of historical prices.
+// A nil PriceDB has no prices.
+type PriceDB struct {
+	// Prices for each pair of units, sorted by date.
+	m map[unitPair][]*Price
+}
+
+type unitPair struct {
+	unit, quote Unit
+}
+
+// newPriceDB makes a PriceDB from prices on or before the given
+// date.  If the date is not valid, all prices are used.
+func newPriceDB(p []*Price, ending civil.Date) *PriceDB {
+	db := &PriceDB{m: make(map[unitPair][]*Price)}
+	for _, p := range p {
+		if ending.IsValid() && p.EntryDate.After(ending) {
+			continue
+		}
+		k := unitPair{p.Unit, p.Quote}
+		db.m[k] = append(db.m[k], p)
+	}
+	for _, p := range db.m {
+		sort.SliceStable(p, func(i, j int) bool {
+			return p[i].EntryDate.Before(p[j].EntryDate)
+		})
+	}
+	return db
+}
+
+// ending returns a database with the prices on or before the date.
+func (db *PriceDB) ending(d civil.Date) *PriceDB {
+	if db == nil {
+		return nil
+	}
+	db2 := &PriceDB{m: make(map[unitPair][]*Price)}
+	for k, p := range db.m {
+		i := sort.Search(len(p), func(i int) bool {
+			return p[i].EntryDate.After(d)
+		})
+		if i > 0 {
+			db2.m[k] = p[:i:i]
+		}
+	}
+	return db2
+}
+
+// Prices returns all of the prices in the database, sorted by date.
+func (db *PriceDB) Prices() []*Price {
+	if db == nil {
+		return nil
+	}
+	var ps []*Price
+	for _, p := range db.m {
+		ps = append(ps, p...)
+	}
+	sort.SliceStable(ps, func(i, j int) bool {
+		if ps[i].EntryDate != ps[j].EntryDate {
+			return ps[i].EntryDate.Before(ps[j].EntryDate)
+		}
+		if ps[i].Unit != ps[j].Unit {
+			return ps[i].Unit.Symbol < ps[j].Unit.Symbol
+		}
+		return ps[i].Quote.Symbol < ps[j].Quote.Symbol
+	})
+	return ps
+}
+
+// Rate returns the amount of the to unit for one of the from unit,
+// using the most recent price on or before the given date.
+// Prices for the inverse pair of units are also considered.
+// The second return value is false if there is no applicable price.
+func (db *PriceDB) Rate(from, to Unit, on civil.Date) (*big.Rat, bool) {
+	if from == to {
+		return big.NewRat(1, 1), true
+	}
+	if db == nil {
+		return nil, false
+	}
+	p := db.latest(unitPair{from, to}, on)
+	inv := db.latest(unitPair{to, from}, on)
+	switch {
+	case p != nil && (inv == nil || !inv.EntryDate.After(p.EntryDate)):
+		return new(big.Rat).Set(p.Rate), true
+	case inv != nil:
+		return new(big.Rat).Inv(inv.Rate), true
+	default:
+		return nil, false
+	}
+}
+
+// latest returns the most recent price for the pair on or before the
+// date, or nil.
+func (db *PriceDB) latest(k unitPair, on civil.Date) *Price {
+	p := db.m[k]
+	i := sort.Search(len(p), func(i int) bool {
+		return p[i].EntryDate.After(on)
+	})
+	if i == 0 {
+		return nil
+	}
+	return p[i-1]
+}
+
+// Convert converts an amount to the given unit using the price on
+// the given date, rounding to the nearest fractional unit.
+// The second return value is false if there is no applicable price.
+func (db *PriceDB) Convert(a *Amount, to Unit, on civil.Date) (*Amount, bool) {
+	r, ok := db.Rate(a.Unit, to, on)
+	if !ok {
+		return nil, false
+	}
+	a2 := &Amount{Unit: to}
+	r2 := newRat()
+	defer ratPool.Put(r2)
+	r.Mul(r, r2.SetInt(&a.Number))
+	r.Mul(r, r2.SetFrac(new(big.Int).SetUint64(to.Scale), new(big.Int).SetUint64(a.Unit.Scale)))
+	roundRat(&a2.Number, r)
+	return a2, true
+}
+
+// roundRat sets n to r rounded to the nearest integer, with halves
+// rounded away from zero.
+func roundRat(n *big.Int, r *big.Rat) {
+	m := newInt()
+	defer intPool.Put(m)
+	n.QuoRem(r.Num(), r.Denom(), m)
+	m.Abs(m)
+	m.Lsh(m, 1)
+	if m.Cmp(r.Denom()) >= 0 {
+		if r.Sign() < 0 {
+			n.Sub(n, big.NewInt(1))
+		} else {
+			n.Add(n, big.NewInt(1))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"math/big"
+	"testing"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestPriceDB_Rate(t *testing.T) {
+	t.Parallel()
+	usd := Unit{Symbol: "USD", Scale: 100}
+	aapl := Unit{Symbol: "AAPL", Scale: 1}
+	jpy := Unit{Symbol: "JPY", Scale: 1}
+	db := newPriceDB([]*Price{
+		{EntryDate: civil.Date{2020, 2, 1}, Unit: aapl, Quote: usd, Rate: big.NewRat(200, 1)},
+		{EntryDate: civil.Date{2020, 1, 1}, Unit: aapl, Quote: usd, Rate: big.NewRat(100, 1)},
+		{EntryDate: civil.Date{2020, 1, 1}, Unit: usd, Quote: jpy, Rate: big.NewRat(110, 1)},
+		{EntryDate: civil.Date{2020, 3, 1}, Unit: aapl, Quote: usd, Rate: big.NewRat(300, 1)},
+	}, civil.Date{2020, 2, 15})
+	cases := []struct {
+		desc     string
+		from, to Unit
+		on       civil.Date
+		want     *big.Rat
+	}{
+		{"before first", aapl, usd, civil.Date{2019, 12, 31}, nil},
+		{"on date", aapl, usd, civil.Date{2020, 1, 1}, big.NewRat(100, 1)},
+		{"between dates", aapl, usd, civil.Date{2020, 1, 15}, big.NewRat(100, 1)},
+		{"latest", aapl, usd, civil.Date{2020, 2, 10}, big.NewRat(200, 1)},
+		{"after ending", aapl, usd, civil.Date{2020, 3, 10}, big.NewRat(200, 1)},
+		{"inverse", usd, aapl, civil.Date{2020, 2, 10}, big.NewRat(1, 200)},
+		{"same unit", jpy, jpy, civil.Date{2000, 1, 1}, big.NewRat(1, 1)},
+		{"unrelated", aapl, jpy, civil.Date{2020, 2, 10}, nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			got, ok := db.Rate(c.from, c.to, c.on)
+			if c.want == nil {
+				if ok {
+					t.Errorf("Got rate %s; want none", got)
+				}
+				return
+			}
+			if !ok || got.Cmp(c.want) != 0 {
+				t.Errorf("Got rate %s, %v; want %s", got, ok, c.want)
+			}
+		})
+	}
+}
+
+func TestPriceDB_Convert(t *testing.T) {
+	t.Parallel()
+	usd := Unit{Symbol: "USD", Scale: 100}
+	vti := Unit{Symbol: "VTI", Scale: 1000}
+	db := newPriceDB([]*Price{
+		{EntryDate: civil.Date{2020, 1, 1}, Unit: vti, Quote: usd, Rate: big.NewRat(12345, 100)},
+	}, civil.Date{})
+	a := &Amount{Unit: vti}
+	a.Number.SetInt64(54321)
+	got, ok := db.Convert(a, usd, civil.Date{2020, 1, 1})
+	if !ok {
+		t.Fatal("Expected conversion")
+	}
+	// 54.321 * 123.45 = 6705.92745
+	if got, want := got.String(), "6,705.93 USD"; got != want {
+		t.Errorf("Got %s; want %s", got, want)
+	}
+}
+
+func TestCompile_prices(t *testing.T) {
+	t.Parallel()
+	j, err := compileText(`unit USD 100
+unit AAPL 1
+price 2020-01-31 AAPL 185.20 USD
+price 2020-02-29 AAPL 190 USD
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := j.Prices.Rate(j.Units["AAPL"], j.Units["USD"], civil.Date{2020, 2, 1})
+	if want := big.NewRat(18520, 100); !ok || got.Cmp(want) != 0 {
+		t.Errorf("Got rate %s, %v; want %s", got, ok, want)
+	}
+}
+
+func TestRoundRat(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		r    *big.Rat
+		want int64
+	}{
+		{big.NewRat(5, 2), 3},
+		{big.NewRat(-5, 2), -3},
+		{big.NewRat(7, 3), 2},
+		{big.NewRat(-7, 3), -2},
+		{big.NewRat(4, 1), 4},
+	}
+	for _, c := range cases {
+		var got big.Int
+		roundRat(&got, c.r)
+		if got.Int64() != c.want {
+			t.Errorf("roundRat(%s) = %s; want %d", c.r, &got, c.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"sort"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// A Recurring is a transaction template that repeats every Count
+// intervals, starting on a date.
+type Recurring struct {
+	EntryPos  token.Position
+	EntryDate civil.Date
+	Interval  Interval
+	// Count is the number of intervals between occurrences.
+	Count int
+	// Template is the transaction for each occurrence.  The
+	// template is dated on the first occurrence.
+	Template *Transaction
+}
+
+func (r *Recurring) Position() token.Position {
+	return r.EntryPos
+}
+
+func (r *Recurring) Date() civil.Date {
+	return r.EntryDate
+}
+
+// Occurrences returns the dates of the occurrences between the start
+// and end dates, inclusive.
+func (r *Recurring) Occurrences(start, end civil.Date) []civil.Date {
+	var ds []civil.Date
+	for n := 0; ; n++ {
+		// Always add from the first occurrence so the day of
+		// the month is kept after short months.
+		d := r.Interval.Add(r.EntryDate, n*r.Count)
+		if d.After(end) {
+			return ds
+		}
+		if !d.Before(start) {
+			ds = append(ds, d)
+		}
+	}
+}
+
+// Transaction returns a new transaction for an occurrence on the
+// date.
+func (r *Recurring) Transaction(d civil.Date) *Transaction {
+	t := *r.Template
+	t.EntryDate = d
+	t.Splits = make([]Split, len(r.Template.Splits))
+	for i, s := range r.Template.Splits {
+		if s.Amount != nil {
+			s.Amount = copyAmount(s.Amount)
+		}
+		t.Splits[i] = s
+	}
+	return &t
+}
+
+// Due returns transactions for the occurrences of the journal's
+// recurring entries through the given date that are due, sorted by
+// date.  See dueTransactions.
+func (j *Journal) Due(through civil.Date) []*Transaction {
+	return dueTransactions(j.Recurring, j.Entries, through)
+}
+
+// dueTransactions returns transactions for the occurrences of the
+// recurring entries through the given date that are due, sorted by
+// date.
+//
+// An occurrence is due if it is after the last transaction in the
+// entries with the same description as the recurring entry.  Thus
+// occurrences that have been entered, whether generated or by hand,
+// are not due again.
+func dueTransactions(rs []*Recurring, e []Entry, through civil.Date) []*Transaction {
+	last := make(map[string]civil.Date)
+	for _, e := range e {
+		t, ok := e.(*Transaction)
+		if !ok {
+			continue
+		}
+		if d, ok := last[t.Description]; !ok || t.EntryDate.After(d) {
+			last[t.Description] = t.EntryDate
+		}
+	}
+	var ts []*Transaction
+	for _, r := range rs {
+		start := r.EntryDate
+		if d, ok := last[r.Template.Description]; ok && !d.Before(start) {
+			start = d.AddDays(1)
+		}
+		for _, d := range r.Occurrences(start, through) {
+			ts = append(ts, r.Transaction(d))
+		}
+	}
+	sort.SliceStable(ts, func(i, j int) bool {
+		return ts[i].EntryDate.Before(ts[j].EntryDate)
+	})
+	return ts
+}
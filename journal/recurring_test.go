@@ -0,0 +1,110 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+)
+
+const recurringText = `unit USD 100
+tx 2020-01-01 "Opening"
+Assets:Cash 5000 USD
+Equity:Opening
+end
+recurring 2020-01-31 monthly "Rent"
+Expenses:Rent 1000 USD
+Assets:Cash
+end
+recurring 2020-01-03 2 weekly "Salary"
+Assets:Cash 800 USD
+Income:Salary
+end
+tx 2020-02-29 "Rent"
+Expenses:Rent 1000 USD
+Assets:Cash
+end
+`
+
+func TestRecurring_Occurrences(t *testing.T) {
+	t.Parallel()
+	r := &Recurring{EntryDate: civil.Date{2020, 1, 31}, Interval: Monthly, Count: 1}
+	got := r.Occurrences(civil.Date{2020, 2, 1}, civil.Date{2020, 5, 30})
+	want := []civil.Date{{2020, 2, 29}, {2020, 3, 31}, {2020, 4, 30}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("occurrences mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestJournal_Due(t *testing.T) {
+	t.Parallel()
+	j, err := Compile(&CompileArgs{
+		Inputs: []CompileInput{Bytes("testfile", []byte(recurringText))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := j.Balances["Income:Salary"]; !got.Empty() {
+		t.Errorf("Got Income:Salary balance %s; want empty", got)
+	}
+	var got []string
+	for _, t := range j.Due(civil.Date{2020, 3, 31}) {
+		got = append(got, t.EntryDate.String()+" "+t.Description+" "+t.Splits[1].Amount.String())
+	}
+	want := []string{
+		"2020-01-03 Salary -800.00 USD",
+		"2020-01-17 Salary -800.00 USD",
+		"2020-01-31 Salary -800.00 USD",
+		"2020-02-14 Salary -800.00 USD",
+		"2020-02-28 Salary -800.00 USD",
+		"2020-03-13 Salary -800.00 USD",
+		"2020-03-27 Salary -800.00 USD",
+		"2020-03-31 Rent -1,000.00 USD",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("due mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompile_forecast(t *testing.T) {
+	t.Parallel()
+	j, err := Compile(&CompileArgs{
+		Inputs:   []CompileInput{Bytes("testfile", []byte(recurringText))},
+		Forecast: civil.Date{2020, 3, 31},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := j.Balances["Assets:Cash"].String(), "8,600.00 USD"; got != want {
+		t.Errorf("Got Assets:Cash balance %s; want %s", got, want)
+	}
+}
+
+func TestCompile_bad_recurring_count(t *testing.T) {
+	t.Parallel()
+	_, err := Compile(&CompileArgs{
+		Inputs: []CompileInput{Bytes("testfile", []byte(`unit USD 100
+recurring 2020-01-01 0.5 monthly "Rent"
+Expenses:Rent 1000 USD
+Assets:Cash
+end
+`))},
+	})
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// A Rule adds splits to matching transactions when a journal is
+// compiled.
+//
+// A rule with an account matches each split for the account or its
+// sub-accounts, and adds its splits once for each matched split.  A
+// rule without an account adds its splits once to each matching
+// transaction.
+type Rule struct {
+	EntryPos    token.Position
+	Description string
+	// Account is the account of matching splits, or empty.
+	Account Account
+	// Pattern matches the description of matching transactions,
+	// or is nil.
+	Pattern *regexp.Regexp
+	// Tags are the tags of matching transactions.  Tags on a
+	// matched split also count.
+	Tags   []string
+	Splits []RuleSplit
+}
+
+func (r *Rule) Position() token.Position {
+	return r.EntryPos
+}
+
+// A RuleSplit is a split added by a rule.
+type RuleSplit struct {
+	Account Account
+	// Amount is the amount of the split, or nil.
+	Amount *Amount
+	// Ratio is the ratio of the split amount to the amount of the
+	// matched split, or nil.  If both Amount and Ratio are nil,
+	// the split balances the other added splits.
+	Ratio *big.Rat
+	// Metadata contains the split metadata, or nil if none.
+	Metadata map[string]string
+	// Tags contains the split tags without the leading #.
+	Tags []string
+}
+
+// apply adds the rule's splits to the transaction if it matches.
+// Only the first n splits of the transaction are matched, so that
+// splits added by rules are not matched.
+func (r *Rule) apply(t *Transaction, n int) error {
+	if r.Pattern != nil && !r.Pattern.MatchString(t.Description) {
+		return nil
+	}
+	if r.Account == "" {
+		if !r.hasTags(t.Tags, nil) {
+			return nil
+		}
+		return r.addSplits(t, nil)
+	}
+	for i := 0; i < n; i++ {
+		// Copy the split since adding splits may move them.
+		s := t.Splits[i]
+		if s.Account != r.Account && !s.Account.Under(r.Account) {
+			continue
+		}
+		if !r.hasTags(t.Tags, s.Tags) {
+			continue
+		}
+		if s.Amount == nil {
+			return fmt.Errorf("amount of matched split for %s is not known", s.Account)
+		}
+		if err := r.addSplits(t, s.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasTags returns whether the transaction or split tags include all
+// of the rule's tags.
+func (r *Rule) hasTags(ttags, stags []string) bool {
+	for _, tag := range r.Tags {
+		if !hasTag(ttags, tag) && !hasTag(stags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// addSplits adds the rule's splits to the transaction for a matched
+// amount, which is nil for rules without an account.
+func (r *Rule) addSplits(t *Transaction, matched *Amount) error {
+	var bal Balance
+	empty := -1
+	for _, rs := range r.Splits {
+		s := Split{
+			Account:  rs.Account,
+			Metadata: rs.Metadata,
+			Tags:     rs.Tags,
+			Rule:     r,
+		}
+		switch {
+		case rs.Amount != nil:
+			s.Amount = copyAmount(rs.Amount)
+		case rs.Ratio != nil:
+			s.Amount = &Amount{Unit: matched.Unit}
+			var x big.Rat
+			x.Mul(x.SetInt(&matched.Number), rs.Ratio)
+			roundRat(&s.Amount.Number, &x)
+		default:
+			empty = len(t.Splits)
+		}
+		if s.Amount != nil {
+			bal.Add(s.Amount)
+		}
+		t.Splits = append(t.Splits, s)
+	}
+	if empty < 0 {
+		if !bal.Empty() {
+			return fmt.Errorf("added splits don't balance (off by %s)", &bal)
+		}
+		return nil
+	}
+	amounts := bal.Amounts()
+	switch len(amounts) {
+	case 0:
+		// The other splits are all zero.
+		a := &Amount{Unit: r.zeroUnit(matched)}
+		t.Splits[empty].Amount = a
+	case 1:
+		a := amounts[0]
+		a.Neg()
+		t.Splits[empty].Amount = a
+	default:
+		return fmt.Errorf("cannot infer missing split amount with balance %s", &bal)
+	}
+	return nil
+}
+
+// zeroUnit returns the unit for a balancing split when the other
+// added splits are all zero.
+func (r *Rule) zeroUnit(matched *Amount) Unit {
+	for _, rs := range r.Splits {
+		if rs.Amount != nil {
+			return rs.Amount.Unit
+		}
+	}
+	return matched.Unit
+}
+
+// applyRules adds the splits of the rules to matching transactions.
+// Rules are applied in order, and splits added by rules are not
+// matched by rules.  Transactions for pad entries are skipped, since
+// their splits are only known once the balance is asserted.
+func (b *builder) applyRules(e []Entry) error {
+	if len(b.rules) == 0 {
+		return nil
+	}
+	for _, e := range e {
+		t, ok := e.(*Transaction)
+		if !ok || t.Pad != nil {
+			continue
+		}
+		n := len(t.Splits)
+		for _, r := range b.rules {
+			if err := r.apply(t, n); err != nil {
+				b.errorAt(t.EntryPos, "rule %q at %s: %s", r.Description, r.EntryPos, err)
+			}
+		}
+	}
+	if err := b.errs.Err(); err != nil {
+		return fmt.Errorf("apply rules: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompile_rules(t *testing.T) {
+	t.Parallel()
+	j, err := Compile(&CompileArgs{
+		Inputs: []CompileInput{Bytes("testfile", []byte(`unit USD 100
+rule "Tax reserve"
+match Income:Consulting
+Liabilities:TaxReserve 12.5%
+Expenses:Taxes
+end
+rule "Reserve fee"
+match Liabilities:TaxReserve
+Expenses:Fees 1 USD
+Assets:Cash -1 USD
+end
+rule "Client fee"
+match "(?i)^acme"
+match #client
+Expenses:Fees 2 USD
+Assets:Cash
+end
+tx 2020-01-05 "ACME invoice" #client
+Assets:Cash 100.01 USD
+Income:Consulting:ACME
+end
+tx 2020-01-06 "ACME refund"
+Assets:Cash -10 USD
+Income:Consulting:ACME
+end
+`))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range j.Entries {
+		for _, s := range e.(*Transaction).Splits {
+			r := ""
+			if s.Rule != nil {
+				r = " (" + s.Rule.Description + ")"
+			}
+			got = append(got, fmt.Sprintf("%s %s %s%s", e.Date(), s.Account, s.Amount, r))
+		}
+	}
+	want := []string{
+		"2020-01-05 Assets:Cash 100.01 USD",
+		"2020-01-05 Income:Consulting:ACME -100.01 USD",
+		"2020-01-05 Liabilities:TaxReserve -12.50 USD (Tax reserve)",
+		"2020-01-05 Expenses:Taxes 12.50 USD (Tax reserve)",
+		"2020-01-05 Expenses:Fees 2.00 USD (Client fee)",
+		"2020-01-05 Assets:Cash -2.00 USD (Client fee)",
+		"2020-01-06 Assets:Cash -10.00 USD",
+		"2020-01-06 Income:Consulting:ACME 10.00 USD",
+		"2020-01-06 Liabilities:TaxReserve 1.25 USD (Tax reserve)",
+		"2020-01-06 Expenses:Taxes -1.25 USD (Tax reserve)",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("splits mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := j.Rules[0].Position().String(), "testfile:2:1"; got != want {
+		t.Errorf("Got rule position %s; want %s", got, want)
+	}
+}
+
+func TestCompile_rules_skip_pad(t *testing.T) {
+	t.Parallel()
+	j, err := Compile(&CompileArgs{
+		Inputs: []CompileInput{Bytes("testfile", []byte(`unit USD 100
+rule "Bank fee"
+match "."
+Expenses:Fees 1 USD
+Assets:Bank -1 USD
+end
+pad 2024-01-01 Assets:Bank Equity:Opening
+balance 2024-01-31 Assets:Bank 100 USD
+`))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := j.Balances["Assets:Bank"].String(), "100.00 USD"; got != want {
+		t.Errorf("Got Assets:Bank balance %s; want %s", got, want)
+	}
+	for _, e := range j.Entries {
+		t2, ok := e.(*Transaction)
+		if !ok {
+			continue
+		}
+		for _, s := range t2.Splits {
+			if s.Rule != nil {
+				t.Errorf("Got rule split %s %s in pad transaction", s.Account, s.Amount)
+			}
+		}
+	}
+}
+
+func TestCompile_rule_errors(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		desc string
+		src  string
+	}{
+		{
+			desc: "unbalanced",
+			src: `rule "Tax"
+match Income:Consulting
+Liabilities:TaxReserve 20%
+Expenses:Taxes 10%
+end
+tx 2020-01-05 "Invoice"
+Assets:Cash 100 USD
+Income:Consulting
+end
+`,
+		},
+		{
+			desc: "percentage without account",
+			src: `rule "Tax"
+match "Invoice"
+Liabilities:TaxReserve 20%
+Expenses:Taxes
+end
+`,
+		},
+		{
+			desc: "no amount without account",
+			src: `rule "x"
+match "Coffee"
+Expenses:Misc
+end
+tx 2020-01-05 "Coffee"
+Expenses:Food 3 USD
+Assets:Cash
+end
+`,
+		},
+		{
+			desc: "percentage in transaction",
+			src: `tx 2020-01-05 "Invoice"
+Assets:Cash 20%
+Income:Consulting
+end
+`,
+		},
+		{
+			desc: "bad regexp",
+			src: `rule "Tax"
+match "("
+Expenses:Taxes 1 USD
+Assets:Cash
+end
+`,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := Compile(&CompileArgs{
+				Inputs: []CompileInput{Bytes("testfile", []byte("unit USD 100\n"+c.src))},
+			})
+			if err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
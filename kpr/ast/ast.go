@@ -24,6 +24,30 @@ import (
 type File struct {
 	Entries  []Entry
 	Comments []*CommentGroup
+
+	// The following fields are only set when parsing in lossless
+	// mode.
+
+	// Src is the source of the file.
+	Src []byte
+	// Trivia maps the parsed entries to their source spans and
+	// attached comments.
+	Trivia map[Entry]*Trivia
+}
+
+// Trivia describes the source surrounding an entry parsed in
+// lossless mode.
+type Trivia struct {
+	// Leading is the group of comment lines directly above the
+	// entry, or nil.
+	Leading *CommentGroup
+	// Trailing is the comment on the last line of the entry, or
+	// nil.
+	Trailing *Comment
+	// From is the start of the line of the leading comments, or of
+	// the entry if there are none.  To is the position after the
+	// newline ending the last line of the entry.
+	From, To token.Pos
 }
 
 // All node types implement the Node interface.
@@ -59,10 +83,36 @@ func (a *Amount) End() token.Pos {
 	return a.Unit.End()
 }
 
+// A Lot node represents a lot annotation on an amount, e.g.,
+// {220.15 USD} [2024-03-01].  Either the cost or the date part may be
+// omitted, but not both.
+type Lot struct {
+	Lbrace token.Pos   // position of "{", or NoPos if no cost part
+	Cost   *Amount     // nil for empty braces
+	Rbrace token.Pos   // position of "}"
+	Lbrack token.Pos   // position of "[", or NoPos if no date part
+	Date   *BasicValue // DATE, or nil if no date part
+	Rbrack token.Pos   // position of "]"
+}
+
+func (l *Lot) Pos() token.Pos {
+	if l.Lbrace.IsValid() {
+		return l.Lbrace
+	}
+	return l.Lbrack
+}
+
+func (l *Lot) End() token.Pos {
+	if l.Lbrack.IsValid() {
+		return l.Rbrack + 1
+	}
+	return l.Rbrace + 1
+}
+
 // A BasicValue node represents a basic single token value.
 type BasicValue struct {
 	ValuePos token.Pos
-	Kind     token.Token // STRING, USYMBOL, ACCTNAME, DECIMAL, DATE
+	Kind     token.Token // STRING, USYMBOL, ACCTNAME, DECIMAL, DATE, TAG, INTERVAL
 	Value    string
 }
 
@@ -26,6 +26,9 @@ type Entry interface {
 // errors for which a correct entry node cannot be created.
 type BadEntry struct {
 	From, To token.Pos
+	// Text is the source of the node.  It is only set when
+	// parsing in lossless mode.
+	Text string
 }
 
 func (b *BadEntry) Pos() token.Pos {
@@ -42,7 +45,8 @@ func (*BadEntry) entry() {}
 // line.
 type SingleBalance struct {
 	BalanceHeader
-	Amount *Amount
+	Amount    *Amount
+	Tolerance *BasicValue // TILDE or nil
 }
 
 func (b *SingleBalance) End() token.Pos {
@@ -71,6 +75,7 @@ type BalanceHeader struct {
 	Token   token.Token
 	Date    *BasicValue // DATE
 	Account *BasicValue // ACCTNAME
+	Partial token.Pos   // position of "partial" keyword, or NoPos
 }
 
 func (b *BalanceHeader) Pos() token.Pos {
@@ -78,6 +83,9 @@ func (b *BalanceHeader) Pos() token.Pos {
 }
 
 func (b *BalanceHeader) End() token.Pos {
+	if b.Partial.IsValid() {
+		return token.Pos(int(b.Partial) + len("partial"))
+	}
 	return b.Account.End()
 }
 
@@ -99,11 +107,15 @@ func (u *UnitDecl) End() token.Pos {
 func (*UnitDecl) entry() {}
 
 // A Transaction node represents a transaction entry node.
+//
+// Metadata lines before the first split apply to the transaction, and
+// metadata lines after a split apply to that split.
 type Transaction struct {
 	TokPos      token.Pos
-	Date        *BasicValue // DATE
-	Description *BasicValue // STRING
-	Splits      []LineNode  // SplitLine, BadLine
+	Date        *BasicValue   // DATE
+	Description *BasicValue   // STRING
+	Tags        []*BasicValue // TAG
+	Splits      []LineNode    // SplitLine, MetadataLine, BadLine
 	EndTok      *End
 }
 
@@ -115,6 +127,52 @@ func (t *Transaction) End() token.Pos {
 	return t.EndTok.End()
 }
 
+// A Rule node represents an automated posting rule entry node.
+//
+// Match lines give the conditions for the rule, and the split and
+// metadata lines are like those of a Transaction.
+type Rule struct {
+	TokPos      token.Pos
+	Description *BasicValue // STRING
+	Lines       []LineNode  // MatchLine, SplitLine, MetadataLine, BadLine
+	EndTok      *End
+}
+
+func (r *Rule) Pos() token.Pos {
+	return r.TokPos
+}
+
+func (r *Rule) End() token.Pos {
+	return r.EndTok.End()
+}
+
+func (*Rule) entry() {}
+
+// A Recurring node represents a recurring transaction template entry
+// node.
+//
+// The body is like that of a Transaction.
+type Recurring struct {
+	TokPos      token.Pos
+	Date        *BasicValue   // DATE
+	Count       *BasicValue   // DECIMAL or nil
+	Interval    *BasicValue   // INTERVAL
+	Description *BasicValue   // STRING
+	Tags        []*BasicValue // TAG
+	Splits      []LineNode    // SplitLine, MetadataLine, BadLine
+	EndTok      *End
+}
+
+func (r *Recurring) Pos() token.Pos {
+	return r.TokPos
+}
+
+func (r *Recurring) End() token.Pos {
+	return r.EndTok.End()
+}
+
+func (*Recurring) entry() {}
+
 func (*Transaction) entry() {}
 
 // A DisableAccount node represents a disable account entry node.
@@ -134,6 +192,24 @@ func (c *DisableAccount) End() token.Pos {
 
 func (*DisableAccount) entry() {}
 
+// A Pad node represents a pad entry node.
+type Pad struct {
+	TokPos  token.Pos
+	Date    *BasicValue // DATE
+	Account *BasicValue // ACCTNAME
+	Source  *BasicValue // ACCTNAME
+}
+
+func (p *Pad) Pos() token.Pos {
+	return p.TokPos
+}
+
+func (p *Pad) End() token.Pos {
+	return p.Source.End()
+}
+
+func (*Pad) entry() {}
+
 // A DeclareAccount node represents a declare account entry node.
 type DeclareAccount struct {
 	TokPos   token.Pos
@@ -151,3 +227,56 @@ func (c *DeclareAccount) End() token.Pos {
 }
 
 func (*DeclareAccount) entry() {}
+
+// An Include node represents an include entry node.
+type Include struct {
+	TokPos token.Pos
+	Path   *BasicValue // STRING
+}
+
+func (i *Include) Pos() token.Pos {
+	return i.TokPos
+}
+
+func (i *Include) End() token.Pos {
+	return i.Path.End()
+}
+
+func (*Include) entry() {}
+
+// A Price node represents a price entry node.
+type Price struct {
+	TokPos token.Pos
+	Date   *BasicValue // DATE
+	Unit   *BasicValue // USYMBOL
+	Amount *Amount
+}
+
+func (p *Price) Pos() token.Pos {
+	return p.TokPos
+}
+
+func (p *Price) End() token.Pos {
+	return p.Amount.End()
+}
+
+func (*Price) entry() {}
+
+// A Budget node represents a budget entry node.
+type Budget struct {
+	TokPos   token.Pos
+	Date     *BasicValue // DATE
+	Account  *BasicValue // ACCTNAME
+	Amount   *Amount
+	Interval *BasicValue // INTERVAL
+}
+
+func (b *Budget) Pos() token.Pos {
+	return b.TokPos
+}
+
+func (b *Budget) End() token.Pos {
+	return b.Interval.End()
+}
+
+func (*Budget) entry() {}
@@ -26,6 +26,9 @@ type LineNode interface {
 // errors for which a correct line node cannot be created.
 type BadLine struct {
 	From, To token.Pos
+	// Text is the source of the node.  It is only set when
+	// parsing in lossless mode.
+	Text string
 }
 
 func (b *BadLine) Pos() token.Pos {
@@ -39,9 +42,14 @@ func (b *BadLine) End() token.Pos {
 func (*BadLine) lineNode() {}
 
 // A SplitLine node represents a split line node in a transaction.
+//
+// Split lines in rules may have a percentage instead of an amount.
 type SplitLine struct {
 	Account *BasicValue // STRING
 	Amount  *Amount
+	Percent *BasicValue   // PERCENT or nil
+	Lot     *Lot          // nil if no lot annotation
+	Tags    []*BasicValue // TAG
 }
 
 func (s *SplitLine) Pos() token.Pos {
@@ -49,21 +57,48 @@ func (s *SplitLine) Pos() token.Pos {
 }
 
 func (s *SplitLine) End() token.Pos {
+	if n := len(s.Tags); n > 0 {
+		return s.Tags[n-1].End()
+	}
+	if s.Percent != nil {
+		return s.Percent.End()
+	}
 	if s.Amount == nil {
 		return s.Account.End()
 	}
+	if s.Lot != nil {
+		return s.Lot.End()
+	}
 	return s.Amount.End()
 }
 
 func (*SplitLine) lineNode() {}
 
-// An AmountLine node represents an amount line node.
+// An AmountLine node represents an amount line node in a balance
+// assertion.
 type AmountLine struct {
 	*Amount
+	Tolerance *BasicValue // TILDE or nil
 }
 
 func (*AmountLine) lineNode() {}
 
+// A MatchLine node represents a rule match line.
+type MatchLine struct {
+	TokPos token.Pos
+	Value  *BasicValue // ACCTNAME, STRING, or TAG
+}
+
+func (m *MatchLine) Pos() token.Pos {
+	return m.TokPos
+}
+
+func (m *MatchLine) End() token.Pos {
+	return m.Value.End()
+}
+
+func (*MatchLine) lineNode() {}
+
 // A MetadataLine node represents a metadata line.
 type MetadataLine struct {
 	TokPos token.Pos
@@ -46,10 +46,21 @@ Numbers can start with a dash:
 
  -1,234.56
 
+Percentages are decimal numbers followed by a percent sign:
+
+ 12.5%
+
 Dates are in ISO 8601 format:
 
  2000-01-31
 
+Intervals describe how often something repeats:
+
+ weekly
+ monthly
+ quarterly
+ yearly
+
 There are some keywords:
 
  tx
@@ -60,6 +71,13 @@ There are some keywords:
  account
  treebal
  meta
+ include
+ price
+ budget
+ recurring
+ rule
+ match
+ pad
 
 Comments are supported:
 
@@ -86,6 +104,32 @@ can be omitted from splits:
  Equity:Capital
  end
 
+Splits can have a lot annotation giving the cost per unit and the
+acquisition date of the amount.  Either part can be omitted, and
+empty braces book the amount against existing lots at their cost:
+
+ tx 2024-03-01 "Buy VTI"
+ Assets:Broker 10 VTI {220.15 USD} [2024-03-01]
+ Assets:Cash
+ end
+
+Transactions and splits can have metadata and tags.  Metadata lines
+before the first split belong to the transaction and metadata lines
+after a split belong to that split.  Tags follow the transaction
+description or the split:
+
+ tx 2024-03-02 "Dinner" #food #trip/2024
+ meta "payee" "Bistro"
+ Expenses:Food 30 USD #work
+ meta "receipt" "2024-03-02.pdf"
+ Assets:Cash
+ end
+
+Tags start with # and are followed by letters, digits, and -_:/.
+characters.  Tags are only recognized on lines that can have them.
+Elsewhere, or when followed by anything other than tags, a # starts
+a comment instead.
+
 Balance assertions assert the balance of an account.  They can be
 multi line for accounts that contain multiple unit types.
 
@@ -95,6 +139,25 @@ multi line for accounts that contain multiple unit types.
  10 BTC
  end
 
+Amounts in balance assertions can have a tolerance after a tilde.
+The assertion passes if the balance is within the tolerance of the
+amount:
+
+ balance 2020-01-01 Some:account 5.00 ~0.01 USD
+
+By default, balance assertions assert all units in the account.  If
+the partial keyword follows the account, only the listed units are
+asserted:
+
+ balance 2020-01-01 Assets:Broker partial 10 VTI
+
+To make all balance assertions for an account partial, set the
+"balance" account metadata to "partial":
+
+ account Assets:Broker
+ meta "balance" "partial"
+ end
+
 Tree balance assertions are like normal balance assertions:
 
  treebal 2020-01-01 Some:account 5 USD
@@ -103,6 +166,12 @@ Tree balance assertions are like normal balance assertions:
  10 BTC
  end
 
+Pad entries pad an account from another account so that the next
+balance assertion for the account passes:
+
+ pad 2020-01-01 Assets:Bank Equity:Opening
+ balance 2020-01-31 Assets:Bank 1,000 USD
+
 Disable account entries disable an account for use:
 
  disable 2020-01-01 Some:account
@@ -112,5 +181,49 @@ Account declarations provide account metadata:
  account Some:account
  meta "my key" "my value"
  end
+
+Price entries record the price of a unit in another unit on a date:
+
+ price 2020-01-31 AAPL 185.20 USD
+
+Budget entries set the amount budgeted for an account tree for each
+interval, starting on a date.  The date may be a month, which starts
+on the first day of the month:
+
+ budget 2025-01 Expenses:Food 600 USD monthly
+
+Recurring entries are transaction templates that repeat every
+interval starting on a date.  An optional count before the interval
+repeats every count intervals.  They are not transactions themselves;
+the keeper forecast and generate commands create transactions from
+them:
+
+ recurring 2025-01-01 monthly "Rent"
+ Expenses:Rent 1,200 USD
+ Assets:Cash
+ end
+
+ recurring 2025-01-03 2 weekly "Salary" #work
+ Assets:Cash 2,000 USD
+ Income:Salary
+ end
+
+Rule entries add splits to matching transactions.  Match lines give
+an account, a regular expression for the description, or a tag that
+matching transactions must have.  Split amounts in rules can be a
+percentage of the amount of the matched account's split.  Rules
+without an account match need at least one split with an amount:
+
+ rule "Tax reserve"
+ match Income:Consulting
+ match #client
+ Liabilities:TaxReserve 20%
+ Expenses:Taxes
+ end
+
+Include entries include other keeper files.  The path may be a glob
+pattern and is relative to the directory of the including file:
+
+ include "2020/*.kpr"
 */
 package kpr
@@ -33,6 +33,11 @@ type Mode uint
 
 const (
 	ParseComments Mode = 1 << iota
+	// Lossless records the source of the file and the span and
+	// attached comments of each entry in the ast.File, so that
+	// entries can be rewritten without changing the rest of the
+	// source.  Lossless implies ParseComments.
+	Lossless
 )
 
 // ParseBytes parses the contents of a keeper file.
@@ -51,7 +56,11 @@ const (
 func ParseBytes(fset *token.FileSet, filename string, src []byte, mode Mode) (*ast.File, error) {
 	p := &parser{
 		f:             fset.AddFile(filename, -1, len(src)),
-		parseComments: mode&ParseComments != 0,
+		parseComments: mode&(ParseComments|Lossless) != 0,
+	}
+	if mode&Lossless != 0 {
+		p.src = src
+		p.trivia = make(map[ast.Entry]*ast.Trivia)
 	}
 	var m scanner.Mode
 	if p.parseComments {
@@ -62,6 +71,8 @@ func ParseBytes(fset *token.FileSet, filename string, src []byte, mode Mode) (*a
 	return &ast.File{
 		Entries:  p.entries,
 		Comments: p.comments,
+		Src:      p.src,
+		Trivia:   p.trivia,
 	}, p.errs.Err()
 }
 
@@ -72,6 +83,13 @@ type parser struct {
 	current       *line
 	curGroup      *ast.CommentGroup
 
+	// Set in lossless mode
+	src    []byte
+	trivia map[ast.Entry]*ast.Trivia
+	// leading holds the comment lines since the last blank line
+	// or entry.
+	leading []*ast.Comment
+
 	entries  []ast.Entry
 	comments []*ast.CommentGroup
 	errs     scanner.ErrorList
@@ -92,10 +110,23 @@ func (p *parser) nextLine() *line {
 			c.List = append(c.List, l.comment)
 		}
 	}
+	if p.lossless() {
+		switch {
+		case !l.Empty():
+		case l.comment != nil:
+			p.leading = append(p.leading, l.comment)
+		default:
+			p.leading = nil
+		}
+	}
 	p.current = l
 	return l
 }
 
+func (p *parser) lossless() bool {
+	return p.trivia != nil
+}
+
 func (p *parser) errorf(pos token.Pos, format string, v ...interface{}) {
 	p.errs.Add(p.f.Position(pos), fmt.Sprintf(format, v...))
 }
@@ -109,9 +140,54 @@ func (p *parser) parse() {
 		if l.Empty() {
 			continue
 		}
+		leading := p.leading
 		e := p.parseEntry(l)
 		p.entries = append(p.entries, e)
+		if p.lossless() {
+			p.addTrivia(e, l, leading)
+			p.leading = nil
+		}
+	}
+}
+
+// addTrivia records the trivia for an entry starting on the given
+// line, which has just been parsed.  The leading comments are the
+// comment lines directly above the start line.
+func (p *parser) addTrivia(e ast.Entry, start *line, leading []*ast.Comment) {
+	t := &ast.Trivia{
+		From: p.lineStart(start.Pos()),
+		To:   p.current.End(),
+	}
+	if len(leading) > 0 {
+		t.Leading = &ast.CommentGroup{List: leading}
+		t.From = p.lineStart(leading[0].Pos())
+	}
+	if !p.current.Empty() {
+		t.Trailing = p.current.comment
+	}
+	p.trivia[e] = t
+}
+
+// lineStart returns the start of the line containing pos.
+func (p *parser) lineStart(pos token.Pos) token.Pos {
+	return p.f.LineStart(p.f.Line(pos))
+}
+
+// text returns the source between the positions in lossless mode,
+// or the empty string otherwise.
+func (p *parser) text(from, to token.Pos) string {
+	if !p.lossless() {
+		return ""
 	}
+	return string(p.src[p.f.Offset(from):p.f.Offset(to)])
+}
+
+func (p *parser) badEntry(l *line) *ast.BadEntry {
+	return &ast.BadEntry{From: l.Pos(), To: l.End(), Text: p.text(l.Pos(), l.End())}
+}
+
+func (p *parser) badLine(l *line) *ast.BadLine {
+	return &ast.BadLine{From: l.Pos(), To: l.End(), Text: p.text(l.Pos(), l.End())}
 }
 
 func (p *parser) parseEntry(l *line) ast.Entry {
@@ -124,18 +200,30 @@ func (p *parser) parseEntry(l *line) ast.Entry {
 		return p.parseBalance(l)
 	case token.DISABLE:
 		return p.parseDisableAccount(l)
+	case token.PAD:
+		return p.parsePad(l)
 	case token.ACCOUNT:
 		return p.parseDeclareAccount(l)
+	case token.INCLUDE:
+		return p.parseInclude(l)
+	case token.PRICE:
+		return p.parsePrice(l)
+	case token.BUDGET:
+		return p.parseBudget(l)
+	case token.RECURRING:
+		return p.parseRecurring(l)
+	case token.RULE:
+		return p.parseRule(l)
 	default:
 		p.errorf(l.Pos(), "bad entry starting with %s", l.tokens[0].lit)
-		return &ast.BadEntry{From: l.Pos(), To: l.End()}
+		return p.badEntry(l)
 	}
 }
 
 func (p *parser) parseUnitDecl(l *line) ast.Entry {
 	if err := matchTokens(l.tokens, token.UNIT, token.USYMBOL, token.DECIMAL); err != nil {
 		p.errorf(l.Pos(), "%s", err)
-		return &ast.BadEntry{From: l.Pos(), To: l.End()}
+		return p.badEntry(l)
 	}
 	u := &ast.UnitDecl{
 		TokPos: l.Pos(),
@@ -146,59 +234,184 @@ func (p *parser) parseUnitDecl(l *line) ast.Entry {
 }
 
 func (p *parser) parseTransaction(l *line) ast.Entry {
-	if err := matchTokens(l.tokens, token.TX, token.DATE, token.STRING); err != nil {
+	t, tags := splitTags(l.tokens)
+	if err := matchTokens(t, token.TX, token.DATE, token.STRING); err != nil {
 		p.errorf(l.Pos(), "%s", err)
-		return &ast.BadEntry{From: l.Pos(), To: l.End()}
+		return p.badEntry(l)
 	}
 	e := &ast.Transaction{
 		TokPos:      l.Pos(),
-		Date:        tokVal(l.tokens[1]),
-		Description: tokVal(l.tokens[2]),
+		Date:        tokVal(t[1]),
+		Description: tokVal(t[2]),
+		Tags:        tags,
+	}
+	e.Splits, e.EndTok = p.parseSplits(l)
+	return e
+}
+
+func (p *parser) parseRecurring(l *line) ast.Entry {
+	t, tags := splitTags(l.tokens)
+	e := &ast.Recurring{
+		TokPos: l.Pos(),
+		Tags:   tags,
+	}
+	switch {
+	case matchTokens(t, token.RECURRING, token.DATE, token.INTERVAL, token.STRING) == nil:
+		e.Interval = tokVal(t[2])
+		e.Description = tokVal(t[3])
+	case matchTokens(t, token.RECURRING, token.DATE, token.DECIMAL, token.INTERVAL, token.STRING) == nil:
+		e.Count = tokVal(t[2])
+		e.Interval = tokVal(t[3])
+		e.Description = tokVal(t[4])
+	default:
+		p.errorf(l.Pos(), "%s", matchTokens(t, token.RECURRING, token.DATE, token.INTERVAL, token.STRING))
+		return p.badEntry(l)
 	}
+	e.Date = tokVal(t[1])
+	e.Splits, e.EndTok = p.parseSplits(l)
+	return e
+}
+
+func (p *parser) parseRule(l *line) ast.Entry {
+	if err := matchTokens(l.tokens, token.RULE, token.STRING); err != nil {
+		p.errorf(l.Pos(), "%s", err)
+		return p.badEntry(l)
+	}
+	e := &ast.Rule{
+		TokPos:      l.Pos(),
+		Description: tokVal(l.tokens[1]),
+	}
+	e.Lines, e.EndTok = p.parseBody(l, func(l *line) ast.LineNode {
+		if l.tokens[0].tok == token.MATCH {
+			return p.parseMatchLine(l)
+		}
+		return p.parseSplitLine(l)
+	})
+	return e
+}
+
+func (p *parser) parseMatchLine(l *line) ast.LineNode {
+	for _, tok := range []token.Token{token.ACCTNAME, token.STRING, token.TAG} {
+		if err := matchTokens(l.tokens, token.MATCH, tok); err == nil {
+			return &ast.MatchLine{
+				TokPos: l.Pos(),
+				Value:  tokVal(l.tokens[1]),
+			}
+		}
+	}
+	p.errorf(l.Pos(), "invalid match tokens %s", formatTokens(l.tokens))
+	return p.badLine(l)
+}
+
+// parseSplits parses the body lines of a transaction entry starting
+// on the given line.  The returned end is nil if the end of the file
+// is reached.
+func (p *parser) parseSplits(start *line) ([]ast.LineNode, *ast.End) {
+	return p.parseBody(start, p.parseSplitLine)
+}
+
+// parseSplitLine parses a split or metadata line.
+func (p *parser) parseSplitLine(l *line) ast.LineNode {
+	if l.tokens[0].tok == token.META {
+		return p.parseMetadataLine(l)
+	}
+	return p.parseSplit(l)
+}
+
+// parseBody parses the body lines of a multiple line entry starting
+// on the given line, using f to parse each line.  The returned end is
+// nil if the end of the file is reached.
+func (p *parser) parseBody(start *line, f func(*line) ast.LineNode) ([]ast.LineNode, *ast.End) {
+	var lines []ast.LineNode
 	for {
 		if p.current.EOF() {
-			p.errorf(l.End(), "unexpected EOF")
-			return e
+			p.errorf(start.End(), "unexpected EOF")
+			return lines, nil
 		}
 		l := p.nextLine()
 		if l.Empty() {
 			continue
 		}
 		if err := matchTokens(l.tokens, token.END); err == nil {
-			e.EndTok = &ast.End{TokPos: l.Pos()}
-			return e
+			return lines, &ast.End{TokPos: l.Pos()}
 		}
-		e.Splits = append(e.Splits, p.parseSplit(l))
+		lines = append(lines, f(l))
 	}
 }
 
 func (p *parser) parseSplit(l *line) ast.LineNode {
-	if err := matchTokens(l.tokens[:1], token.ACCTNAME); err != nil {
+	t, tags := splitTags(l.tokens)
+	if err := matchTokens(t[:1], token.ACCTNAME); err != nil {
 		p.errorf(l.Pos(), "%s", err)
-		return &ast.BadLine{From: l.Pos(), To: l.End()}
+		return p.badLine(l)
 	}
 	s := &ast.SplitLine{
-		Account: tokVal(l.tokens[0]),
+		Account: tokVal(t[0]),
+		Tags:    tags,
+	}
+	if len(t) == 1 {
+		return s
 	}
-	if len(l.tokens) == 1 {
+	if len(t) == 2 && t[1].tok == token.PERCENT {
+		s.Percent = tokVal(t[1])
 		return s
 	}
-	if err := matchTokens(l.tokens, token.ACCTNAME, token.DECIMAL, token.USYMBOL); err != nil {
+	if len(t) < 3 {
+		p.errorf(l.Pos(), "%s", matchTokens(t, token.ACCTNAME, token.DECIMAL, token.USYMBOL))
+		return p.badLine(l)
+	}
+	if err := matchTokens(t[:3], token.ACCTNAME, token.DECIMAL, token.USYMBOL); err != nil {
 		p.errorf(l.Pos(), "%s", err)
-		return &ast.BadLine{From: l.Pos(), To: l.End()}
+		return p.badLine(l)
+	}
+	s.Amount = tokAmount(t[1:])
+	if len(t) == 3 {
+		return s
 	}
-	s.Amount = tokAmount(l.tokens[1:])
+	lot, err := parseLot(t[3:])
+	if err != nil {
+		p.errorf(l.Pos(), "%s", err)
+		return p.badLine(l)
+	}
+	s.Lot = lot
 	return s
 }
 
+// parseLot parses the tokens of a lot annotation.
+func parseLot(t []tokenInfo) (*ast.Lot, error) {
+	lot := &ast.Lot{}
+	rest := t
+	if len(rest) > 0 && rest[0].tok == token.LBRACE {
+		switch {
+		case len(rest) >= 2 && rest[1].tok == token.RBRACE:
+			lot.Lbrace, lot.Rbrace = rest[0].pos, rest[1].pos
+			rest = rest[2:]
+		case len(rest) >= 4 && matchTokens(rest[:4], token.LBRACE, token.DECIMAL, token.USYMBOL, token.RBRACE) == nil:
+			lot.Lbrace, lot.Rbrace = rest[0].pos, rest[3].pos
+			lot.Cost = tokAmount(rest[1:])
+			rest = rest[4:]
+		default:
+			return nil, fmt.Errorf("invalid lot cost tokens %s", formatTokens(rest))
+		}
+	}
+	if len(rest) > 0 {
+		if err := matchTokens(rest, token.LBRACK, token.DATE, token.RBRACK); err != nil {
+			return nil, err
+		}
+		lot.Lbrack, lot.Rbrack = rest[0].pos, rest[2].pos
+		lot.Date = tokVal(rest[1])
+	}
+	return lot, nil
+}
+
 func (p *parser) parseBalance(l *line) ast.Entry {
 	if len(l.tokens) < 3 {
 		p.errorf(l.Pos(), "invalid tokens for balance")
-		return &ast.BadEntry{From: l.Pos(), To: l.End()}
+		return p.badEntry(l)
 	}
 	if err := matchTokens(l.tokens[1:3], token.DATE, token.ACCTNAME); err != nil {
 		p.errorf(l.Pos(), "%s", err)
-		return &ast.BadEntry{From: l.Pos(), To: l.End()}
+		return p.badEntry(l)
 	}
 	h := ast.BalanceHeader{
 		TokPos:  l.tokens[0].pos,
@@ -206,11 +419,17 @@ func (p *parser) parseBalance(l *line) ast.Entry {
 		Date:    tokVal(l.tokens[1]),
 		Account: tokVal(l.tokens[2]),
 	}
+	rest := l.tokens[3:]
+	if len(rest) > 0 && rest[0].tok == token.PARTIAL {
+		h.Partial = rest[0].pos
+		rest = rest[1:]
+	}
 
-	if err := matchTokens(l.tokens[3:], token.DECIMAL, token.USYMBOL); err == nil {
+	if a, tol, ok := balanceAmount(rest); ok {
 		return &ast.SingleBalance{
 			BalanceHeader: h,
-			Amount:        tokAmount(l.tokens[3:]),
+			Amount:        a,
+			Tolerance:     tol,
 		}
 	}
 
@@ -230,21 +449,38 @@ func (p *parser) parseBalance(l *line) ast.Entry {
 			e.EndTok = &ast.End{TokPos: l.Pos()}
 			return e
 		}
-		if err := matchTokens(l.tokens, token.DECIMAL, token.USYMBOL); err != nil {
-			p.errorf(l.Pos(), "%s", err)
-			n := &ast.BadLine{From: l.Pos(), To: l.End()}
+		a, tol, ok := balanceAmount(l.tokens)
+		if !ok {
+			p.errorf(l.Pos(), "%s", matchTokens(l.tokens, token.DECIMAL, token.USYMBOL))
+			n := p.badLine(l)
 			e.Amounts = append(e.Amounts, n)
 			continue
 		}
-		n := &ast.AmountLine{Amount: tokAmount(l.tokens)}
+		n := &ast.AmountLine{Amount: a, Tolerance: tol}
 		e.Amounts = append(e.Amounts, n)
 	}
 }
 
+// balanceAmount parses the tokens of an amount in a balance
+// assertion, which may have a tolerance before the unit.
+func balanceAmount(t []tokenInfo) (*ast.Amount, *ast.BasicValue, bool) {
+	if matchTokens(t, token.DECIMAL, token.USYMBOL) == nil {
+		return tokAmount(t), nil, true
+	}
+	if matchTokens(t, token.DECIMAL, token.TILDE, token.USYMBOL) == nil {
+		a := &ast.Amount{
+			Decimal: tokVal(t[0]),
+			Unit:    tokVal(t[2]),
+		}
+		return a, tokVal(t[1]), true
+	}
+	return nil, nil, false
+}
+
 func (p *parser) parseDisableAccount(l *line) ast.Entry {
 	if err := matchTokens(l.tokens, token.DISABLE, token.DATE, token.ACCTNAME); err != nil {
 		p.errorf(l.Pos(), "%s", err)
-		return &ast.BadEntry{From: l.Pos(), To: l.End()}
+		return p.badEntry(l)
 	}
 	e := &ast.DisableAccount{
 		TokPos:  l.Pos(),
@@ -254,10 +490,23 @@ func (p *parser) parseDisableAccount(l *line) ast.Entry {
 	return e
 }
 
+func (p *parser) parsePad(l *line) ast.Entry {
+	if err := matchTokens(l.tokens, token.PAD, token.DATE, token.ACCTNAME, token.ACCTNAME); err != nil {
+		p.errorf(l.Pos(), "%s", err)
+		return p.badEntry(l)
+	}
+	return &ast.Pad{
+		TokPos:  l.Pos(),
+		Date:    tokVal(l.tokens[1]),
+		Account: tokVal(l.tokens[2]),
+		Source:  tokVal(l.tokens[3]),
+	}
+}
+
 func (p *parser) parseDeclareAccount(l *line) ast.Entry {
 	if err := matchTokens(l.tokens, token.ACCOUNT, token.ACCTNAME); err != nil {
 		p.errorf(l.Pos(), "%s", err)
-		return &ast.BadEntry{From: l.Pos(), To: l.End()}
+		return p.badEntry(l)
 	}
 	e := &ast.DeclareAccount{
 		TokPos:  l.Pos(),
@@ -276,18 +525,57 @@ func (p *parser) parseDeclareAccount(l *line) ast.Entry {
 			e.EndTok = &ast.End{TokPos: l.Pos()}
 			return e
 		}
-		if err := matchTokens(l.tokens, token.META, token.STRING, token.STRING); err != nil {
-			p.errorf(l.Pos(), "%s", err)
-			n := &ast.BadLine{From: l.Pos(), To: l.End()}
-			e.Metadata = append(e.Metadata, n)
-			continue
-		}
-		n := &ast.MetadataLine{
-			TokPos: l.tokens[0].pos,
-			Key:    tokVal(l.tokens[1]),
-			Val:    tokVal(l.tokens[2]),
-		}
-		e.Metadata = append(e.Metadata, n)
+		e.Metadata = append(e.Metadata, p.parseMetadataLine(l))
+	}
+}
+
+func (p *parser) parseMetadataLine(l *line) ast.LineNode {
+	if err := matchTokens(l.tokens, token.META, token.STRING, token.STRING); err != nil {
+		p.errorf(l.Pos(), "%s", err)
+		return p.badLine(l)
+	}
+	return &ast.MetadataLine{
+		TokPos: l.tokens[0].pos,
+		Key:    tokVal(l.tokens[1]),
+		Val:    tokVal(l.tokens[2]),
+	}
+}
+
+func (p *parser) parseInclude(l *line) ast.Entry {
+	if err := matchTokens(l.tokens, token.INCLUDE, token.STRING); err != nil {
+		p.errorf(l.Pos(), "%s", err)
+		return p.badEntry(l)
+	}
+	return &ast.Include{
+		TokPos: l.Pos(),
+		Path:   tokVal(l.tokens[1]),
+	}
+}
+
+func (p *parser) parsePrice(l *line) ast.Entry {
+	if err := matchTokens(l.tokens, token.PRICE, token.DATE, token.USYMBOL, token.DECIMAL, token.USYMBOL); err != nil {
+		p.errorf(l.Pos(), "%s", err)
+		return p.badEntry(l)
+	}
+	return &ast.Price{
+		TokPos: l.Pos(),
+		Date:   tokVal(l.tokens[1]),
+		Unit:   tokVal(l.tokens[2]),
+		Amount: tokAmount(l.tokens[3:]),
+	}
+}
+
+func (p *parser) parseBudget(l *line) ast.Entry {
+	if err := matchTokens(l.tokens, token.BUDGET, token.DATE, token.ACCTNAME, token.DECIMAL, token.USYMBOL, token.INTERVAL); err != nil {
+		p.errorf(l.Pos(), "%s", err)
+		return p.badEntry(l)
+	}
+	return &ast.Budget{
+		TokPos:   l.Pos(),
+		Date:     tokVal(l.tokens[1]),
+		Account:  tokVal(l.tokens[2]),
+		Amount:   tokAmount(l.tokens[3:]),
+		Interval: tokVal(l.tokens[5]),
 	}
 }
 
@@ -303,6 +591,19 @@ func tokAmount(t []tokenInfo) *ast.Amount {
 	}
 }
 
+// splitTags splits trailing TAG tokens from the other tokens.
+func splitTags(t []tokenInfo) ([]tokenInfo, []*ast.BasicValue) {
+	i := len(t)
+	for i > 0 && t[i-1].tok == token.TAG {
+		i--
+	}
+	var tags []*ast.BasicValue
+	for _, t := range t[i:] {
+		tags = append(tags, tokVal(t))
+	}
+	return t[:i], tags
+}
+
 func tokVal(t tokenInfo) *ast.BasicValue {
 	return &ast.BasicValue{ValuePos: t.pos, Kind: t.tok, Value: t.lit}
 }
@@ -412,6 +412,92 @@ end
 	}
 }
 
+func TestParseBytes_balance_tolerance(t *testing.T) {
+	t.Parallel()
+	const input = `balance 2001-02-03 Some:account 1.00 ~0.01 USD
+balance 2001-02-05 Some:account
+1.00 ~0.01 USD
+5 JPY
+end
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.SingleBalance{
+			BalanceHeader: ast.BalanceHeader{
+				TokPos:  1,
+				Token:   token.BALANCE,
+				Date:    val(9, token.DATE, "2001-02-03"),
+				Account: val(20, token.ACCTNAME, "Some:account"),
+			},
+			Amount:    amount(33, "1.00", 44, "USD"),
+			Tolerance: val(38, token.TILDE, "~0.01"),
+		},
+		&ast.MultiBalance{
+			BalanceHeader: ast.BalanceHeader{
+				TokPos:  48,
+				Token:   token.BALANCE,
+				Date:    val(56, token.DATE, "2001-02-05"),
+				Account: val(67, token.ACCTNAME, "Some:account"),
+			},
+			Amounts: []ast.LineNode{
+				&ast.AmountLine{
+					Amount:    amount(80, "1.00", 91, "USD"),
+					Tolerance: val(85, token.TILDE, "~0.01"),
+				},
+				&ast.AmountLine{Amount: amount(95, "5", 97, "JPY")},
+			},
+			EndTok: &ast.End{TokPos: 101},
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_balance_partial(t *testing.T) {
+	t.Parallel()
+	const input = `balance 2001-02-03 Some:account partial 5 USD
+treebal 2001-02-05 Some:account partial
+5 JPY
+end
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.SingleBalance{
+			BalanceHeader: ast.BalanceHeader{
+				TokPos:  1,
+				Token:   token.BALANCE,
+				Date:    val(9, token.DATE, "2001-02-03"),
+				Account: val(20, token.ACCTNAME, "Some:account"),
+				Partial: 33,
+			},
+			Amount: amount(41, "5", 43, "USD"),
+		},
+		&ast.MultiBalance{
+			BalanceHeader: ast.BalanceHeader{
+				TokPos:  47,
+				Token:   token.TREEBAL,
+				Date:    val(55, token.DATE, "2001-02-05"),
+				Account: val(66, token.ACCTNAME, "Some:account"),
+				Partial: 79,
+			},
+			Amounts: []ast.LineNode{
+				&ast.AmountLine{Amount: amount(87, "5", 89, "JPY")},
+			},
+			EndTok: &ast.End{TokPos: 93},
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestParseBytes_ignore_comment_mode(t *testing.T) {
 	t.Parallel()
 	const input = `# kyaru
@@ -498,6 +584,311 @@ end
 	}
 }
 
+func TestParseBytes_tag_like_comments(t *testing.T) {
+	t.Parallel()
+	const input = `#header
+unit USD 100 #dollars
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ast.File{
+		Entries: []ast.Entry{
+			&ast.UnitDecl{
+				TokPos: 9,
+				Unit:   val(14, token.USYMBOL, "USD"),
+				Scale:  val(18, token.DECIMAL, "100"),
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_include(t *testing.T) {
+	t.Parallel()
+	const input = `include "2001/*.kpr"
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Include{
+			TokPos: 1,
+			Path:   val(9, token.STRING, `"2001/*.kpr"`),
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_lot(t *testing.T) {
+	t.Parallel()
+	const input = `tx 2001-02-03 "Buy stuff"
+Assets:Broker 10 VTI {220.15 USD} [2001-02-01]
+Assets:Broker -1 VTI {}
+Assets:Broker -2 VTI [2001-02-01]
+end
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Transaction{
+			TokPos:      1,
+			Date:        val(4, token.DATE, "2001-02-03"),
+			Description: val(15, token.STRING, `"Buy stuff"`),
+			Splits: []ast.LineNode{
+				&ast.SplitLine{
+					Account: val(27, token.ACCTNAME, "Assets:Broker"),
+					Amount:  amount(41, "10", 44, "VTI"),
+					Lot: &ast.Lot{
+						Lbrace: 48,
+						Cost:   amount(49, "220.15", 56, "USD"),
+						Rbrace: 59,
+						Lbrack: 61,
+						Date:   val(62, token.DATE, "2001-02-01"),
+						Rbrack: 72,
+					},
+				},
+				&ast.SplitLine{
+					Account: val(74, token.ACCTNAME, "Assets:Broker"),
+					Amount:  amount(88, "-1", 91, "VTI"),
+					Lot: &ast.Lot{
+						Lbrace: 95,
+						Rbrace: 96,
+					},
+				},
+				&ast.SplitLine{
+					Account: val(98, token.ACCTNAME, "Assets:Broker"),
+					Amount:  amount(112, "-2", 115, "VTI"),
+					Lot: &ast.Lot{
+						Lbrack: 119,
+						Date:   val(120, token.DATE, "2001-02-01"),
+						Rbrack: 130,
+					},
+				},
+			},
+			EndTok: &ast.End{TokPos: 132},
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_bad_lot(t *testing.T) {
+	t.Parallel()
+	for _, lot := range []string{"{", "{220.15}", "[2001-02-01", "[2001-02-01] {}", "{} {}"} {
+		input := "tx 2001-02-03 \"Buy stuff\"\nAssets:Broker 10 VTI " + lot + "\nend\n"
+		got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+		if err == nil {
+			t.Errorf("Parsing lot %q: expected error", lot)
+			continue
+		}
+		tx := got.Entries[0].(*ast.Transaction)
+		if _, ok := tx.Splits[0].(*ast.BadLine); !ok {
+			t.Errorf("Parsing lot %q: got %T; want *ast.BadLine", lot, tx.Splits[0])
+		}
+	}
+}
+
+func TestParseBytes_tx_metadata_and_tags(t *testing.T) {
+	t.Parallel()
+	const input = `tx 2001-02-03 "Dinner" #food
+meta "payee" "Bistro"
+Expenses:Food 30 USD #work
+Assets:Cash #cash
+end
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Transaction{
+			TokPos:      1,
+			Date:        val(4, token.DATE, "2001-02-03"),
+			Description: val(15, token.STRING, `"Dinner"`),
+			Tags:        []*ast.BasicValue{val(24, token.TAG, "#food")},
+			Splits: []ast.LineNode{
+				&ast.MetadataLine{
+					TokPos: 30,
+					Key:    val(35, token.STRING, `"payee"`),
+					Val:    val(43, token.STRING, `"Bistro"`),
+				},
+				&ast.SplitLine{
+					Account: val(52, token.ACCTNAME, "Expenses:Food"),
+					Amount:  amount(66, "30", 69, "USD"),
+					Tags:    []*ast.BasicValue{val(73, token.TAG, "#work")},
+				},
+				&ast.SplitLine{
+					Account: val(79, token.ACCTNAME, "Assets:Cash"),
+					Tags:    []*ast.BasicValue{val(91, token.TAG, "#cash")},
+				},
+			},
+			EndTok: &ast.End{TokPos: 97},
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_price(t *testing.T) {
+	t.Parallel()
+	const input = `price 2001-02-03 AAPL 185.20 USD
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Price{
+			TokPos: 1,
+			Date:   val(7, token.DATE, "2001-02-03"),
+			Unit:   val(18, token.USYMBOL, "AAPL"),
+			Amount: amount(23, "185.20", 30, "USD"),
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_budget(t *testing.T) {
+	t.Parallel()
+	const input = `budget 2001-02 Expenses:Food 600 USD monthly
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Budget{
+			TokPos:   1,
+			Date:     val(8, token.DATE, "2001-02"),
+			Account:  val(16, token.ACCTNAME, "Expenses:Food"),
+			Amount:   amount(30, "600", 34, "USD"),
+			Interval: val(38, token.INTERVAL, "monthly"),
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_recurring(t *testing.T) {
+	t.Parallel()
+	const input = `recurring 2001-02-03 2 weekly "Salary" #work
+Assets:Cash 100 USD
+Income:Salary
+end
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Recurring{
+			TokPos:      1,
+			Date:        val(11, token.DATE, "2001-02-03"),
+			Count:       val(22, token.DECIMAL, "2"),
+			Interval:    val(24, token.INTERVAL, "weekly"),
+			Description: val(31, token.STRING, `"Salary"`),
+			Tags:        []*ast.BasicValue{val(40, token.TAG, "#work")},
+			Splits: []ast.LineNode{
+				&ast.SplitLine{
+					Account: val(46, token.ACCTNAME, "Assets:Cash"),
+					Amount:  amount(58, "100", 62, "USD"),
+				},
+				&ast.SplitLine{
+					Account: val(66, token.ACCTNAME, "Income:Salary"),
+				},
+			},
+			EndTok: &ast.End{TokPos: 80},
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_bad_recurring(t *testing.T) {
+	t.Parallel()
+	const input = `recurring 2001-02-03 "Salary"
+Assets:Cash 100 USD
+Income:Salary
+end
+`
+	_, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestParseBytes_rule(t *testing.T) {
+	t.Parallel()
+	const input = `rule "Tax"
+match Income:Work
+match #client
+Liabilities:Tax 20%
+Expenses:Tax
+end
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Rule{
+			TokPos:      1,
+			Description: val(6, token.STRING, `"Tax"`),
+			Lines: []ast.LineNode{
+				&ast.MatchLine{TokPos: 12, Value: val(18, token.ACCTNAME, "Income:Work")},
+				&ast.MatchLine{TokPos: 30, Value: val(36, token.TAG, "#client")},
+				&ast.SplitLine{
+					Account: val(44, token.ACCTNAME, "Liabilities:Tax"),
+					Percent: val(60, token.PERCENT, "20%"),
+				},
+				&ast.SplitLine{
+					Account: val(64, token.ACCTNAME, "Expenses:Tax"),
+				},
+			},
+			EndTok: &ast.End{TokPos: 77},
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseBytes_pad(t *testing.T) {
+	t.Parallel()
+	const input = `pad 2001-02-03 Assets:Bank Equity:Opening
+`
+	got, err := ParseBytes(token.NewFileSet(), "", []byte(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ast.Entry{
+		&ast.Pad{
+			TokPos:  1,
+			Date:    val(5, token.DATE, "2001-02-03"),
+			Account: val(16, token.ACCTNAME, "Assets:Bank"),
+			Source:  val(28, token.ACCTNAME, "Equity:Opening"),
+		},
+	}
+	if diff := cmp.Diff(want, got.Entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func amount(pos1 token.Pos, lit1 string, pos2 token.Pos, lit2 string) *ast.Amount {
 	return &ast.Amount{
 		Decimal: val(pos1, token.DECIMAL, lit1),
@@ -508,3 +899,79 @@ func amount(pos1 token.Pos, lit1 string, pos2 token.Pos, lit2 string) *ast.Amoun
 func val(pos token.Pos, tok token.Token, lit string) *ast.BasicValue {
 	return &ast.BasicValue{ValuePos: pos, Kind: tok, Value: lit}
 }
+
+func TestParseBytes_lossless(t *testing.T) {
+	t.Parallel()
+	const input = `# file comment
+
+# leading
+# comments
+unit  USD 100 # unit
+tx 2001-02-03 "Some description"
+# inside
+Some:account 1.2 USD
+Other:account
+end # end
+
+bad entry
+  price 2001-02-03 JPY 0.01 USD`
+	fset := token.NewFileSet()
+	got, err := ParseBytes(fset, "", []byte(input), Lossless)
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+	if string(got.Src) != input {
+		t.Errorf("Got Src %q; want %q", got.Src, input)
+	}
+	type trivia struct {
+		Span     string
+		Leading  []string
+		Trailing string
+	}
+	var gotTrivia []trivia
+	for _, e := range got.Entries {
+		tr := got.Trivia[e]
+		if tr == nil {
+			t.Fatalf("No trivia for entry %T", e)
+		}
+		var g trivia
+		g.Span = input[fset.Position(tr.From).Offset:fset.Position(tr.To).Offset]
+		if tr.Leading != nil {
+			for _, c := range tr.Leading.List {
+				g.Leading = append(g.Leading, c.Text)
+			}
+		}
+		if tr.Trailing != nil {
+			g.Trailing = tr.Trailing.Text
+		}
+		gotTrivia = append(gotTrivia, g)
+	}
+	want := []trivia{
+		{
+			Span:     "# leading\n# comments\nunit  USD 100 # unit\n",
+			Leading:  []string{"# leading", "# comments"},
+			Trailing: "# unit",
+		},
+		{
+			Span: `tx 2001-02-03 "Some description"
+# inside
+Some:account 1.2 USD
+Other:account
+end # end
+`,
+			Trailing: "# end",
+		},
+		{Span: "bad entry\n"},
+		{Span: "  price 2001-02-03 JPY 0.01 USD"},
+	}
+	if diff := cmp.Diff(want, gotTrivia); diff != "" {
+		t.Errorf("trivia mismatch (-want +got):\n%s", diff)
+	}
+	b, ok := got.Entries[2].(*ast.BadEntry)
+	if !ok {
+		t.Fatalf("Got entry %T; want *ast.BadEntry", got.Entries[2])
+	}
+	if want := "bad entry\n"; b.Text != want {
+		t.Errorf("Got BadEntry text %q; want %q", b.Text, want)
+	}
+}
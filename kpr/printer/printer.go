@@ -0,0 +1,430 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package printer implements printing of AST nodes in canonical form.
+
+The canonical form has the following properties:
+
+  - Tokens on a line are separated by a single space.
+  - Decimal numbers have their integer part grouped by commas.
+  - Split amounts in a transaction and amounts in a balance assertion
+    are right aligned.
+  - Blank lines between entries are collapsed to at most one, and
+    multiple line entries are separated from other entries by a blank
+    line.
+  - Blank lines inside multiple line entries are removed.
+
+Comments are printed if the node is an *ast.File parsed with
+parser.ParseComments.
+
+Rewrite prints a file parsed with parser.Lossless, preserving the
+source of entries that were not changed.
+*/
+package printer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// Fprint prints an AST node to w in canonical form.
+// The node must be an *ast.File or an ast.Entry.
+//
+// Position information in fset is used for placing comments and
+// preserving blank lines between entries.  fset may be nil for nodes
+// that were not produced by the parser, in which case comments are
+// not printed.
+//
+// Nodes containing ast.BadEntry or ast.BadLine nodes cannot be
+// printed.
+func Fprint(w io.Writer, fset *token.FileSet, node interface{}) error {
+	p := &printer{fset: fset}
+	switch n := node.(type) {
+	case *ast.File:
+		if fset != nil {
+			for _, g := range n.Comments {
+				p.comments = append(p.comments, g.List...)
+			}
+			sort.Slice(p.comments, func(i, j int) bool {
+				return p.comments[i].Pos() < p.comments[j].Pos()
+			})
+		}
+		for _, e := range n.Entries {
+			p.entry(e)
+		}
+		p.flushComments(token.NoPos)
+	case ast.Entry:
+		p.entry(n)
+	default:
+		return fmt.Errorf("printer: unsupported node type %T", node)
+	}
+	if p.err != nil {
+		return p.err
+	}
+	bw := bufio.NewWriter(w)
+	for _, l := range p.lines {
+		bw.WriteString(l)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// Format formats the keeper file AST in canonical form and returns
+// the result.
+func Format(fset *token.FileSet, f *ast.File) ([]byte, error) {
+	var b bytes.Buffer
+	if err := Fprint(&b, fset, f); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+type printer struct {
+	fset *token.FileSet
+	// Comments not yet printed, sorted by position.
+	comments []*ast.Comment
+	// Output lines, without newlines.
+	lines []string
+	// Source line of the last printed line, or 0 if unknown.
+	lastLine int
+	// Whether the last printed item was a multiple line entry.
+	lastMulti bool
+	// Whether the last printed item was a comment line.
+	lastComment bool
+	// Whether an entry is being printed.
+	inEntry bool
+	// Whether anything has been printed.
+	started bool
+	err     error
+}
+
+// line returns the source line for the position, or 0 if unknown.
+func (p *printer) line(pos token.Pos) int {
+	if p.fset == nil || !pos.IsValid() {
+		return 0
+	}
+	return p.fset.Position(pos).Line
+}
+
+// separate adds a blank line before an entry or comment line at the
+// given position if needed.
+func (p *printer) separate(pos token.Pos, multi bool) {
+	if !p.started || p.inEntry {
+		return
+	}
+	l := p.line(pos)
+	switch {
+	case l > 0 && p.lastLine > 0 && l-p.lastLine > 1:
+	case p.lastMulti:
+	case multi && !p.lastComment:
+	default:
+		return
+	}
+	if n := len(p.lines); n > 0 && p.lines[n-1] != "" {
+		p.lines = append(p.lines, "")
+	}
+}
+
+// flushComments prints comments before the position as their own
+// lines.  If pos is NoPos, all remaining comments are printed.
+func (p *printer) flushComments(pos token.Pos) {
+	for len(p.comments) > 0 {
+		c := p.comments[0]
+		if pos.IsValid() && c.Pos() >= pos {
+			return
+		}
+		p.comments = p.comments[1:]
+		p.separate(c.Pos(), false)
+		p.lines = append(p.lines, c.Text)
+		p.lastLine = p.line(c.Pos())
+		p.lastMulti = false
+		p.lastComment = !p.inEntry
+		p.started = true
+	}
+}
+
+// printLine prints a line of tokens for the source range [pos, end).
+// A comment on the same line is printed after the tokens.
+func (p *printer) printLine(pos, end token.Pos, s string) {
+	p.flushComments(pos)
+	if len(p.comments) > 0 {
+		c := p.comments[0]
+		if l := p.line(end); l > 0 && p.line(c.Pos()) == l {
+			s += " " + c.Text
+			p.comments = p.comments[1:]
+		}
+	}
+	p.lines = append(p.lines, s)
+	if l := p.line(end); l > 0 {
+		p.lastLine = l
+	}
+	p.started = true
+}
+
+func (p *printer) entry(e ast.Entry) {
+	multi := isMultiLine(e)
+	p.flushComments(e.Pos())
+	p.separate(e.Pos(), multi)
+	p.inEntry = true
+	switch e := e.(type) {
+	case *ast.BadEntry:
+		p.errorf("cannot print bad entry at %s", p.position(e.Pos()))
+	case *ast.UnitDecl:
+		p.printLine(e.Pos(), e.End(), join("unit", e.Unit.Value, formatDecimal(e.Scale.Value)))
+	case *ast.Transaction:
+		end := e.Description.End()
+		if n := len(e.Tags); n > 0 {
+			end = e.Tags[n-1].End()
+		}
+		p.printLine(e.Pos(), end, joinTags(join("tx", e.Date.Value, e.Description.Value), e.Tags))
+		p.amountLines(e.Splits)
+		p.endLine(e.EndTok)
+	case *ast.Rule:
+		p.printLine(e.Pos(), e.Description.End(), join("rule", e.Description.Value))
+		p.amountLines(e.Lines)
+		p.endLine(e.EndTok)
+	case *ast.Recurring:
+		end := e.Description.End()
+		if n := len(e.Tags); n > 0 {
+			end = e.Tags[n-1].End()
+		}
+		s := []string{"recurring", e.Date.Value}
+		if e.Count != nil {
+			s = append(s, formatDecimal(e.Count.Value))
+		}
+		s = append(s, e.Interval.Value, e.Description.Value)
+		p.printLine(e.Pos(), end, joinTags(join(s...), e.Tags))
+		p.amountLines(e.Splits)
+		p.endLine(e.EndTok)
+	case *ast.SingleBalance:
+		p.printLine(e.Pos(), e.End(), join(balanceHeader(&e.BalanceHeader), formatBalanceAmount(e.Amount, e.Tolerance, 0)))
+	case *ast.MultiBalance:
+		p.printLine(e.Pos(), e.BalanceHeader.End(), balanceHeader(&e.BalanceHeader))
+		p.amountLines(e.Amounts)
+		p.endLine(e.EndTok)
+	case *ast.Pad:
+		p.printLine(e.Pos(), e.End(), join("pad", e.Date.Value, e.Account.Value, e.Source.Value))
+	case *ast.DisableAccount:
+		p.printLine(e.Pos(), e.End(), join("disable", e.Date.Value, e.Account.Value))
+	case *ast.DeclareAccount:
+		p.printLine(e.Pos(), e.Account.End(), join("account", e.Account.Value))
+		for _, n := range e.Metadata {
+			p.lineNode(n, 0, 0)
+		}
+		p.endLine(e.EndTok)
+	case *ast.Include:
+		p.printLine(e.Pos(), e.End(), join("include", e.Path.Value))
+	case *ast.Price:
+		p.printLine(e.Pos(), e.End(), join("price", e.Date.Value, e.Unit.Value, formatAmount(e.Amount)))
+	case *ast.Budget:
+		p.printLine(e.Pos(), e.End(), join("budget", e.Date.Value, e.Account.Value, formatAmount(e.Amount), e.Interval.Value))
+	default:
+		p.errorf("unknown entry node %T", e)
+	}
+	p.inEntry = false
+	p.lastMulti = multi
+	p.lastComment = false
+}
+
+// amountLines prints lines containing amounts, aligning the amounts.
+func (p *printer) amountLines(n []ast.LineNode) {
+	var acctWidth, decWidth int
+	for _, n := range n {
+		var a *ast.Amount
+		switch n := n.(type) {
+		case *ast.SplitLine:
+			acctWidth = max(acctWidth, width(n.Account.Value))
+			a = n.Amount
+			if n.Percent != nil {
+				decWidth = max(decWidth, width(formatPercent(n.Percent.Value)))
+			}
+		case *ast.AmountLine:
+			a = n.Amount
+		}
+		if a != nil {
+			decWidth = max(decWidth, width(formatDecimal(a.Decimal.Value)))
+		}
+	}
+	for _, n := range n {
+		p.lineNode(n, acctWidth, decWidth)
+	}
+}
+
+// lineNode prints a line node.
+// The widths are used to align split accounts and amounts.
+func (p *printer) lineNode(n ast.LineNode, acctWidth, decWidth int) {
+	switch n := n.(type) {
+	case *ast.BadLine:
+		p.errorf("cannot print bad line at %s", p.position(n.Pos()))
+	case *ast.SplitLine:
+		if n.Percent != nil {
+			d := formatPercent(n.Percent.Value)
+			s := pad(n.Account.Value, acctWidth) + " " + strings.Repeat(" ", decWidth-width(d)) + d
+			p.printLine(n.Pos(), n.End(), joinTags(s, n.Tags))
+			return
+		}
+		if n.Amount == nil {
+			p.printLine(n.Pos(), n.End(), joinTags(n.Account.Value, n.Tags))
+			return
+		}
+		s := pad(n.Account.Value, acctWidth) + " " + alignAmount(n.Amount, decWidth)
+		if n.Lot != nil {
+			s += " " + formatLot(n.Lot)
+		}
+		p.printLine(n.Pos(), n.End(), joinTags(s, n.Tags))
+	case *ast.AmountLine:
+		p.printLine(n.Pos(), n.End(), formatBalanceAmount(n.Amount, n.Tolerance, decWidth))
+	case *ast.MetadataLine:
+		p.printLine(n.Pos(), n.End(), join("meta", n.Key.Value, n.Val.Value))
+	case *ast.MatchLine:
+		p.printLine(n.Pos(), n.End(), join("match", n.Value.Value))
+	default:
+		p.errorf("unknown line node %T", n)
+	}
+}
+
+func (p *printer) endLine(e *ast.End) {
+	if e == nil {
+		p.printLine(token.NoPos, token.NoPos, "end")
+		return
+	}
+	p.printLine(e.Pos(), e.End(), "end")
+}
+
+func (p *printer) position(pos token.Pos) token.Position {
+	if p.fset == nil {
+		return token.Position{}
+	}
+	return p.fset.Position(pos)
+}
+
+func (p *printer) errorf(format string, v ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("printer: "+format, v...)
+	}
+}
+
+func isMultiLine(e ast.Entry) bool {
+	switch e.(type) {
+	case *ast.Transaction, *ast.Recurring, *ast.Rule, *ast.MultiBalance, *ast.DeclareAccount:
+		return true
+	default:
+		return false
+	}
+}
+
+func balanceHeader(h *ast.BalanceHeader) string {
+	kw := "balance"
+	if h.Token == token.TREEBAL {
+		kw = "treebal"
+	}
+	if h.Partial.IsValid() {
+		return join(kw, h.Date.Value, h.Account.Value, "partial")
+	}
+	return join(kw, h.Date.Value, h.Account.Value)
+}
+
+func formatAmount(a *ast.Amount) string {
+	return join(formatDecimal(a.Decimal.Value), a.Unit.Value)
+}
+
+func alignAmount(a *ast.Amount, decWidth int) string {
+	d := formatDecimal(a.Decimal.Value)
+	return strings.Repeat(" ", decWidth-width(d)) + join(d, a.Unit.Value)
+}
+
+// formatBalanceAmount formats an amount in a balance assertion with
+// an optional tolerance.
+func formatBalanceAmount(a *ast.Amount, tol *ast.BasicValue, decWidth int) string {
+	d := formatDecimal(a.Decimal.Value)
+	s := strings.Repeat(" ", max(0, decWidth-width(d))) + d
+	if tol != nil {
+		s = join(s, "~"+formatDecimal(strings.TrimPrefix(tol.Value, "~")))
+	}
+	return join(s, a.Unit.Value)
+}
+
+func formatLot(l *ast.Lot) string {
+	var s []string
+	switch {
+	case l.Cost != nil:
+		s = append(s, "{"+formatAmount(l.Cost)+"}")
+	case l.Lbrace.IsValid() || l.Date == nil:
+		s = append(s, "{}")
+	}
+	if l.Date != nil {
+		s = append(s, "["+l.Date.Value+"]")
+	}
+	return join(s...)
+}
+
+// formatDecimal normalizes the comma grouping of a decimal literal.
+func formatDecimal(s string) string {
+	s = strings.ReplaceAll(s, ",", "")
+	var b strings.Builder
+	if strings.HasPrefix(s, "-") {
+		b.WriteByte('-')
+		s = s[1:]
+	}
+	intPart, frac, hasDot := strings.Cut(s, ".")
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if hasDot {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// formatPercent normalizes the comma grouping of a percentage
+// literal.
+func formatPercent(s string) string {
+	return formatDecimal(strings.TrimSuffix(s, "%")) + "%"
+}
+
+func join(s ...string) string {
+	return strings.Join(s, " ")
+}
+
+// joinTags appends tags to a line.
+func joinTags(s string, tags []*ast.BasicValue) string {
+	for _, t := range tags {
+		s += " " + t.Value
+	}
+	return s
+}
+
+func width(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+func pad(s string, n int) string {
+	if w := width(s); w < n {
+		return s + strings.Repeat(" ", n-w)
+	}
+	return s
+}
@@ -0,0 +1,283 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/parser"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		desc  string
+		input string
+		want  string
+	}{
+		{
+			desc: "full example",
+			input: `unit   USD   100
+unit JPY 1
+tx 2001-02-03   "Buy stuff"
+Some:account   1200 USD
+
+Expenses:Stuff -1,2000.5 USD
+Assets:Cash
+end
+balance 2001-02-03 Some:account 1200 USD
+
+
+balance 2001-02-05 Some:account
+123.45 USD
+56700 JPY
+end
+disable 2001-02-06 Some:account
+pad 2001-02-01   Some:account  Equity:Opening
+account Some:account
+meta "foo"   "bar"
+end
+include "foo.kpr"
+price 2001-02-03   AAPL 1185.20 USD
+budget 2001-02   Expenses:Food 1200 USD   monthly
+rule "Tax"
+match   Income:Consulting
+match "(?i)acme"
+Liabilities:Tax 1200.5%
+Expenses:Tax
+end
+recurring 2001-02-01 1 monthly   "Rent"  #home
+Expenses:Rent 1200 USD
+Assets:Cash
+end
+tx 2001-02-04 "Buy VTI"
+Assets:Broker 10 VTI {  220.15 USD }   [ 2001-02-04 ]
+Assets:Broker -1 VTI [2001-02-01]
+Assets:Cash
+end
+tx 2001-02-05 "Dinner"   #food  #trip/2001
+meta "payee" "Bistro"
+Expenses:Food 30 USD   #work
+meta "receipt" "r1.pdf"
+Assets:Cash #cash
+end
+`,
+			want: `unit USD 100
+unit JPY 1
+
+tx 2001-02-03 "Buy stuff"
+Some:account       1,200 USD
+Expenses:Stuff -12,000.5 USD
+Assets:Cash
+end
+
+balance 2001-02-03 Some:account 1,200 USD
+
+balance 2001-02-05 Some:account
+123.45 USD
+56,700 JPY
+end
+
+disable 2001-02-06 Some:account
+pad 2001-02-01 Some:account Equity:Opening
+
+account Some:account
+meta "foo" "bar"
+end
+
+include "foo.kpr"
+price 2001-02-03 AAPL 1,185.20 USD
+budget 2001-02 Expenses:Food 1,200 USD monthly
+
+rule "Tax"
+match Income:Consulting
+match "(?i)acme"
+Liabilities:Tax 1,200.5%
+Expenses:Tax
+end
+
+recurring 2001-02-01 1 monthly "Rent" #home
+Expenses:Rent 1,200 USD
+Assets:Cash
+end
+
+tx 2001-02-04 "Buy VTI"
+Assets:Broker 10 VTI {220.15 USD} [2001-02-04]
+Assets:Broker -1 VTI [2001-02-01]
+Assets:Cash
+end
+
+tx 2001-02-05 "Dinner" #food #trip/2001
+meta "payee" "Bistro"
+Expenses:Food 30 USD #work
+meta "receipt" "r1.pdf"
+Assets:Cash #cash
+end
+`,
+		},
+		{
+			desc: "balance tolerances",
+			input: `balance 2001-02-03 Some:account 1200   ~1   USD
+balance 2001-02-05 Some:account
+123.45 ~0.01 USD
+56700 JPY
+end
+`,
+			want: `balance 2001-02-03 Some:account 1,200 ~1 USD
+
+balance 2001-02-05 Some:account
+123.45 ~0.01 USD
+56,700 JPY
+end
+`,
+		},
+		{
+			desc: "balance partial",
+			input: `balance 2001-02-03 Some:account   partial 5 USD
+treebal 2001-02-05 Some:account partial
+56700 JPY
+end
+`,
+			want: `balance 2001-02-03 Some:account partial 5 USD
+
+treebal 2001-02-05 Some:account partial
+56,700 JPY
+end
+`,
+		},
+		{
+			desc: "comments",
+			input: `# header
+
+# about USD
+unit USD 100 # dollars
+# about tx
+tx 2001-02-03 "Buy stuff" # header comment
+Some:account 1.2 USD
+# between splits
+Expenses:Stuff
+# before end
+end
+# trailing
+`,
+			want: `# header
+
+# about USD
+unit USD 100 # dollars
+# about tx
+tx 2001-02-03 "Buy stuff" # header comment
+Some:account   1.2 USD
+# between splits
+Expenses:Stuff
+# before end
+end
+
+# trailing
+`,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			got := format(t, c.input)
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+			if got2 := format(t, got); got2 != got {
+				t.Errorf("format not idempotent:\n%s", got2)
+			}
+		})
+	}
+}
+
+func TestFprint_without_positions(t *testing.T) {
+	t.Parallel()
+	e := &ast.Transaction{
+		Date:        &ast.BasicValue{Kind: token.DATE, Value: "2001-02-03"},
+		Description: &ast.BasicValue{Kind: token.STRING, Value: `"Buy stuff"`},
+		Splits: []ast.LineNode{
+			&ast.SplitLine{
+				Account: &ast.BasicValue{Kind: token.ACCTNAME, Value: "Expenses:Stuff"},
+				Amount: &ast.Amount{
+					Decimal: &ast.BasicValue{Kind: token.DECIMAL, Value: "1234.5"},
+					Unit:    &ast.BasicValue{Kind: token.USYMBOL, Value: "USD"},
+				},
+			},
+			&ast.SplitLine{
+				Account: &ast.BasicValue{Kind: token.ACCTNAME, Value: "Assets:Cash"},
+			},
+		},
+	}
+	var b bytes.Buffer
+	if err := Fprint(&b, nil, e); err != nil {
+		t.Fatal(err)
+	}
+	const want = `tx 2001-02-03 "Buy stuff"
+Expenses:Stuff 1,234.5 USD
+Assets:Cash
+end
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFprint_bad_entry(t *testing.T) {
+	t.Parallel()
+	fset := token.NewFileSet()
+	f, _ := parser.ParseBytes(fset, "", []byte("blah\n"), parser.ParseComments)
+	var b bytes.Buffer
+	if err := Fprint(&b, fset, f); err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		in, want string
+	}{
+		{"1", "1"},
+		{"123", "123"},
+		{"1234", "1,234"},
+		{"-1234567.891", "-1,234,567.891"},
+		{"12,34.5", "1,234.5"},
+		{"-123", "-123"},
+	}
+	for _, c := range cases {
+		if got := formatDecimal(c.in); got != c.want {
+			t.Errorf("formatDecimal(%q) = %q; want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func format(t *testing.T, s string) string {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseBytes(fset, "", []byte(s), parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Format(fset, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(got)
+}
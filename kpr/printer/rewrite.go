@@ -0,0 +1,277 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/scanner"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+// Rewrite prints a file parsed with parser.Lossless, copying the
+// source of unchanged entries so that their comments and spacing are
+// preserved.
+//
+// Parsed entries in f.Entries are copied verbatim along with the
+// source between them.  Parsed entries removed from f.Entries are
+// omitted along with their leading and trailing comments.  Other
+// entries are printed in canonical form.  A new entry in the place of
+// a removed entry replaces it, keeping the leading comments of the
+// removed entry and printing its other comments with the new entry;
+// comments stay on their lines if the new entry has the positions of
+// the removed entry, as when it is a modified copy.
+//
+// If a modified copy differs from the removed entry only in the
+// values of its tokens, such as a renamed account, the source of the
+// removed entry is copied with only the changed values replaced, so
+// its spacing is preserved too.
+//
+// Unchanged entries are recognized by identity, so entries must not
+// be modified in place; replace them with a modified copy instead.
+// Parsed entries must be kept in source order.
+func Rewrite(w io.Writer, fset *token.FileSet, f *ast.File) error {
+	if f.Trivia == nil {
+		return errors.New("printer: file not parsed in lossless mode")
+	}
+	r := &rewriter{
+		fset: fset,
+		f:    f,
+		kept: make(map[ast.Entry]bool),
+	}
+	for e := range f.Trivia {
+		r.orig = append(r.orig, e)
+	}
+	sort.Slice(r.orig, func(i, j int) bool {
+		return f.Trivia[r.orig[i]].From < f.Trivia[r.orig[j]].From
+	})
+	for _, e := range f.Entries {
+		if _, ok := f.Trivia[e]; ok {
+			r.kept[e] = true
+		}
+	}
+	for _, e := range f.Entries {
+		if err := r.entry(e); err != nil {
+			return err
+		}
+	}
+	r.copyTo(len(f.Src))
+	_, err := w.Write(r.buf.Bytes())
+	return err
+}
+
+type rewriter struct {
+	fset *token.FileSet
+	f    *ast.File
+	// Parsed entries in source order.
+	orig []ast.Entry
+	// Parsed entries that are still in the file.
+	kept map[ast.Entry]bool
+	// Index of the next parsed entry in orig not yet handled.
+	next int
+	// Source offset copied up to.
+	pos int
+	// Whether the last printed entry was a multiple line entry.
+	lastMulti bool
+	buf       bytes.Buffer
+}
+
+func (r *rewriter) entry(e ast.Entry) error {
+	if t, ok := r.f.Trivia[e]; ok {
+		r.copyTo(r.offset(t.From))
+		if r.next < len(r.orig) && r.orig[r.next] == e {
+			r.next++
+		}
+		r.buf.Write(r.f.Src[r.offset(t.From):r.offset(t.To)])
+		r.pos = r.offset(t.To)
+		r.lastMulti = isMultiLine(e)
+		return nil
+	}
+	if r.next < len(r.orig) && !r.kept[r.orig[r.next]] {
+		return r.replace(e, r.orig[r.next])
+	}
+	return r.insert(e)
+}
+
+// replace prints a new entry in place of a removed parsed entry.
+func (r *rewriter) replace(e, old ast.Entry) error {
+	t := r.f.Trivia[old]
+	r.next++
+	if src, ok := r.splice(e, old); ok {
+		r.copyTo(r.offset(t.From))
+		r.buf.Write(src)
+		r.pos = r.offset(t.To)
+		r.lastMulti = isMultiLine(e)
+		return nil
+	}
+	start := r.lineStart(old.Pos())
+	r.copyTo(r.offset(start))
+	var comments []*ast.Comment
+	for _, g := range r.f.Comments {
+		for _, c := range g.List {
+			if c.Pos() >= start && c.Pos() < t.To {
+				comments = append(comments, c)
+			}
+		}
+	}
+	nf := &ast.File{Entries: []ast.Entry{e}}
+	if len(comments) > 0 {
+		nf.Comments = []*ast.CommentGroup{{List: comments}}
+	}
+	if err := Fprint(&r.buf, r.fset, nf); err != nil {
+		return err
+	}
+	r.pos = r.offset(t.To)
+	r.lastMulti = isMultiLine(e)
+	return nil
+}
+
+// splice returns the source of a removed parsed entry, including its
+// leading comments, with the values of the tokens that differ in the
+// new entry replaced.  It returns false if the new entry is not a
+// modified copy of the removed entry with the same tokens.
+func (r *rewriter) splice(e, old ast.Entry) ([]byte, bool) {
+	if e.Pos() != old.Pos() {
+		return nil, false
+	}
+	oldCanon, ok := printTokens(old)
+	if !ok {
+		return nil, false
+	}
+	newCanon, ok := printTokens(e)
+	if !ok || len(newCanon) != len(oldCanon) {
+		return nil, false
+	}
+	t := r.f.Trivia[old]
+	from, start, end := r.offset(t.From), r.offset(old.Pos()), r.offset(old.End())
+	src := r.f.Src[start:end]
+	toks := scanTokens(src)
+	if len(toks) != len(oldCanon) {
+		return nil, false
+	}
+	for i := range toks {
+		if toks[i].tok != oldCanon[i].tok || newCanon[i].tok != oldCanon[i].tok {
+			return nil, false
+		}
+	}
+	var b bytes.Buffer
+	b.Write(r.f.Src[from:start])
+	last := 0
+	for i, tk := range toks {
+		if newCanon[i].lit == oldCanon[i].lit {
+			continue
+		}
+		b.Write(src[last:tk.off])
+		b.WriteString(newCanon[i].lit)
+		last = tk.off + len(tk.lit)
+	}
+	b.Write(r.f.Src[start+last : r.offset(t.To)])
+	return b.Bytes(), true
+}
+
+// A scannedToken is a token scanned from source.
+type scannedToken struct {
+	off int
+	tok token.Token
+	lit string
+}
+
+// scanTokens returns the tokens in the source, without newlines and
+// comments.
+func scanTokens(src []byte) []scannedToken {
+	f := token.NewFileSet().AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(f, src, nil, 0)
+	var toks []scannedToken
+	for {
+		pos, tok, lit := s.Scan()
+		switch tok {
+		case token.EOF:
+			return toks
+		case token.NEWLINE, token.COMMENT:
+			continue
+		}
+		toks = append(toks, scannedToken{off: f.Offset(pos), tok: tok, lit: lit})
+	}
+}
+
+// printTokens returns the tokens of an entry printed in canonical
+// form.  It returns false if the entry cannot be printed.
+func printTokens(e ast.Entry) ([]scannedToken, bool) {
+	var b bytes.Buffer
+	if err := Fprint(&b, nil, e); err != nil {
+		return nil, false
+	}
+	return scanTokens(b.Bytes()), true
+}
+
+// insert prints a new entry after the last printed entry.
+func (r *rewriter) insert(e ast.Entry) error {
+	b := r.buf.Bytes()
+	if n := len(b); n > 0 && b[n-1] != '\n' {
+		r.buf.WriteByte('\n')
+	}
+	multi := isMultiLine(e)
+	if r.buf.Len() > 0 && (multi || r.lastMulti) && !bytes.HasSuffix(r.buf.Bytes(), []byte("\n\n")) {
+		r.buf.WriteByte('\n')
+	}
+	if err := Fprint(&r.buf, nil, e); err != nil {
+		return err
+	}
+	r.lastMulti = multi
+	return nil
+}
+
+// copyTo copies the source up to the offset, omitting removed
+// entries.
+func (r *rewriter) copyTo(end int) {
+	src := r.f.Src
+	for r.next < len(r.orig) {
+		o := r.orig[r.next]
+		t := r.f.Trivia[o]
+		if r.offset(t.From) >= end {
+			break
+		}
+		r.next++
+		if r.kept[o] {
+			continue
+		}
+		r.buf.Write(src[r.pos:r.offset(t.From)])
+		r.pos = r.offset(t.To)
+		// Collapse the blank lines around the removed entry.
+		b := r.buf.Bytes()
+		if (len(b) == 0 || bytes.HasSuffix(b, []byte("\n\n"))) && r.pos < len(src) && src[r.pos] == '\n' {
+			r.pos++
+		}
+	}
+	if end > r.pos {
+		r.buf.Write(src[r.pos:end])
+		r.pos = end
+	}
+}
+
+func (r *rewriter) offset(pos token.Pos) int {
+	return r.fset.Position(pos).Offset
+}
+
+// lineStart returns the start of the line containing pos.
+func (r *rewriter) lineStart(pos token.Pos) token.Pos {
+	f := r.fset.File(pos)
+	return f.LineStart(f.Line(pos))
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/kpr/ast"
+	"go.felesatra.moe/keeper/kpr/parser"
+	"go.felesatra.moe/keeper/kpr/token"
+)
+
+const rewriteInput = `# file comment
+unit USD   100
+
+# groceries
+tx 2001-02-03   "Buy food" # weekly
+Expenses:Food    1.2 USD # lunch
+Assets:Cash
+end
+
+balance 2001-02-04 Assets:Cash  -1.20 USD
+bad entry
+`
+
+func parseLossless(t *testing.T, src string) (*token.FileSet, *ast.File) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, _ := parser.ParseBytes(fset, "", []byte(src), parser.Lossless)
+	return fset, f
+}
+
+func rewrite(t *testing.T, fset *token.FileSet, f *ast.File) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := Rewrite(&b, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRewrite_unchanged(t *testing.T) {
+	t.Parallel()
+	fset, f := parseLossless(t, rewriteInput)
+	if diff := cmp.Diff(rewriteInput, rewrite(t, fset, f)); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewrite_replace(t *testing.T) {
+	t.Parallel()
+	fset, f := parseLossless(t, rewriteInput)
+	old := f.Entries[1].(*ast.Transaction)
+	tx := *old
+	tx.Splits = append([]ast.LineNode(nil), old.Splits...)
+	s := *tx.Splits[0].(*ast.SplitLine)
+	acct := *s.Account
+	acct.Value = "Expenses:Groceries"
+	s.Account = &acct
+	tx.Splits[0] = &s
+	f.Entries[1] = &tx
+	const want = `# file comment
+unit USD   100
+
+# groceries
+tx 2001-02-03   "Buy food" # weekly
+Expenses:Groceries    1.2 USD # lunch
+Assets:Cash
+end
+
+balance 2001-02-04 Assets:Cash  -1.20 USD
+bad entry
+`
+	if diff := cmp.Diff(want, rewrite(t, fset, f)); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewrite_replace_different_tokens(t *testing.T) {
+	t.Parallel()
+	fset, f := parseLossless(t, rewriteInput)
+	old := f.Entries[1].(*ast.Transaction)
+	tx := *old
+	tx.Splits = []ast.LineNode{old.Splits[1], old.Splits[0]}
+	f.Entries[1] = &tx
+	const want = `# file comment
+unit USD   100
+
+# groceries
+tx 2001-02-03 "Buy food" # weekly
+# lunch
+Assets:Cash
+Expenses:Food 1.2 USD
+end
+
+balance 2001-02-04 Assets:Cash  -1.20 USD
+bad entry
+`
+	if diff := cmp.Diff(want, rewrite(t, fset, f)); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewrite_remove(t *testing.T) {
+	t.Parallel()
+	fset, f := parseLossless(t, rewriteInput)
+	f.Entries = append(f.Entries[:1], f.Entries[2:]...)
+	const want = `# file comment
+unit USD   100
+
+balance 2001-02-04 Assets:Cash  -1.20 USD
+bad entry
+`
+	if diff := cmp.Diff(want, rewrite(t, fset, f)); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewrite_insert(t *testing.T) {
+	t.Parallel()
+	fset, f := parseLossless(t, rewriteInput)
+	f.Entries = append(f.Entries, &ast.DisableAccount{
+		Date:    &ast.BasicValue{Kind: token.DATE, Value: "2001-02-05"},
+		Account: &ast.BasicValue{Kind: token.ACCTNAME, Value: "Assets:Cash"},
+	})
+	want := rewriteInput + "disable 2001-02-05 Assets:Cash\n"
+	if diff := cmp.Diff(want, rewrite(t, fset, f)); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewrite_not_lossless(t *testing.T) {
+	t.Parallel()
+	fset := token.NewFileSet()
+	f, _ := parser.ParseBytes(fset, "", []byte(rewriteInput), parser.ParseComments)
+	var b bytes.Buffer
+	if err := Rewrite(&b, fset, f); err == nil {
+		t.Errorf("Expected error")
+	}
+}
@@ -49,6 +49,7 @@ type Scanner struct {
 	state      stateFn
 	results    chan result
 	scannedEOF bool
+	lineTok    token.Token // first token of the line, NEWLINE at line start
 
 	// Public state - ok to modify
 	ErrorCount int
@@ -95,6 +96,7 @@ func (s *Scanner) Init(file *token.File, src []byte, err ErrorHandler, mode Mode
 	s.pending = nil
 	s.state = lexStart
 	s.results = make(chan result, 2)
+	s.lineTok = token.NEWLINE
 	s.ErrorCount = 0
 }
 
@@ -196,6 +198,12 @@ func (s *Scanner) emit(tok token.Token) {
 		Tok: tok,
 		Lit: string(s.pending),
 	}
+	switch {
+	case tok == token.NEWLINE:
+		s.lineTok = token.NEWLINE
+	case s.lineTok == token.NEWLINE:
+		s.lineTok = tok
+	}
 	s.ignore()
 }
 
@@ -245,12 +253,27 @@ func lexStart(s *Scanner) stateFn {
 		s.emitEOF()
 		return lexStart
 	case r == '#':
+		if s.tagLine() && startsTags(s.src[s.offset:]) {
+			return lexTag
+		}
 		return lexComment
 	case r == '\n':
 		s.emit(token.NEWLINE)
 		return lexStart
 	case r == '"':
 		return lexString
+	case r == '{':
+		s.emit(token.LBRACE)
+		return lexStart
+	case r == '}':
+		s.emit(token.RBRACE)
+		return lexStart
+	case r == '[':
+		s.emit(token.LBRACK)
+		return lexStart
+	case r == ']':
+		s.emit(token.RBRACK)
+		return lexStart
 	case unicode.IsUpper(r):
 		return lexUpper
 	case unicode.IsLower(r):
@@ -259,6 +282,8 @@ func lexStart(s *Scanner) stateFn {
 		return lexDigit
 	case r == '-':
 		return lexDecimal
+	case r == '~':
+		return lexTilde
 	case unicode.IsSpace(r):
 		s.ignore()
 		return lexStart
@@ -287,6 +312,68 @@ func lexComment(s *Scanner) stateFn {
 	}
 }
 
+// A tag is a '#' immediately followed by tag characters.  To not
+// conflict with comments, a '#' only starts a tag on lines that can
+// have tags, and only if the rest of the line contains only more
+// tags, optionally followed by a comment.
+func lexTag(s *Scanner) stateFn {
+	s.acceptRun(tagChars)
+	s.emit(token.TAG)
+	return lexExprEnd
+}
+
+// tagLine reports whether the current line can have tags.
+func (s *Scanner) tagLine() bool {
+	switch s.lineTok {
+	case token.TX, token.RECURRING, token.ACCTNAME, token.MATCH:
+		return true
+	default:
+		return false
+	}
+}
+
+const tagChars = letters + digits + "-_:/."
+
+// startsTags reports whether the source following a '#' is a tag
+// followed only by tags or a comment until the end of the line.
+func startsTags(src []byte) bool {
+	for {
+		n := tagLen(src)
+		if n == 0 {
+			return false
+		}
+		src = src[n:]
+		i := 0
+		for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\r') {
+			i++
+		}
+		switch {
+		case i == len(src), src[i] == '\n':
+			return true
+		case i == 0, src[i] != '#':
+			return false
+		}
+		src = src[i+1:]
+		if tagLen(src) == 0 {
+			// The rest of the line is a comment.
+			return true
+		}
+	}
+}
+
+// tagLen returns the length of the tag at the start of src, not
+// including the '#'.
+func tagLen(src []byte) int {
+	if len(src) == 0 || !strings.ContainsRune(letters+digits, rune(src[0])) {
+		return 0
+	}
+	n := 0
+	for n < len(src) && strings.IndexByte(tagChars, src[n]) >= 0 {
+		n++
+	}
+	return n
+}
+
 // Expression-like tokens cannot be followed by expression-like characters.
 func lexExprEnd(s *Scanner) stateFn {
 	switch next := s.peek(); {
@@ -362,6 +449,33 @@ func lexLower(s *Scanner) stateFn {
 	case "meta":
 		s.emit(token.META)
 		return lexExprEnd
+	case "include":
+		s.emit(token.INCLUDE)
+		return lexExprEnd
+	case "price":
+		s.emit(token.PRICE)
+		return lexExprEnd
+	case "budget":
+		s.emit(token.BUDGET)
+		return lexExprEnd
+	case "recurring":
+		s.emit(token.RECURRING)
+		return lexExprEnd
+	case "rule":
+		s.emit(token.RULE)
+		return lexExprEnd
+	case "match":
+		s.emit(token.MATCH)
+		return lexExprEnd
+	case "pad":
+		s.emit(token.PAD)
+		return lexExprEnd
+	case "partial":
+		s.emit(token.PARTIAL)
+		return lexExprEnd
+	case "weekly", "monthly", "quarterly", "yearly":
+		s.emit(token.INTERVAL)
+		return lexExprEnd
 	}
 	s.errorf(s.start, "invalid token")
 	s.emit(token.ILLEGAL)
@@ -397,17 +511,38 @@ func lexDigit(s *Scanner) stateFn {
 		return lexDate
 	default:
 		s.unread()
-		s.emit(token.DECIMAL)
-		return lexExprEnd
+		return lexDecimalEnd
 	}
 }
 
 func lexDecimal(s *Scanner) stateFn {
 	s.acceptRun(digits + ".,")
+	return lexDecimalEnd
+}
+
+// lexDecimalEnd emits a decimal, which is a percentage if followed by
+// a percent sign.
+func lexDecimalEnd(s *Scanner) stateFn {
+	if s.accept("%") {
+		s.emit(token.PERCENT)
+		return lexExprEnd
+	}
 	s.emit(token.DECIMAL)
 	return lexExprEnd
 }
 
+// lexTilde lexes a tolerance, which is a tilde followed by a decimal.
+func lexTilde(s *Scanner) stateFn {
+	s.acceptRun(digits + ".,")
+	if len(s.pending) == 1 {
+		s.errorf(s.start, "tilde not followed by decimal")
+		s.emit(token.ILLEGAL)
+		return lexExprEnd
+	}
+	s.emit(token.TILDE)
+	return lexExprEnd
+}
+
 func lexDate(s *Scanner) stateFn {
 	s.acceptRun(digits + "-")
 	s.emit(token.DATE)
@@ -76,6 +76,50 @@ end
 				{179, token.NEWLINE, "\n"},
 			},
 		},
+		{
+			desc: "lot annotation",
+			text: `Some:account 1 VTI {2.5 USD} [2001-02-03]
+`,
+			want: []result{
+				{1, token.ACCTNAME, `Some:account`},
+				{14, token.DECIMAL, `1`},
+				{16, token.USYMBOL, `VTI`},
+				{20, token.LBRACE, `{`},
+				{21, token.DECIMAL, `2.5`},
+				{25, token.USYMBOL, `USD`},
+				{28, token.RBRACE, `}`},
+				{30, token.LBRACK, `[`},
+				{31, token.DATE, `2001-02-03`},
+				{41, token.RBRACK, `]`},
+				{42, token.NEWLINE, "\n"},
+			},
+		},
+		{
+			desc: "tags",
+			text: `tx 2001-02-03 "x" #foo #bar/baz # comment
+`,
+			want: []result{
+				{1, token.TX, `tx`},
+				{4, token.DATE, `2001-02-03`},
+				{15, token.STRING, `"x"`},
+				{19, token.TAG, `#foo`},
+				{24, token.TAG, `#bar/baz`},
+				{42, token.NEWLINE, "\n"},
+			},
+		},
+		{
+			desc: "tag-like comments",
+			text: `#header
+unit USD 100 #dollars
+`,
+			want: []result{
+				{8, token.NEWLINE, "\n"},
+				{9, token.UNIT, `unit`},
+				{14, token.USYMBOL, `USD`},
+				{18, token.DECIMAL, `100`},
+				{30, token.NEWLINE, "\n"},
+			},
+		},
 		{
 			desc: "comment ignored",
 			text: `tx 2001-02-03 "Some description"  # blah
@@ -219,6 +263,55 @@ end
 				{38, token.NEWLINE, "\n"},
 			},
 		},
+		{
+			desc: "budget",
+			text: `budget 2020-01 Some:account 1 USD weekly
+`,
+			want: []result{
+				{1, token.BUDGET, "budget"},
+				{8, token.DATE, "2020-01"},
+				{16, token.ACCTNAME, "Some:account"},
+				{29, token.DECIMAL, "1"},
+				{31, token.USYMBOL, "USD"},
+				{35, token.INTERVAL, "weekly"},
+				{41, token.NEWLINE, "\n"},
+			},
+		},
+		{
+			desc: "percent",
+			text: `Some:account 1,200.5%
+`,
+			want: []result{
+				{1, token.ACCTNAME, "Some:account"},
+				{14, token.PERCENT, "1,200.5%"},
+				{22, token.NEWLINE, "\n"},
+			},
+		},
+		{
+			desc: "tilde",
+			text: `1.00 ~0.01 USD
+`,
+			want: []result{
+				{1, token.DECIMAL, "1.00"},
+				{6, token.TILDE, "~0.01"},
+				{12, token.USYMBOL, "USD"},
+				{15, token.NEWLINE, "\n"},
+			},
+		},
+		{
+			desc: "partial",
+			text: `balance 2001-02-03 Some:account partial 5 USD
+`,
+			want: []result{
+				{1, token.BALANCE, "balance"},
+				{9, token.DATE, "2001-02-03"},
+				{20, token.ACCTNAME, "Some:account"},
+				{33, token.PARTIAL, "partial"},
+				{41, token.DECIMAL, "5"},
+				{43, token.USYMBOL, "USD"},
+				{46, token.NEWLINE, "\n"},
+			},
+		},
 		{
 			desc: "empty",
 			text: ``,
@@ -29,6 +29,10 @@ const (
 
 	// Syntactic
 	NEWLINE
+	LBRACE // {
+	RBRACE // }
+	LBRACK // [
+	RBRACK // ]
 
 	// Values
 	STRING   // "foo"
@@ -36,6 +40,10 @@ const (
 	ACCTNAME // Assets:Cash
 	DECIMAL  // -1,234.56
 	DATE     // 2000-01-31
+	TAG      // #foo
+	INTERVAL // monthly
+	PERCENT  // 12.5%
+	TILDE    // ~0.01
 
 	// Keywords
 	TX
@@ -46,4 +54,12 @@ const (
 	ACCOUNT
 	TREEBAL
 	META
+	INCLUDE
+	PRICE
+	BUDGET
+	RECURRING
+	RULE
+	MATCH
+	PAD
+	PARTIAL
 )
@@ -12,24 +12,40 @@ func _() {
 	_ = x[EOF-1]
 	_ = x[COMMENT-2]
 	_ = x[NEWLINE-3]
-	_ = x[STRING-4]
-	_ = x[USYMBOL-5]
-	_ = x[ACCTNAME-6]
-	_ = x[DECIMAL-7]
-	_ = x[DATE-8]
-	_ = x[TX-9]
-	_ = x[END-10]
-	_ = x[BALANCE-11]
-	_ = x[UNIT-12]
-	_ = x[DISABLE-13]
-	_ = x[ACCOUNT-14]
-	_ = x[TREEBAL-15]
-	_ = x[META-16]
+	_ = x[LBRACE-4]
+	_ = x[RBRACE-5]
+	_ = x[LBRACK-6]
+	_ = x[RBRACK-7]
+	_ = x[STRING-8]
+	_ = x[USYMBOL-9]
+	_ = x[ACCTNAME-10]
+	_ = x[DECIMAL-11]
+	_ = x[DATE-12]
+	_ = x[TAG-13]
+	_ = x[INTERVAL-14]
+	_ = x[PERCENT-15]
+	_ = x[TILDE-16]
+	_ = x[TX-17]
+	_ = x[END-18]
+	_ = x[BALANCE-19]
+	_ = x[UNIT-20]
+	_ = x[DISABLE-21]
+	_ = x[ACCOUNT-22]
+	_ = x[TREEBAL-23]
+	_ = x[META-24]
+	_ = x[INCLUDE-25]
+	_ = x[PRICE-26]
+	_ = x[BUDGET-27]
+	_ = x[RECURRING-28]
+	_ = x[RULE-29]
+	_ = x[MATCH-30]
+	_ = x[PAD-31]
+	_ = x[PARTIAL-32]
 }
 
-const _Token_name = "ILLEGALEOFCOMMENTNEWLINESTRINGUSYMBOLACCTNAMEDECIMALDATETXENDBALANCEUNITDISABLEACCOUNTTREEBALMETA"
+const _Token_name = "ILLEGALEOFCOMMENTNEWLINELBRACERBRACELBRACKRBRACKSTRINGUSYMBOLACCTNAMEDECIMALDATETAGINTERVALPERCENTTILDETXENDBALANCEUNITDISABLEACCOUNTTREEBALMETAINCLUDEPRICEBUDGETRECURRINGRULEMATCHPADPARTIAL"
 
-var _Token_index = [...]uint8{0, 7, 10, 17, 24, 30, 37, 45, 52, 56, 58, 61, 68, 72, 79, 86, 93, 97}
+var _Token_index = [...]uint8{0, 7, 10, 17, 24, 30, 36, 42, 48, 54, 61, 69, 76, 80, 83, 91, 98, 103, 105, 108, 115, 119, 126, 133, 140, 144, 151, 156, 162, 171, 175, 180, 183, 190}
 
 func (i Token) String() string {
 	if i < 0 || i >= Token(len(_Token_index)-1) {
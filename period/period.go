@@ -0,0 +1,279 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package period implements reporting periods.
+//
+// Periods are written as:
+//
+//	2024-03-15              a day
+//	2024-W05                an ISO week
+//	2024-03                 a month
+//	2024-Q1                 a quarter
+//	2024                    a calendar year
+//	FY2024                  a fiscal year
+//	2024-01-15..2024-02-14  a custom range
+//
+// The ends of a custom range may be any period, so 2024-01..2024-03
+// is the first three months of 2024.
+//
+// Fiscal years start on the first day of a configurable month and are
+// named by the calendar year they start in.
+package period
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// A Kind is a kind of period.
+type Kind int
+
+const (
+	Day Kind = iota
+	Week
+	Month
+	Quarter
+	Year
+	FiscalYear
+	Custom
+)
+
+// A Period is a range of dates.
+type Period struct {
+	Kind Kind
+	// Start and End are the first and last days of the period,
+	// inclusive.
+	Start, End civil.Date
+}
+
+var (
+	weekPattern    = regexp.MustCompile(`^([0-9]{4})-W([0-9]{2})$`)
+	quarterPattern = regexp.MustCompile(`^([0-9]{4})-Q([1-4])$`)
+	monthPattern   = regexp.MustCompile(`^([0-9]{4})-([0-9]{2})$`)
+	yearPattern    = regexp.MustCompile(`^([0-9]{4})$`)
+	fiscalPattern  = regexp.MustCompile(`^FY([0-9]{4})$`)
+)
+
+// Parse parses a period.  fiscalStart is the month that fiscal years
+// start in.
+func Parse(s string, fiscalStart time.Month) (Period, error) {
+	p, err := parse(s, fiscalStart)
+	if err != nil {
+		return Period{}, fmt.Errorf("parse period: %s", err)
+	}
+	return p, nil
+}
+
+func parse(s string, fiscalStart time.Month) (Period, error) {
+	if from, to, ok := strings.Cut(s, ".."); ok {
+		p1, err := parseSingle(from, fiscalStart)
+		if err != nil {
+			return Period{}, err
+		}
+		p2, err := parseSingle(to, fiscalStart)
+		if err != nil {
+			return Period{}, err
+		}
+		if p2.End.Before(p1.Start) {
+			return Period{}, fmt.Errorf("%q ends before it starts", s)
+		}
+		return Period{Kind: Custom, Start: p1.Start, End: p2.End}, nil
+	}
+	return parseSingle(s, fiscalStart)
+}
+
+func parseSingle(s string, fiscalStart time.Month) (Period, error) {
+	if m := weekPattern.FindStringSubmatch(s); m != nil {
+		y, w := atoi(m[1]), atoi(m[2])
+		p := Containing(Week, isoWeekStart(y).AddDays(7*(w-1)), fiscalStart)
+		if w < 1 || isoYear(p.Start) != y {
+			return Period{}, fmt.Errorf("invalid week %q", s)
+		}
+		return p, nil
+	}
+	if m := quarterPattern.FindStringSubmatch(s); m != nil {
+		d := civil.Date{Year: atoi(m[1]), Month: time.Month(3*atoi(m[2]) - 2), Day: 1}
+		return Containing(Quarter, d, fiscalStart), nil
+	}
+	if m := monthPattern.FindStringSubmatch(s); m != nil {
+		d := civil.Date{Year: atoi(m[1]), Month: time.Month(atoi(m[2])), Day: 1}
+		if !d.IsValid() {
+			return Period{}, fmt.Errorf("invalid month %q", s)
+		}
+		return Containing(Month, d, fiscalStart), nil
+	}
+	if m := yearPattern.FindStringSubmatch(s); m != nil {
+		d := civil.Date{Year: atoi(m[1]), Month: time.January, Day: 1}
+		return Containing(Year, d, fiscalStart), nil
+	}
+	if m := fiscalPattern.FindStringSubmatch(s); m != nil {
+		d := civil.Date{Year: atoi(m[1]), Month: fiscalStart, Day: 1}
+		return Containing(FiscalYear, d, fiscalStart), nil
+	}
+	d, err := civil.ParseDate(s)
+	if err != nil {
+		return Period{}, fmt.Errorf("invalid period %q", s)
+	}
+	return Containing(Day, d, fiscalStart), nil
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Containing returns the period of the given kind that contains the
+// date.  fiscalStart is the month that fiscal years start in.
+// Custom periods are not supported.
+func Containing(k Kind, d civil.Date, fiscalStart time.Month) Period {
+	p := Period{Kind: k}
+	switch k {
+	case Day:
+		p.Start = d
+		p.End = d
+	case Week:
+		p.Start = d.AddDays(-((int(d.In(time.UTC).Weekday()) + 6) % 7))
+		p.End = p.Start.AddDays(6)
+	case Month:
+		p.Start = civil.Date{Year: d.Year, Month: d.Month, Day: 1}
+		p.End = addMonths(p.Start, 1).AddDays(-1)
+	case Quarter:
+		p.Start = civil.Date{Year: d.Year, Month: d.Month - (d.Month-1)%3, Day: 1}
+		p.End = addMonths(p.Start, 3).AddDays(-1)
+	case Year:
+		p.Start = civil.Date{Year: d.Year, Month: time.January, Day: 1}
+		p.End = addMonths(p.Start, 12).AddDays(-1)
+	case FiscalYear:
+		if fiscalStart < time.January || fiscalStart > time.December {
+			fiscalStart = time.January
+		}
+		p.Start = civil.Date{Year: d.Year, Month: fiscalStart, Day: 1}
+		if d.Month < fiscalStart {
+			p.Start.Year--
+		}
+		p.End = addMonths(p.Start, 12).AddDays(-1)
+	default:
+		panic(fmt.Sprintf("unsupported period kind %d", k))
+	}
+	return p
+}
+
+// Next returns the period of the same kind and length after the
+// period.
+func (p Period) Next() Period {
+	return p.shift(1)
+}
+
+// Prev returns the period of the same kind and length before the
+// period.
+func (p Period) Prev() Period {
+	return p.shift(-1)
+}
+
+func (p Period) shift(n int) Period {
+	p2 := Period{Kind: p.Kind}
+	if m := p.months(); m > 0 {
+		p2.Start = addMonths(p.Start, m*n)
+		p2.End = addMonths(p2.Start, m).AddDays(-1)
+		return p2
+	}
+	days := (p.End.DaysSince(p.Start) + 1) * n
+	p2.Start = p.Start.AddDays(days)
+	p2.End = p.End.AddDays(days)
+	return p2
+}
+
+// months returns the length of the period in months, or 0 if the
+// period is not made of whole months.
+func (p Period) months() int {
+	switch p.Kind {
+	case Day, Week:
+		return 0
+	case Month:
+		return 1
+	case Quarter:
+		return 3
+	case Year, FiscalYear:
+		return 12
+	}
+	next := p.End.AddDays(1)
+	if p.Start.Day != 1 || next.Day != 1 {
+		return 0
+	}
+	return (next.Year-p.Start.Year)*12 + int(next.Month-p.Start.Month)
+}
+
+// Contains returns true if the date is in the period.
+func (p Period) Contains(d civil.Date) bool {
+	return !d.Before(p.Start) && !d.After(p.End)
+}
+
+// String returns the period in the format accepted by Parse.
+func (p Period) String() string {
+	switch p.Kind {
+	case Day:
+		return p.Start.String()
+	case Week:
+		y, w := p.Start.In(time.UTC).ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", y, w)
+	case Month:
+		return fmt.Sprintf("%04d-%02d", p.Start.Year, p.Start.Month)
+	case Quarter:
+		return fmt.Sprintf("%04d-Q%d", p.Start.Year, (p.Start.Month+2)/3)
+	case Year:
+		return fmt.Sprintf("%04d", p.Start.Year)
+	case FiscalYear:
+		return fmt.Sprintf("FY%04d", p.Start.Year)
+	default:
+		return p.Start.String() + ".." + p.End.String()
+	}
+}
+
+// addMonths adds months to a date on the first day of a month.
+func addMonths(d civil.Date, n int) civil.Date {
+	return civil.DateOf(time.Date(d.Year, d.Month+time.Month(n), 1, 0, 0, 0, 0, time.UTC))
+}
+
+// isoWeekStart returns the Monday of the first ISO week of the year.
+func isoWeekStart(y int) civil.Date {
+	jan4 := civil.Date{Year: y, Month: time.January, Day: 4}
+	return Containing(Week, jan4, time.January).Start
+}
+
+func isoYear(d civil.Date) int {
+	y, _ := d.In(time.UTC).ISOWeek()
+	return y
+}
+
+// Series returns n consecutive periods of the same kind and length,
+// ending with the given period.
+func Series(last Period, n int) []Period {
+	if n <= 0 {
+		return nil
+	}
+	ps := make([]Period, n)
+	ps[n-1] = last
+	for i := n - 2; i >= 0; i-- {
+		ps[i] = ps[i+1].Prev()
+	}
+	return ps
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package period
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		s          string
+		start, end civil.Date
+		str        string
+	}{
+		{"2024-03-15", civil.Date{2024, 3, 15}, civil.Date{2024, 3, 15}, "2024-03-15"},
+		{"2024-W01", civil.Date{2024, 1, 1}, civil.Date{2024, 1, 7}, "2024-W01"},
+		{"2021-W01", civil.Date{2021, 1, 4}, civil.Date{2021, 1, 10}, "2021-W01"},
+		{"2020-W53", civil.Date{2020, 12, 28}, civil.Date{2021, 1, 3}, "2020-W53"},
+		{"2024-02", civil.Date{2024, 2, 1}, civil.Date{2024, 2, 29}, "2024-02"},
+		{"2024-Q4", civil.Date{2024, 10, 1}, civil.Date{2024, 12, 31}, "2024-Q4"},
+		{"2024", civil.Date{2024, 1, 1}, civil.Date{2024, 12, 31}, "2024"},
+		{"FY2024", civil.Date{2024, 4, 1}, civil.Date{2025, 3, 31}, "FY2024"},
+		{"2024-01..2024-03", civil.Date{2024, 1, 1}, civil.Date{2024, 3, 31}, "2024-01-01..2024-03-31"},
+		{"2024-01-15..2024-02-14", civil.Date{2024, 1, 15}, civil.Date{2024, 2, 14}, "2024-01-15..2024-02-14"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.s, func(t *testing.T) {
+			t.Parallel()
+			p, err := Parse(c.s, time.April)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Start != c.start || p.End != c.end {
+				t.Errorf("Parse(%q) = %v..%v; want %v..%v", c.s, p.Start, p.End, c.start, c.end)
+			}
+			if got := p.String(); got != c.str {
+				t.Errorf("String() = %q; want %q", got, c.str)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"", "2024-13", "2023-W53", "2024-W00", "2024-Q5", "FY", "2024-03..2024-01", "March"} {
+		if p, err := Parse(s, time.January); err == nil {
+			t.Errorf("Parse(%q) = %v; want error", s, p)
+		}
+	}
+}
+
+func TestPeriod_Prev(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		s    string
+		want string
+	}{
+		{"2024-03-01", "2024-02-29"},
+		{"2024-W01", "2023-W52"},
+		{"2024-03", "2024-02"},
+		{"2024-Q1", "2023-Q4"},
+		{"2024", "2023"},
+		{"FY2024", "FY2023"},
+		{"2024-04..2024-06", "2024-01-01..2024-03-31"},
+		{"2024-01-10..2024-01-19", "2023-12-31..2024-01-09"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.s, time.October)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Prev().String(); got != c.want {
+			t.Errorf("Parse(%q).Prev() = %q; want %q", c.s, got, c.want)
+		}
+		if got := p.Prev().Next(); got != p {
+			t.Errorf("Parse(%q).Prev().Next() = %v; want %v", c.s, got, p)
+		}
+	}
+}
+
+func TestContaining(t *testing.T) {
+	t.Parallel()
+	d := civil.Date{2024, 2, 15}
+	cases := []struct {
+		k    Kind
+		want string
+	}{
+		{Day, "2024-02-15"},
+		{Week, "2024-W07"},
+		{Month, "2024-02"},
+		{Quarter, "2024-Q1"},
+		{Year, "2024"},
+		{FiscalYear, "FY2023"},
+	}
+	for _, c := range cases {
+		if got := Containing(c.k, d, time.April).String(); got != c.want {
+			t.Errorf("Containing(%v, %v) = %q; want %q", c.k, d, got, c.want)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	t.Parallel()
+	p, err := Parse("2024-Q1", time.January)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range Series(p, 3) {
+		got = append(got, p.String())
+	}
+	want := []string{"2023-Q3", "2023-Q4", "2024-Q1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Series() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,491 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// A row is one split in a transaction.
+type row struct {
+	tx    *journal.Transaction
+	split *journal.Split
+}
+
+// An env is the rows an expression is evaluated over.  Aggregate
+// functions are evaluated over all of the rows and other expressions
+// are evaluated on the first row.
+type env struct {
+	rows []row
+}
+
+func (e *env) row() (row, bool) {
+	if len(e.rows) == 0 {
+		return row{}, false
+	}
+	return e.rows[0], true
+}
+
+type expr interface {
+	eval(e *env) (Value, error)
+	String() string
+}
+
+type literal struct {
+	v   Value
+	src string
+}
+
+func (l *literal) eval(*env) (Value, error) { return l.v, nil }
+func (l *literal) String() string           { return l.src }
+
+type paren struct {
+	x expr
+}
+
+func (p *paren) eval(e *env) (Value, error) { return p.x.eval(e) }
+func (p *paren) String() string             { return "(" + p.x.String() + ")" }
+
+type column struct {
+	name string
+}
+
+func (c *column) eval(e *env) (Value, error) {
+	r, ok := e.row()
+	if !ok {
+		return Null{}, nil
+	}
+	return columns[c.name](r), nil
+}
+
+func (c *column) String() string { return c.name }
+
+// columns are the values available for each split.
+var columns = map[string]func(r row) Value{
+	"date": func(r row) Value {
+		return Date(r.tx.EntryDate)
+	},
+	"description": func(r row) Value {
+		return String(r.tx.Description)
+	},
+	"account": func(r row) Value {
+		return String(r.split.Account)
+	},
+	"amount": func(r row) Value {
+		return Amount{r.split.Amount}
+	},
+	"number": func(r row) Value {
+		return Number{Rat: amountRat(r.split.Amount)}
+	},
+	"unit": func(r row) Value {
+		return String(r.split.Amount.Unit.Symbol)
+	},
+	"tags": func(r row) Value {
+		var tags []string
+		tags = append(tags, r.tx.Tags...)
+		tags = append(tags, r.split.Tags...)
+		return String(strings.Join(tags, ","))
+	},
+	"position": func(r row) Value {
+		return String(r.tx.EntryPos.String())
+	},
+}
+
+type logical struct {
+	op   string // AND, OR
+	x, y expr
+}
+
+func (l *logical) eval(e *env) (Value, error) {
+	x, err := evalBool(l.x, e)
+	if err != nil {
+		return nil, err
+	}
+	if l.op == "AND" && !x || l.op == "OR" && x {
+		return Bool(x), nil
+	}
+	y, err := evalBool(l.y, e)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(y), nil
+}
+
+func (l *logical) String() string {
+	return l.x.String() + " " + l.op + " " + l.y.String()
+}
+
+type not struct {
+	x expr
+}
+
+func (n *not) eval(e *env) (Value, error) {
+	x, err := evalBool(n.x, e)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(!x), nil
+}
+
+func (n *not) String() string { return "NOT " + n.x.String() }
+
+// evalBool evaluates a boolean expression.  Null is treated as false.
+func evalBool(x expr, e *env) (bool, error) {
+	v, err := x.eval(e)
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case Bool:
+		return bool(v), nil
+	case Null:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s: expected bool, got %s", x, typeName(v))
+	}
+}
+
+type comparison struct {
+	op   string
+	x, y expr
+}
+
+func (c *comparison) eval(e *env) (Value, error) {
+	x, err := c.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	y, err := c.y.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	if isNull(x) || isNull(y) {
+		return Bool(false), nil
+	}
+	n, err := compareValues(x, y)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", c, err)
+	}
+	switch c.op {
+	case "=":
+		return Bool(n == 0), nil
+	case "!=":
+		return Bool(n != 0), nil
+	case "<":
+		return Bool(n < 0), nil
+	case "<=":
+		return Bool(n <= 0), nil
+	case ">":
+		return Bool(n > 0), nil
+	case ">=":
+		return Bool(n >= 0), nil
+	default:
+		panic(fmt.Sprintf("unknown operator %s", c.op))
+	}
+}
+
+func (c *comparison) String() string {
+	return c.x.String() + " " + c.op + " " + c.y.String()
+}
+
+type match struct {
+	x   expr
+	re  *regexp.Regexp
+	src string
+}
+
+func (m *match) eval(e *env) (Value, error) {
+	v, err := m.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case String:
+		return Bool(m.re.MatchString(string(v))), nil
+	case Null:
+		return Bool(false), nil
+	default:
+		return nil, fmt.Errorf("%s: cannot match %s", m, typeName(v))
+	}
+}
+
+func (m *match) String() string { return m.x.String() + " ~ " + m.src }
+
+type call struct {
+	name string
+	f    function
+	args []expr
+}
+
+func (c *call) eval(e *env) (Value, error) {
+	args := make([]Value, len(c.args))
+	for i, x := range c.args {
+		v, err := x.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	r, ok := e.row()
+	if !ok {
+		return Null{}, nil
+	}
+	v, err := c.f.call(r, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", c, err)
+	}
+	return v, nil
+}
+
+func (c *call) String() string {
+	s := make([]string, len(c.args))
+	for i, x := range c.args {
+		s[i] = x.String()
+	}
+	return c.name + "(" + strings.Join(s, ", ") + ")"
+}
+
+// A function is a scalar function.
+type function struct {
+	nargs int
+	call  func(r row, args []Value) (Value, error)
+}
+
+var functions = map[string]function{
+	// root returns the first n components of an account.
+	"root": {2, func(_ row, args []Value) (Value, error) {
+		a, ok1 := args[0].(String)
+		n, ok2 := args[1].(Number)
+		if !ok1 || !ok2 || !n.Rat.IsInt() {
+			return nil, fmt.Errorf("expected string and integer arguments")
+		}
+		parts := strings.Split(string(a), ":")
+		if k := int(n.Rat.Num().Int64()); k < len(parts) {
+			parts = parts[:max(k, 0)]
+		}
+		return String(strings.Join(parts, ":")), nil
+	}},
+	// parent returns the parent of an account.
+	"parent": {1, func(_ row, args []Value) (Value, error) {
+		a, ok := args[0].(String)
+		if !ok {
+			return nil, fmt.Errorf("expected string argument")
+		}
+		p := journal.Account(a).Parent()
+		if p == "" {
+			return Null{}, nil
+		}
+		return String(p), nil
+	}},
+	"year": {1, func(_ row, args []Value) (Value, error) {
+		d, ok := args[0].(Date)
+		if !ok {
+			return nil, fmt.Errorf("expected date argument")
+		}
+		return Number{Rat: big.NewRat(int64(d.Year), 1)}, nil
+	}},
+	// month returns the month of a date as YYYY-MM.
+	"month": {1, func(_ row, args []Value) (Value, error) {
+		d, ok := args[0].(Date)
+		if !ok {
+			return nil, fmt.Errorf("expected date argument")
+		}
+		return String(civil.Date(d).String()[:7]), nil
+	}},
+	// meta returns a split metadata value, falling back to the
+	// transaction metadata.
+	"meta": {1, func(r row, args []Value) (Value, error) {
+		k, ok := args[0].(String)
+		if !ok {
+			return nil, fmt.Errorf("expected string argument")
+		}
+		if v, ok := r.split.Metadata[string(k)]; ok {
+			return String(v), nil
+		}
+		if v, ok := r.tx.Metadata[string(k)]; ok {
+			return String(v), nil
+		}
+		return Null{}, nil
+	}},
+	// has_tag returns whether the split or transaction has a tag.
+	"has_tag": {1, func(r row, args []Value) (Value, error) {
+		t, ok := args[0].(String)
+		if !ok {
+			return nil, fmt.Errorf("expected string argument")
+		}
+		return Bool(r.split.HasTag(string(t)) || r.tx.HasTag(string(t))), nil
+	}},
+}
+
+type aggregate struct {
+	name string
+	arg  expr // nil for count(*)
+}
+
+// aggregates are the aggregate functions.
+var aggregates = map[string]func(vs []Value) (Value, error){
+	"count": func(vs []Value) (Value, error) {
+		n := 0
+		for _, v := range vs {
+			if !isNull(v) {
+				n++
+			}
+		}
+		return Number{Rat: big.NewRat(int64(n), 1)}, nil
+	},
+	"sum":   sum,
+	"min":   func(vs []Value) (Value, error) { return extreme(vs, -1) },
+	"max":   func(vs []Value) (Value, error) { return extreme(vs, 1) },
+	"first": func(vs []Value) (Value, error) { return firstNonNull(vs, 0, 1) },
+	"last":  func(vs []Value) (Value, error) { return firstNonNull(vs, len(vs)-1, -1) },
+}
+
+func (a *aggregate) eval(e *env) (Value, error) {
+	vs := make([]Value, len(e.rows))
+	for i, r := range e.rows {
+		if a.arg == nil {
+			vs[i] = Bool(true)
+			continue
+		}
+		v, err := a.arg.eval(&env{rows: []row{r}})
+		if err != nil {
+			return nil, err
+		}
+		vs[i] = v
+	}
+	v, err := aggregates[a.name](vs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", a, err)
+	}
+	return v, nil
+}
+
+func (a *aggregate) String() string {
+	if a.arg == nil {
+		return a.name + "(*)"
+	}
+	return a.name + "(" + a.arg.String() + ")"
+}
+
+// sum sums numbers, or amounts into a balance.
+func sum(vs []Value) (Value, error) {
+	var result Value = Null{}
+	for _, v := range vs {
+		switch v := v.(type) {
+		case Null:
+		case Number:
+			switch r := result.(type) {
+			case Null:
+				result = Number{Rat: new(big.Rat).Set(v.Rat)}
+			case Number:
+				r.Rat.Add(r.Rat, v.Rat)
+			default:
+				return nil, fmt.Errorf("cannot add %s and %s", typeName(r), typeName(v))
+			}
+		case Amount, Balance:
+			switch r := result.(type) {
+			case Null:
+				b := Balance{new(journal.Balance)}
+				addBalance(b.Balance, v)
+				result = b
+			case Balance:
+				addBalance(r.Balance, v)
+			default:
+				return nil, fmt.Errorf("cannot add %s and %s", typeName(r), typeName(v))
+			}
+		default:
+			return nil, fmt.Errorf("cannot sum %s", typeName(v))
+		}
+	}
+	return result, nil
+}
+
+func addBalance(b *journal.Balance, v Value) {
+	switch v := v.(type) {
+	case Amount:
+		b.Add(v.Amount)
+	case Balance:
+		b.AddBal(v.Balance)
+	}
+}
+
+// extreme returns the smallest value if sign is -1 or the largest
+// if sign is 1, ignoring nulls.
+func extreme(vs []Value, sign int) (Value, error) {
+	var result Value = Null{}
+	for _, v := range vs {
+		if isNull(v) {
+			continue
+		}
+		if isNull(result) {
+			result = v
+			continue
+		}
+		n, err := compareValues(v, result)
+		if err != nil {
+			return nil, err
+		}
+		if n*sign > 0 {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+func firstNonNull(vs []Value, start, step int) (Value, error) {
+	for i := start; i >= 0 && i < len(vs); i += step {
+		if !isNull(vs[i]) {
+			return vs[i], nil
+		}
+	}
+	return Null{}, nil
+}
+
+// hasAggregate returns whether the expression contains an aggregate
+// function.
+func hasAggregate(x expr) bool {
+	switch x := x.(type) {
+	case *aggregate:
+		return true
+	case *paren:
+		return hasAggregate(x.x)
+	case *not:
+		return hasAggregate(x.x)
+	case *logical:
+		return hasAggregate(x.x) || hasAggregate(x.y)
+	case *comparison:
+		return hasAggregate(x.x) || hasAggregate(x.y)
+	case *match:
+		return hasAggregate(x.x)
+	case *call:
+		for _, a := range x.args {
+			if hasAggregate(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isNull(v Value) bool {
+	_, ok := v.(Null)
+	return ok
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tString
+	tNumber
+	tDate
+	tSymbol
+)
+
+type qtoken struct {
+	kind tokenKind
+	val  string
+	// pos is the byte offset of the token in the query.
+	pos int
+}
+
+func (t qtoken) String() string {
+	switch t.kind {
+	case tEOF:
+		return "end of query"
+	case tString:
+		return t.val
+	default:
+		return strconv.Quote(t.val)
+	}
+}
+
+// symbols are the operator and punctuation tokens, longest first.
+var symbols = []string{"<=", ">=", "!=", "<>", "=", "<", ">", "~", "(", ")", ",", "*", "-"}
+
+// lex splits a query into tokens.  The last token is always tEOF.
+func lex(s string) ([]qtoken, error) {
+	var toks []qtoken
+	i := 0
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			return append(toks, qtoken{kind: tEOF, pos: i}), nil
+		}
+		c := s[i]
+		switch {
+		case c == '"':
+			n, err := stringLen(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("offset %d: %s", i, err)
+			}
+			toks = append(toks, qtoken{kind: tString, val: s[i : i+n], pos: i})
+			i += n
+		case isDateAt(s[i:]):
+			toks = append(toks, qtoken{kind: tDate, val: s[i : i+10], pos: i})
+			i += 10
+		case isDigit(c):
+			j := i
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			if j+1 < len(s) && s[j] == '.' && isDigit(s[j+1]) {
+				j++
+				for j < len(s) && isDigit(s[j]) {
+					j++
+				}
+			}
+			toks = append(toks, qtoken{kind: tNumber, val: s[i:j], pos: i})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < len(s) && (isIdentStart(s[j]) || isDigit(s[j])) {
+				j++
+			}
+			toks = append(toks, qtoken{kind: tIdent, val: s[i:j], pos: i})
+			i = j
+		default:
+			sym := ""
+			for _, x := range symbols {
+				if strings.HasPrefix(s[i:], x) {
+					sym = x
+					break
+				}
+			}
+			if sym == "" {
+				return nil, fmt.Errorf("offset %d: unexpected character %q", i, c)
+			}
+			toks = append(toks, qtoken{kind: tSymbol, val: sym, pos: i})
+			i += len(sym)
+		}
+	}
+}
+
+// stringLen returns the length of the quoted string at the start of s.
+func stringLen(s string) (int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string")
+}
+
+// isDateAt returns whether s starts with a YYYY-MM-DD date.
+func isDateAt(s string) bool {
+	if len(s) < 10 {
+		return false
+	}
+	for i := 0; i < 10; i++ {
+		switch i {
+		case 4, 7:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isDigit(s[i]) {
+				return false
+			}
+		}
+	}
+	return len(s) == 10 || !isIdentStart(s[10]) && !isDigit(s[10])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
@@ -0,0 +1,410 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/civil"
+)
+
+var keywords = map[string]bool{
+	"SELECT": true,
+	"WHERE":  true,
+	"GROUP":  true,
+	"BY":     true,
+	"ORDER":  true,
+	"ASC":    true,
+	"DESC":   true,
+	"LIMIT":  true,
+	"AS":     true,
+	"AND":    true,
+	"OR":     true,
+	"NOT":    true,
+	"TRUE":   true,
+	"FALSE":  true,
+	"NULL":   true,
+}
+
+type parser struct {
+	toks []qtoken
+	i    int
+	// aliases maps select target names given with AS to their
+	// expressions.
+	aliases map[string]expr
+}
+
+func (p *parser) peek() qtoken {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() qtoken {
+	t := p.toks[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the keyword.
+func (p *parser) keyword(k string) bool {
+	t := p.peek()
+	if t.kind == tIdent && strings.EqualFold(t.val, k) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// symbol consumes the next token if it is the symbol.
+func (p *parser) symbol(s string) bool {
+	t := p.peek()
+	if t.kind == tSymbol && t.val == s {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(t qtoken, format string, v ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", t.pos, fmt.Sprintf(format, v...))
+}
+
+func (p *parser) expectKeyword(k string) error {
+	if !p.keyword(k) {
+		t := p.peek()
+		return p.errorf(t, "expected %s, got %s", k, t)
+	}
+	return nil
+}
+
+func (p *parser) expectSymbol(s string) error {
+	if !p.symbol(s) {
+		t := p.peek()
+		return p.errorf(t, "expected %q, got %s", s, t)
+	}
+	return nil
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{limit: -1}
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		t, err := p.parseTarget()
+		if err != nil {
+			return nil, err
+		}
+		q.targets = append(q.targets, t)
+		if !p.symbol(",") {
+			break
+		}
+	}
+	p.aliases = make(map[string]expr)
+	for _, t := range q.targets {
+		if t.alias {
+			p.aliases[t.name] = t.expr
+		}
+	}
+	if p.keyword("WHERE") {
+		t := p.peek()
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if hasAggregate(x) {
+			return nil, p.errorf(t, "aggregate function in WHERE")
+		}
+		q.where = x
+	}
+	if p.keyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			t := p.peek()
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if hasAggregate(x) {
+				return nil, p.errorf(t, "aggregate function in GROUP BY")
+			}
+			q.groupBy = append(q.groupBy, x)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			o := order{expr: x}
+			if p.keyword("DESC") {
+				o.desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			q.orderBy = append(q.orderBy, o)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("LIMIT") {
+		t := p.next()
+		n, err := strconv.Atoi(t.val)
+		if t.kind != tNumber || err != nil {
+			return nil, p.errorf(t, "expected limit, got %s", t)
+		}
+		q.limit = n
+	}
+	if t := p.peek(); t.kind != tEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return q, nil
+}
+
+func (p *parser) parseTarget() (target, error) {
+	x, err := p.parseExpr()
+	if err != nil {
+		return target{}, err
+	}
+	t := target{name: x.String(), expr: x}
+	if p.keyword("AS") {
+		n := p.next()
+		if n.kind != tIdent || keywords[strings.ToUpper(n.val)] {
+			return target{}, p.errorf(n, "expected name, got %s", n)
+		}
+		t.name = n.val
+		t.alias = true
+	}
+	return t, nil
+}
+
+func (p *parser) parseExpr() (expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &logical{op: "OR", x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = &logical{op: "AND", x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.keyword("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisonOps = map[string]bool{
+	"=": true, "!=": true, "<>": true,
+	"<": true, "<=": true, ">": true, ">=": true,
+}
+
+func (p *parser) parseComparison() (expr, error) {
+	x, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tSymbol && comparisonOps[t.val]:
+		p.next()
+		y, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		op := t.val
+		if op == "<>" {
+			op = "!="
+		}
+		return &comparison{op: op, x: x, y: y}, nil
+	case t.kind == tSymbol && t.val == "~":
+		p.next()
+		s := p.next()
+		if s.kind != tString {
+			return nil, p.errorf(s, "expected regexp string, got %s", s)
+		}
+		v, err := strconv.Unquote(s.val)
+		if err != nil {
+			return nil, p.errorf(s, "%s", err)
+		}
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, p.errorf(s, "%s", err)
+		}
+		return &match{x: x, re: re, src: s.val}, nil
+	}
+	return x, nil
+}
+
+func (p *parser) parseOperand() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tString:
+		s, err := strconv.Unquote(t.val)
+		if err != nil {
+			return nil, p.errorf(t, "%s", err)
+		}
+		return &literal{v: String(s), src: t.val}, nil
+	case tDate:
+		d, err := civil.ParseDate(t.val)
+		if err != nil {
+			return nil, p.errorf(t, "%s", err)
+		}
+		return &literal{v: Date(d), src: t.val}, nil
+	case tNumber:
+		return numberLiteral(t.val), nil
+	case tSymbol:
+		switch t.val {
+		case "(":
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			return &paren{x: x}, nil
+		case "-":
+			n := p.next()
+			if n.kind != tNumber {
+				return nil, p.errorf(n, "expected number, got %s", n)
+			}
+			return numberLiteral("-" + n.val), nil
+		}
+	case tIdent:
+		switch strings.ToUpper(t.val) {
+		case "TRUE":
+			return &literal{v: Bool(true), src: "TRUE"}, nil
+		case "FALSE":
+			return &literal{v: Bool(false), src: "FALSE"}, nil
+		case "NULL":
+			return &literal{v: Null{}, src: "NULL"}, nil
+		}
+		if keywords[strings.ToUpper(t.val)] {
+			break
+		}
+		name := strings.ToLower(t.val)
+		if p.symbol("(") {
+			return p.parseCall(t, name)
+		}
+		if x, ok := p.aliases[t.val]; ok {
+			return x, nil
+		}
+		if _, ok := columns[name]; !ok {
+			return nil, p.errorf(t, "unknown column %s", t.val)
+		}
+		return &column{name: name}, nil
+	}
+	return nil, p.errorf(t, "unexpected %s", t)
+}
+
+// parseCall parses a function call after the opening parenthesis.
+func (p *parser) parseCall(t qtoken, name string) (expr, error) {
+	var args []expr
+	star := false
+	switch {
+	case p.symbol(")"):
+	case p.symbol("*"):
+		star = true
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+	default:
+		for {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, x)
+			if !p.symbol(",") {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := aggregates[name]; ok {
+		switch {
+		case star && name != "count":
+			return nil, p.errorf(t, "%s(*) not allowed", name)
+		case !star && len(args) != 1:
+			return nil, p.errorf(t, "%s takes 1 argument", name)
+		case len(args) == 1 && hasAggregate(args[0]):
+			return nil, p.errorf(t, "nested aggregate function")
+		}
+		var arg expr
+		if len(args) == 1 {
+			arg = args[0]
+		}
+		return &aggregate{name: name, arg: arg}, nil
+	}
+	f, ok := functions[name]
+	if !ok {
+		return nil, p.errorf(t, "unknown function %s", name)
+	}
+	if star || len(args) != f.nargs {
+		return nil, p.errorf(t, "%s takes %d arguments", name, f.nargs)
+	}
+	return &call{name: name, f: f, args: args}, nil
+}
+
+func numberLiteral(s string) expr {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic(fmt.Sprintf("invalid number %q", s))
+	}
+	return &literal{v: Number{Rat: r}, src: s}
+}
@@ -0,0 +1,259 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package query implements a small SQL-like query language over the
+transaction splits in a journal.
+
+A query looks like:
+
+	SELECT account, sum(amount) AS total
+	WHERE date >= 2024-01-01 AND account ~ "^Expenses:"
+	GROUP BY account
+	ORDER BY total DESC
+	LIMIT 10
+
+Every clause except SELECT is optional.  Keywords are case
+insensitive.  Each split of each transaction is one row, with the
+following columns:
+
+	date         transaction date
+	description  transaction description
+	account      split account
+	amount       split amount
+	number       split amount without the unit
+	unit         split unit
+	tags         transaction and split tags, comma separated
+	position     transaction position in the input files
+
+Literals are strings ("foo"), dates (2024-01-01), numbers (-1.5),
+TRUE, FALSE and NULL.  Expressions can use the comparison operators
+=, !=, <>, <, <=, > and >=, regular expression matching with ~,
+AND, OR, NOT and parentheses.  Comparisons with NULL are false.
+Numbers compare with amounts by value.
+
+Scalar functions:
+
+	root(account, n)  first n components of an account
+	parent(account)   parent of an account
+	year(date)        year of a date
+	month(date)       month of a date as YYYY-MM
+	meta(key)         split metadata value, else transaction metadata value
+	has_tag(tag)      whether the split or transaction has a tag
+
+Aggregate functions are count, sum, min, max, first and last.
+count(*) counts rows.  Summing amounts produces a balance.  If the
+query has aggregate functions or GROUP BY, the rows are grouped and
+each group produces one result row.  Expressions outside of
+aggregate functions are evaluated on the first row in the group.
+
+Clauses after SELECT can refer to select targets by their AS name.
+*/
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.felesatra.moe/keeper/journal"
+)
+
+// A Query is a parsed query.
+type Query struct {
+	targets []target
+	where   expr
+	groupBy []expr
+	orderBy []order
+	// limit is the maximum number of result rows, or -1 for no
+	// limit.
+	limit int
+}
+
+// A target is a selected expression.
+type target struct {
+	// name is the name of the result column.
+	name  string
+	alias bool
+	expr  expr
+}
+
+// An order is an ORDER BY item.
+type order struct {
+	desc bool
+	expr expr
+}
+
+// Parse parses a query.
+func Parse(s string) (*Query, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, fmt.Errorf("parse query: %s", err)
+	}
+	p := &parser{toks: toks}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("parse query: %s", err)
+	}
+	return q, nil
+}
+
+// A Result is the result of running a query.
+type Result struct {
+	Columns []string
+	Rows    [][]Value
+}
+
+// Run runs the query over the transaction splits in the journal.
+func (q *Query) Run(j *journal.Journal) (*Result, error) {
+	var rows []row
+	for _, e := range j.Entries {
+		t, ok := e.(*journal.Transaction)
+		if !ok {
+			continue
+		}
+		for i := range t.Splits {
+			r := row{tx: t, split: &t.Splits[i]}
+			if q.where != nil {
+				ok, err := evalBool(q.where, &env{rows: []row{r}})
+				if err != nil {
+					return nil, fmt.Errorf("run query: %s", err)
+				}
+				if !ok {
+					continue
+				}
+			}
+			rows = append(rows, r)
+		}
+	}
+	var envs []*env
+	if q.grouped() {
+		var err error
+		envs, err = q.group(rows)
+		if err != nil {
+			return nil, fmt.Errorf("run query: %s", err)
+		}
+	} else {
+		for _, r := range rows {
+			envs = append(envs, &env{rows: []row{r}})
+		}
+	}
+	res := &Result{}
+	for _, t := range q.targets {
+		res.Columns = append(res.Columns, t.name)
+	}
+	type resultRow struct {
+		vals []Value
+		keys []Value
+	}
+	rr := make([]resultRow, len(envs))
+	for i, e := range envs {
+		for _, t := range q.targets {
+			v, err := t.expr.eval(e)
+			if err != nil {
+				return nil, fmt.Errorf("run query: %s", err)
+			}
+			rr[i].vals = append(rr[i].vals, v)
+		}
+		for _, o := range q.orderBy {
+			v, err := o.expr.eval(e)
+			if err != nil {
+				return nil, fmt.Errorf("run query: %s", err)
+			}
+			rr[i].keys = append(rr[i].keys, v)
+		}
+	}
+	var sortErr error
+	sort.SliceStable(rr, func(i, j int) bool {
+		for k, o := range q.orderBy {
+			n, err := compareValues(rr[i].keys[k], rr[j].keys[k])
+			if err != nil {
+				sortErr = fmt.Errorf("%s: %s", o.expr, err)
+				return false
+			}
+			if o.desc {
+				n = -n
+			}
+			if n != 0 {
+				return n < 0
+			}
+		}
+		return false
+	})
+	if sortErr != nil {
+		return nil, fmt.Errorf("run query: %s", sortErr)
+	}
+	if q.limit >= 0 && len(rr) > q.limit {
+		rr = rr[:q.limit]
+	}
+	for _, r := range rr {
+		res.Rows = append(res.Rows, r.vals)
+	}
+	return res, nil
+}
+
+// grouped returns whether the query groups rows.
+func (q *Query) grouped() bool {
+	if len(q.groupBy) > 0 {
+		return true
+	}
+	for _, t := range q.targets {
+		if hasAggregate(t.expr) {
+			return true
+		}
+	}
+	for _, o := range q.orderBy {
+		if hasAggregate(o.expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// group groups rows by the GROUP BY expressions, in order of first
+// appearance.  Without GROUP BY, all rows form one group.
+func (q *Query) group(rows []row) ([]*env, error) {
+	if len(q.groupBy) == 0 {
+		return []*env{{rows: rows}}, nil
+	}
+	var envs []*env
+	groups := make(map[string]*env)
+	for _, r := range rows {
+		k, err := q.groupKey(r)
+		if err != nil {
+			return nil, err
+		}
+		e, ok := groups[k]
+		if !ok {
+			e = &env{}
+			groups[k] = e
+			envs = append(envs, e)
+		}
+		e.rows = append(e.rows, r)
+	}
+	return envs, nil
+}
+
+func (q *Query) groupKey(r row) (string, error) {
+	var b strings.Builder
+	e := &env{rows: []row{r}}
+	for _, x := range q.groupBy {
+		v, err := x.eval(e)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%s\x00", typeName(v), v)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,215 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/journal"
+)
+
+const testJournal = `unit USD 100
+unit JPY 1
+tx 2024-01-05 "Groceries" #food
+meta "invoice" "A-1"
+Expenses:Food 12.50 USD
+Assets:Cash
+end
+tx 2024-01-20 "Dinner" #food
+Expenses:Food:Restaurant 30 USD #work
+meta "project" "apollo"
+Assets:Cash
+end
+tx 2024-02-01 "Rent"
+Expenses:Rent 1000 USD
+Assets:Bank
+end
+tx 2024-02-10 "Ramen"
+Expenses:Food 900 JPY
+Assets:Cash
+end
+balance 2024-02-11 Assets:Bank -1000 USD
+`
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, testJournal)
+	cases := []struct {
+		desc  string
+		query string
+		want  [][]string
+	}{
+		{
+			desc:  "select with where",
+			query: `SELECT date, account, amount WHERE account ~ "^Expenses:Food" AND date >= 2024-01-10`,
+			want: [][]string{
+				{"date", "account", "amount"},
+				{"2024-01-20", "Expenses:Food:Restaurant", "30.00 USD"},
+				{"2024-02-10", "Expenses:Food", "900 JPY"},
+			},
+		},
+		{
+			desc:  "group by",
+			query: `select root(account, 1) as top, sum(amount) as total, count(*) group by top order by top`,
+			want: [][]string{
+				{"top", "total", "count(*)"},
+				{"Assets", "-900 JPY, -1,042.50 USD", "4"},
+				{"Expenses", "900 JPY, 1,042.50 USD", "4"},
+			},
+		},
+		{
+			desc:  "aggregate without group by",
+			query: `SELECT sum(amount), min(date), max(date) WHERE unit = "USD" AND number > 0`,
+			want: [][]string{
+				{"sum(amount)", "min(date)", "max(date)"},
+				{"1,042.50 USD", "2024-01-05", "2024-02-01"},
+			},
+		},
+		{
+			desc:  "aggregate no rows",
+			query: `SELECT count(*), sum(amount) WHERE account = "Nope"`,
+			want: [][]string{
+				{"count(*)", "sum(amount)"},
+				{"0", ""},
+			},
+		},
+		{
+			desc:  "order and limit",
+			query: `SELECT account, amount WHERE amount > 0 AND unit = "USD" ORDER BY amount DESC LIMIT 2`,
+			want: [][]string{
+				{"account", "amount"},
+				{"Expenses:Rent", "1,000.00 USD"},
+				{"Expenses:Food:Restaurant", "30.00 USD"},
+			},
+		},
+		{
+			desc:  "metadata and tags",
+			query: `SELECT description, meta("invoice"), meta("project"), tags WHERE has_tag("food") AND NOT account = "Assets:Cash"`,
+			want: [][]string{
+				{"description", `meta("invoice")`, `meta("project")`, "tags"},
+				{"Groceries", "A-1", "", "food"},
+				{"Dinner", "", "apollo", "food,work"},
+			},
+		},
+		{
+			desc:  "month grouping",
+			query: `SELECT month(date) AS m, sum(number) WHERE account ~ "^Expenses" AND unit = "USD" GROUP BY m`,
+			want: [][]string{
+				{"m", "sum(number)"},
+				{"2024-01", "42.5"},
+				{"2024-02", "1000"},
+			},
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			q, err := Parse(c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := q.Run(j)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.want, formatResult(r)); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{`account`, "expected SELECT"},
+		{`SELECT foo`, "unknown column foo"},
+		{`SELECT bar(account)`, "unknown function bar"},
+		{`SELECT account WHERE sum(amount) > 0`, "aggregate function in WHERE"},
+		{`SELECT sum(sum(amount))`, "nested aggregate function"},
+		{`SELECT root(account)`, "root takes 2 arguments"},
+		{`SELECT account WHERE account ~ "["`, "error parsing regexp"},
+		{`SELECT account WHERE account = "foo`, "unterminated string"},
+		{`SELECT account LIMIT x`, "expected limit"},
+		{`SELECT account account`, `unexpected "account"`},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.query)
+		if err == nil {
+			t.Errorf("Parse(%q): expected error", c.query)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%q) = %q; want %q", c.query, err, c.want)
+		}
+	}
+}
+
+func TestRun_errors(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, testJournal)
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT account WHERE date = "foo"`, "cannot compare date and string"},
+		{`SELECT account WHERE account`, "expected bool, got string"},
+		{`SELECT sum(account)`, "cannot sum string"},
+	}
+	for _, c := range cases {
+		q, err := Parse(c.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %s", c.query, err)
+			continue
+		}
+		_, err = q.Run(j)
+		if err == nil {
+			t.Errorf("Run(%q): expected error", c.query)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Run(%q) = %q; want %q", c.query, err, c.want)
+		}
+	}
+}
+
+func compileText(t *testing.T, s string) *journal.Journal {
+	t.Helper()
+	j, err := journal.Compile(&journal.CompileArgs{
+		Inputs: []journal.CompileInput{journal.Bytes("testfile", []byte(s))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func formatResult(r *Result) [][]string {
+	s := [][]string{r.Columns}
+	for _, row := range r.Rows {
+		var vs []string
+		for _, v := range row {
+			vs = append(vs, v.String())
+		}
+		s = append(s, vs)
+	}
+	return s
+}
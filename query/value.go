@@ -0,0 +1,231 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// A Value is a value in a query result.
+//
+// Value types:
+//
+//   - [Null]
+//   - [String]
+//   - [Bool]
+//   - [Date]
+//   - [Number]
+//   - [Amount]
+//   - [Balance]
+type Value interface {
+	String() string
+	value()
+}
+
+// Null is a missing value.
+type Null struct{}
+
+func (Null) String() string { return "" }
+
+// A String is a string value.
+type String string
+
+func (s String) String() string { return string(s) }
+
+// A Bool is a boolean value.
+type Bool bool
+
+func (b Bool) String() string { return fmt.Sprint(bool(b)) }
+
+// A Date is a date value.
+type Date civil.Date
+
+func (d Date) String() string { return civil.Date(d).String() }
+
+// A Number is a unitless number.
+type Number struct {
+	Rat *big.Rat
+}
+
+func (n Number) String() string { return ratString(n.Rat) }
+
+// An Amount is an amount of a unit.
+type Amount struct {
+	*journal.Amount
+}
+
+// A Balance is a sum of amounts of possibly different units.
+type Balance struct {
+	*journal.Balance
+}
+
+func (Null) value()    {}
+func (String) value()  {}
+func (Bool) value()    {}
+func (Date) value()    {}
+func (Number) value()  {}
+func (Amount) value()  {}
+func (Balance) value() {}
+
+// typeName returns the name of the value type for error messages.
+func typeName(v Value) string {
+	switch v.(type) {
+	case Null:
+		return "null"
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	case Date:
+		return "date"
+	case Number:
+		return "number"
+	case Amount:
+		return "amount"
+	case Balance:
+		return "balance"
+	default:
+		panic(fmt.Sprintf("unknown value %T", v))
+	}
+}
+
+// ratString formats a rational number as a decimal, using as few
+// decimal places as needed, up to a limit.
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	const maxPrec = 10
+	var x big.Rat
+	for prec := 1; prec < maxPrec; prec++ {
+		s := r.FloatString(prec)
+		x.SetString(s)
+		if x.Cmp(r) == 0 {
+			return s
+		}
+	}
+	return r.FloatString(maxPrec)
+}
+
+// amountRat returns the value of an amount as a rational number.
+func amountRat(a *journal.Amount) *big.Rat {
+	var d big.Int
+	d.SetUint64(a.Unit.Scale)
+	return new(big.Rat).SetFrac(&a.Number, &d)
+}
+
+// compareValues compares two values for ordering.  Null orders before
+// all other values.  Amounts of different units are ordered by unit.
+func compareValues(a, b Value) (int, error) {
+	_, an := a.(Null)
+	_, bn := b.(Null)
+	switch {
+	case an && bn:
+		return 0, nil
+	case an:
+		return -1, nil
+	case bn:
+		return 1, nil
+	}
+	switch a := a.(type) {
+	case String:
+		if b, ok := b.(String); ok {
+			return strings.Compare(string(a), string(b)), nil
+		}
+	case Bool:
+		if b, ok := b.(Bool); ok {
+			return boolInt(bool(a)) - boolInt(bool(b)), nil
+		}
+	case Date:
+		if b, ok := b.(Date); ok {
+			return compareDates(civil.Date(a), civil.Date(b)), nil
+		}
+	case Amount:
+		if b, ok := b.(Amount); ok {
+			return compareAmounts(a.Amount, b.Amount), nil
+		}
+	case Balance:
+		if b, ok := b.(Balance); ok {
+			return compareBalances(a.Balance, b.Balance), nil
+		}
+	}
+	ar, aok := numericRat(a)
+	br, bok := numericRat(b)
+	if aok && bok {
+		return ar.Cmp(br), nil
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", typeName(a), typeName(b))
+}
+
+// numericRat returns the numeric value of a number, amount, or
+// balance with at most one unit.
+func numericRat(v Value) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case Number:
+		return v.Rat, true
+	case Amount:
+		return amountRat(v.Amount), true
+	case Balance:
+		amts := v.Amounts()
+		switch len(amts) {
+		case 0:
+			return new(big.Rat), true
+		case 1:
+			return amountRat(amts[0]), true
+		}
+	}
+	return nil, false
+}
+
+func compareAmounts(a, b *journal.Amount) int {
+	if c := strings.Compare(a.Unit.Symbol, b.Unit.Symbol); c != 0 {
+		return c
+	}
+	return amountRat(a).Cmp(amountRat(b))
+}
+
+// compareBalances compares balances by their amounts in unit order.
+func compareBalances(a, b *journal.Balance) int {
+	aa, ba := a.Amounts(), b.Amounts()
+	for i := 0; i < len(aa) && i < len(ba); i++ {
+		if c := compareAmounts(aa[i], ba[i]); c != 0 {
+			return c
+		}
+	}
+	return len(aa) - len(ba)
+}
+
+func compareDates(a, b civil.Date) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"math/big"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// A BudgetReport compares budgets with actual amounts over a period.
+type BudgetReport struct {
+	Start, End civil.Date
+	// Rows contains a row for each budgeted account and unit,
+	// sorted by account.
+	Rows []BudgetRow
+}
+
+// A BudgetRow compares the budget for an account tree with the actual
+// amount.
+type BudgetRow struct {
+	Account   journal.Account
+	Budgeted  *journal.Amount
+	Actual    *journal.Amount
+	Remaining *journal.Amount
+	// Used is the fraction of the budget used, or nil if nothing
+	// is budgeted.
+	Used *big.Rat
+}
+
+// Over returns true if the actual amount is over budget.
+func (r *BudgetRow) Over() bool {
+	return r.Remaining.Sign() < 0
+}
+
+// NewBudgetReport returns the budget report for the period between
+// the start and end dates, inclusive.
+//
+// The actual amount for a budget is the total change of the account
+// and its sub-accounts in the period.  Accounts with credit balances
+// (income, liabilities, and equity) have their amounts negated so
+// that budgets are positive.  Closing transactions are ignored, like
+// in NewIncomeStatement.
+func NewBudgetReport(j *journal.Journal, c Classifier, start, end civil.Date) *BudgetReport {
+	r := &BudgetReport{Start: start, End: end}
+	keys := j.Budgets.Keys()
+	ts := transactions(j, start, end)
+	for _, k := range keys {
+		row := BudgetRow{
+			Account:  k.Account,
+			Budgeted: j.Budgets.Budgeted(k, start, end),
+			Actual:   &journal.Amount{Unit: k.Unit},
+		}
+		for _, t := range ts {
+			if isClosing(c, t) {
+				continue
+			}
+			for _, s := range t.Splits {
+				if s.Amount.Unit == k.Unit && (s.Account == k.Account || s.Account.Under(k.Account)) {
+					row.Actual.Number.Add(&row.Actual.Number, &s.Amount.Number)
+				}
+			}
+		}
+		if c.IsIncome(k.Account) || c.IsLiabilities(k.Account) || c.IsEquity(k.Account) {
+			row.Actual.Neg()
+		}
+		if row.Budgeted.Zero() && row.Actual.Zero() {
+			continue
+		}
+		row.Remaining = &journal.Amount{Unit: k.Unit}
+		row.Remaining.Number.Sub(&row.Budgeted.Number, &row.Actual.Number)
+		if !row.Budgeted.Zero() {
+			row.Used = new(big.Rat).SetFrac(&row.Actual.Number, &row.Budgeted.Number)
+		}
+		r.Rows = append(r.Rows, row)
+	}
+	return r
+}
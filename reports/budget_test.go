@@ -0,0 +1,49 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewBudgetReport(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, statementText+`tx 2020-02-15 "Lunch"
+Assets:Cash -10 USD
+Expenses:Food:Out 10 USD
+end
+budget 2020-01 Expenses:Food 35 USD monthly
+budget 2020-01 Income:Salary 400 USD monthly
+budget 2020-01 Expenses:Rent 1000 USD monthly
+`)
+	got := NewBudgetReport(j, statementConfig(), civil.Date{2020, 2, 1}, civil.Date{2020, 2, 29})
+	var rows []string
+	for _, r := range got.Rows {
+		rows = append(rows, fmt.Sprintf("%s %s %s %s %s %v",
+			r.Account, r.Budgeted, r.Actual, r.Remaining, r.Used.FloatString(2), r.Over()))
+	}
+	want := []string{
+		"Expenses:Food 35.00 USD 50.00 USD -15.00 USD 1.43 true",
+		"Expenses:Rent 1,000.00 USD 0.00 USD 1,000.00 USD 0.00 false",
+		"Income:Salary 400.00 USD 0.00 USD 400.00 USD 0.00 false",
+	}
+	if diff := cmp.Diff(want, rows); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+}
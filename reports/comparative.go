@@ -0,0 +1,163 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"math/big"
+	"sort"
+
+	"go.felesatra.moe/keeper/journal"
+	"go.felesatra.moe/keeper/period"
+)
+
+// A Comparative is a financial statement for several periods side by
+// side.
+type Comparative struct {
+	Periods  []period.Period
+	Sections []ComparativeSection
+}
+
+// A ComparativeSection is a section of a Comparative.
+type ComparativeSection struct {
+	Title string
+	Rows  []ComparativeRow
+	// Totals contains a row for each unit in the section totals.
+	Totals []ComparativeRow
+}
+
+// A ComparativeRow contains the amounts of one unit for an account
+// in each period.
+type ComparativeRow struct {
+	// Account is empty for total rows.
+	Account journal.Account
+	Unit    journal.Unit
+	// Amounts contains the amount for each period.
+	Amounts []*journal.Amount
+	// Variance is the change from the first period to the last
+	// period.
+	Variance *journal.Amount
+	// Change is the variance as a fraction of the amount for the
+	// first period, or nil if the amount for the first period is
+	// zero.
+	Change *big.Rat
+}
+
+// NewComparativeIncomeStatement returns income statements for the
+// periods side by side.
+func NewComparativeIncomeStatement(j *journal.Journal, c Classifier, periods []period.Period) *Comparative {
+	var income, expenses []*StatementSection
+	var net []*journal.Balance
+	for _, p := range periods {
+		s := NewIncomeStatement(j, c, p.Start, p.End)
+		income = append(income, &s.Income)
+		expenses = append(expenses, &s.Expenses)
+		net = append(net, &s.NetProfit)
+	}
+	return &Comparative{
+		Periods: periods,
+		Sections: []ComparativeSection{
+			compareSections("Income", income),
+			compareSections("Expenses", expenses),
+			{Title: "Net Profit", Totals: compareBalances("", net)},
+		},
+	}
+}
+
+// NewComparativeBalanceSheet returns balance sheets at the close of
+// the periods side by side.
+func NewComparativeBalanceSheet(j *journal.Journal, c Classifier, periods []period.Period) *Comparative {
+	var assets, liabilities, equity []*StatementSection
+	var total []*journal.Balance
+	for _, p := range periods {
+		s := NewBalanceSheet(j, c, p.End)
+		assets = append(assets, &s.Assets)
+		liabilities = append(liabilities, &s.Liabilities)
+		equity = append(equity, &s.Equity)
+		total = append(total, &s.LiabilitiesEquity)
+	}
+	return &Comparative{
+		Periods: periods,
+		Sections: []ComparativeSection{
+			compareSections("Assets", assets),
+			compareSections("Liabilities", liabilities),
+			compareSections("Equity", equity),
+			{Title: "Liabilities & Equity", Totals: compareBalances("", total)},
+		},
+	}
+}
+
+// compareSections combines the sections of statements for several
+// periods.
+func compareSections(title string, s []*StatementSection) ComparativeSection {
+	bals := make(map[journal.Account][]*journal.Balance)
+	totals := make([]*journal.Balance, len(s))
+	for i, s := range s {
+		for k := range s.Rows {
+			r := &s.Rows[k]
+			b, ok := bals[r.Account]
+			if !ok {
+				b = make([]*journal.Balance, len(totals))
+				bals[r.Account] = b
+			}
+			b[i] = &r.Balance
+		}
+		totals[i] = &s.Total
+	}
+	accounts := make([]journal.Account, 0, len(bals))
+	for a := range bals {
+		accounts = append(accounts, a)
+	}
+	sortAccounts(accounts)
+	cs := ComparativeSection{Title: title}
+	for _, a := range accounts {
+		cs.Rows = append(cs.Rows, compareBalances(a, bals[a])...)
+	}
+	cs.Totals = compareBalances("", totals)
+	return cs
+}
+
+// compareBalances returns a row for each unit in the balances for
+// several periods.  Nil balances are treated as empty.
+func compareBalances(a journal.Account, b []*journal.Balance) []ComparativeRow {
+	var units []journal.Unit
+	seen := make(map[journal.Unit]bool)
+	for _, b := range b {
+		for _, u := range b.Units() {
+			if !seen[u] {
+				seen[u] = true
+				units = append(units, u)
+			}
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Symbol < units[j].Symbol })
+	var rows []ComparativeRow
+	for _, u := range units {
+		r := ComparativeRow{Account: a, Unit: u}
+		for _, b := range b {
+			r.Amounts = append(r.Amounts, b.Amount(u))
+		}
+		first, last := r.Amounts[0], r.Amounts[len(r.Amounts)-1]
+		r.Variance = &journal.Amount{Unit: u}
+		r.Variance.Number.Sub(&last.Number, &first.Number)
+		if !first.Zero() {
+			r.Change = new(big.Rat).SetFrac(&r.Variance.Number, &first.Number)
+			if first.Sign() < 0 {
+				r.Change.Neg(r.Change)
+			}
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/period"
+)
+
+func TestNewComparativeIncomeStatement(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, statementText)
+	p, err := period.Parse("2020-02", time.January)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := NewComparativeIncomeStatement(j, statementConfig(), period.Series(p, 2))
+	want := []string{
+		"Income",
+		"Income:Salary USD 500.00 USD 0.00 USD -500.00 USD -1",
+		"total USD 500.00 USD 0.00 USD -500.00 USD -1",
+		"Expenses",
+		"Expenses:Food USD 30.00 USD 40.00 USD 10.00 USD 1/3",
+		"total USD 30.00 USD 40.00 USD 10.00 USD 1/3",
+		"Net Profit",
+		"total USD 470.00 USD -40.00 USD -510.00 USD -51/47",
+	}
+	if diff := cmp.Diff(want, formatComparative(got)); diff != "" {
+		t.Errorf("statement mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewComparativeBalanceSheet(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, statementText)
+	p, err := period.Parse("2020-02", time.January)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := NewComparativeBalanceSheet(j, statementConfig(), period.Series(p, 2))
+	want := []string{
+		"Assets",
+		"Assets:Cash USD 1,500.00 USD 1,430.00 USD -70.00 USD -7/150",
+		"total USD 1,500.00 USD 1,430.00 USD -70.00 USD -7/150",
+		"Liabilities",
+		"Liabilities:Card USD 30.00 USD 0.00 USD -30.00 USD -1",
+		"total USD 30.00 USD 0.00 USD -30.00 USD -1",
+		"Equity",
+		"Equity:Opening USD 1,000.00 USD 1,000.00 USD 0.00 USD 0",
+		"Equity:Retained USD 0.00 USD 470.00 USD 470.00 USD -",
+		"Expenses:Food USD -30.00 USD -40.00 USD -10.00 USD -1/3",
+		"Income:Salary USD 500.00 USD 0.00 USD -500.00 USD -1",
+		"total USD 1,470.00 USD 1,430.00 USD -40.00 USD -4/147",
+		"Liabilities & Equity",
+		"total USD 1,500.00 USD 1,430.00 USD -70.00 USD -7/150",
+	}
+	if diff := cmp.Diff(want, formatComparative(got)); diff != "" {
+		t.Errorf("statement mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func formatComparative(c *Comparative) []string {
+	var r []string
+	format := func(name string, row ComparativeRow) string {
+		s := fmt.Sprintf("%s %s", name, row.Unit)
+		for _, a := range row.Amounts {
+			s += " " + a.String()
+		}
+		s += " " + row.Variance.String()
+		if row.Change != nil {
+			s += " " + row.Change.RatString()
+		} else {
+			s += " -"
+		}
+		return s
+	}
+	for _, s := range c.Sections {
+		r = append(r, s.Title)
+		for _, row := range s.Rows {
+			r = append(r, format(string(row.Account), row))
+		}
+		for _, row := range s.Totals {
+			r = append(r, format("total", row))
+		}
+	}
+	return r
+}
@@ -0,0 +1,485 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// CapitalGains is a report of realized capital gains.
+type CapitalGains struct {
+	// Base is the unit that gains are computed in.
+	Base       journal.Unit
+	Start, End civil.Date
+	// Rows contains the disposals in the report period, in order.
+	Rows []CapitalGainsRow
+	// Totals contains the totals for each disposed unit, sorted
+	// by unit.
+	Totals []CapitalGainsTotal
+}
+
+// A CapitalGainsRow describes the disposal of units acquired on one
+// date.
+type CapitalGainsRow struct {
+	Account journal.Account
+	// Amount is the amount of units disposed.
+	Amount   *journal.Amount
+	Acquired civil.Date
+	Disposed civil.Date
+	Proceeds *journal.Amount
+	Cost     *journal.Amount
+	Gain     *journal.Amount
+	// LongTerm is true if the units were held for more than a year.
+	LongTerm bool
+	// A reference to the file location for the disposal.
+	Ref string
+}
+
+// A CapitalGainsTotal contains the totals for the disposals of a unit.
+type CapitalGainsTotal struct {
+	Unit      journal.Unit
+	Amount    *journal.Amount
+	Proceeds  *journal.Amount
+	Cost      *journal.Amount
+	Gain      *journal.Amount
+	ShortTerm *journal.Amount
+	LongTerm  *journal.Amount
+}
+
+// NewCapitalGains returns the capital gains realized by disposing of
+// non-base units between the start and end dates, inclusive.
+//
+// Units are tracked per account.  Increases of a unit in an account
+// are acquisitions, and decreases are disposals matched against
+// earlier acquisitions in the same account, oldest first.  The cost
+// of acquisitions is the base unit amount paid in the transaction,
+// and the proceeds of disposals is the base unit amount received in
+// the transaction.  If a transaction exchanges more than one unit,
+// the base unit amount is allocated in proportion to the value of
+// the units, using the journal prices if available.  Transactions
+// that only move units between accounts transfer the acquisitions
+// instead.
+//
+// Decreases that make the balance of an account negative, and the
+// increases that return it to zero, are ignored, so trading accounts
+// do not count as holding units.
+//
+// Splits with lot annotations are not tracked by this report.
+// Instead, the lots booked by the journal are used for disposals.
+// An error is returned if the cost of a booked lot cannot be
+// converted to the base unit.
+func NewCapitalGains(j *journal.Journal, base journal.Unit, start, end civil.Date) (*CapitalGains, error) {
+	g := &CapitalGains{
+		Base:  base,
+		Start: start,
+		End:   end,
+	}
+	gb := gainsBuilder{
+		j:    j,
+		base: base,
+		held: make(map[holding]*holdingLots),
+	}
+	for _, e := range j.Entries {
+		t, ok := e.(*journal.Transaction)
+		if !ok {
+			continue
+		}
+		if t.EntryDate.After(end) {
+			break
+		}
+		rows, err := gb.exchange(t)
+		if err != nil {
+			return nil, fmt.Errorf("new capital gains: %s", err)
+		}
+		for _, r := range rows {
+			if !r.Disposed.Before(start) {
+				g.Rows = append(g.Rows, r)
+			}
+		}
+	}
+	g.Totals = gainsTotals(base, g.Rows)
+	return g, nil
+}
+
+func gainsTotals(base journal.Unit, rows []CapitalGainsRow) []CapitalGainsTotal {
+	m := make(map[journal.Unit]*CapitalGainsTotal)
+	var units []journal.Unit
+	for _, r := range rows {
+		t, ok := m[r.Amount.Unit]
+		if !ok {
+			t = &CapitalGainsTotal{
+				Unit:      r.Amount.Unit,
+				Amount:    &journal.Amount{Unit: r.Amount.Unit},
+				Proceeds:  &journal.Amount{Unit: base},
+				Cost:      &journal.Amount{Unit: base},
+				Gain:      &journal.Amount{Unit: base},
+				ShortTerm: &journal.Amount{Unit: base},
+				LongTerm:  &journal.Amount{Unit: base},
+			}
+			m[r.Amount.Unit] = t
+			units = append(units, r.Amount.Unit)
+		}
+		addAmount(t.Amount, r.Amount)
+		addAmount(t.Proceeds, r.Proceeds)
+		addAmount(t.Cost, r.Cost)
+		addAmount(t.Gain, r.Gain)
+		if r.LongTerm {
+			addAmount(t.LongTerm, r.Gain)
+		} else {
+			addAmount(t.ShortTerm, r.Gain)
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Symbol < units[j].Symbol })
+	var totals []CapitalGainsTotal
+	for _, u := range units {
+		totals = append(totals, *m[u])
+	}
+	return totals
+}
+
+func addAmount(a, b *journal.Amount) {
+	a.Number.Add(&a.Number, &b.Number)
+}
+
+type holding struct {
+	account journal.Account
+	unit    journal.Unit
+}
+
+// holdingLots tracks the acquisitions of a unit in an account.
+type holdingLots struct {
+	// Balance of the unit from the tracked splits.
+	balance big.Int
+	lots    []*gainLot
+}
+
+// A gainLot is an acquisition of units.
+type gainLot struct {
+	qty  big.Int
+	cost big.Int // in the base unit
+	date civil.Date
+}
+
+type gainsBuilder struct {
+	j    *journal.Journal
+	base journal.Unit
+	held map[holding]*holdingLots
+}
+
+func (b *gainsBuilder) get(h holding) *holdingLots {
+	l, ok := b.held[h]
+	if !ok {
+		l = &holdingLots{}
+		b.held[h] = l
+	}
+	return l
+}
+
+// An exchangeItem is an acquisition or disposal in a transaction.
+type exchangeItem struct {
+	holding
+	qty big.Int
+	// For disposals, the acquisitions being disposed of.
+	lots []*gainLot
+}
+
+// exchange processes a transaction, returning disposals.
+func (b *gainsBuilder) exchange(t *journal.Transaction) ([]CapitalGainsRow, error) {
+	var in, out big.Int
+	deltas := make(map[holding]*big.Int)
+	var holdings []holding
+	var sells []*exchangeItem
+	for _, s := range t.Splits {
+		a := s.Amount
+		switch {
+		case a.Unit == b.base:
+			if a.Sign() > 0 {
+				in.Add(&in, &a.Number)
+			} else {
+				out.Sub(&out, &a.Number)
+			}
+		case s.Lot != nil:
+			if a.Sign() < 0 {
+				i, err := b.bookedItem(s)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s", t.Position(), err)
+				}
+				sells = append(sells, i)
+			}
+		default:
+			h := holding{s.Account, a.Unit}
+			d, ok := deltas[h]
+			if !ok {
+				d = new(big.Int)
+				deltas[h] = d
+				holdings = append(holdings, h)
+			}
+			d.Add(d, &a.Number)
+		}
+	}
+	var buys []*exchangeItem
+	for _, h := range holdings {
+		d := deltas[h]
+		l := b.get(h)
+		var bal big.Int
+		bal.Set(&l.balance)
+		l.balance.Add(&l.balance, d)
+		switch d.Sign() {
+		case 1:
+			// Ignore the part covering a negative balance.
+			if bal.Sign() < 0 {
+				d.Add(d, &bal)
+			}
+			if d.Sign() > 0 {
+				i := &exchangeItem{holding: h}
+				i.qty.Set(d)
+				buys = append(buys, i)
+			}
+		case -1:
+			// Ignore the part making the balance negative.
+			d.Neg(d)
+			if d.Cmp(&bal) > 0 {
+				d.Set(&bal)
+			}
+			if d.Sign() > 0 {
+				i := &exchangeItem{holding: h}
+				i.qty.Set(d)
+				i.lots = l.take(d)
+				sells = append(sells, i)
+			}
+		}
+	}
+	buys, sells = b.transfer(buys, sells)
+	for i, c := range b.allocate(t.EntryDate, &out, buys) {
+		l := &gainLot{date: t.EntryDate}
+		l.qty.Set(&buys[i].qty)
+		l.cost.Set(c)
+		h := b.get(buys[i].holding)
+		h.lots = append(h.lots, l)
+	}
+	var rows []CapitalGainsRow
+	for i, p := range b.allocate(t.EntryDate, &in, sells) {
+		rows = append(rows, b.disposals(t, sells[i], p)...)
+	}
+	return rows, nil
+}
+
+// bookedItem returns a disposal for a split with lots booked by the
+// journal.
+func (b *gainsBuilder) bookedItem(s journal.Split) (*exchangeItem, error) {
+	i := &exchangeItem{holding: holding{s.Account, s.Amount.Unit}}
+	for _, l := range s.Booked {
+		gl := &gainLot{date: l.Date}
+		gl.qty.Neg(&l.Amount.Number)
+		c := l.TotalCost()
+		if c.Unit != b.base {
+			c.Neg()
+			c2, ok := b.j.Prices.Convert(c, b.base, l.Date)
+			if !ok {
+				return nil, fmt.Errorf("no price to convert cost of %s lot from %s to %s", l.Date, c.Unit.Symbol, b.base.Symbol)
+			}
+			c = c2
+			c.Neg()
+		}
+		gl.cost.Neg(&c.Number)
+		i.qty.Add(&i.qty, &gl.qty)
+		i.lots = append(i.lots, gl)
+	}
+	return i, nil
+}
+
+// transfer matches disposals and acquisitions of the same unit in a
+// transaction, moving the disposed acquisitions.  The remaining
+// acquisitions and disposals are returned.
+func (b *gainsBuilder) transfer(buys, sells []*exchangeItem) ([]*exchangeItem, []*exchangeItem) {
+	var buys2 []*exchangeItem
+	for _, buy := range buys {
+		for _, sell := range sells {
+			if buy.qty.Sign() == 0 {
+				break
+			}
+			if sell.unit != buy.unit || sell.qty.Sign() == 0 {
+				continue
+			}
+			lots := takeLots(&sell.lots, &buy.qty)
+			h := b.get(buy.holding)
+			for _, l := range lots {
+				sell.qty.Sub(&sell.qty, &l.qty)
+				buy.qty.Sub(&buy.qty, &l.qty)
+				h.lots = append(h.lots, l)
+			}
+		}
+		if buy.qty.Sign() > 0 {
+			buys2 = append(buys2, buy)
+		}
+	}
+	var sells2 []*exchangeItem
+	for _, sell := range sells {
+		if sell.qty.Sign() > 0 {
+			sells2 = append(sells2, sell)
+		}
+	}
+	return buys2, sells2
+}
+
+// allocate allocates a base unit amount to exchange items.
+func (b *gainsBuilder) allocate(d civil.Date, total *big.Int, items []*exchangeItem) []*big.Int {
+	if len(items) == 0 {
+		return nil
+	}
+	if total.Sign() == 0 {
+		// Nothing was exchanged, so use the value of the units.
+		return b.value(d, items)
+	}
+	weights := make([]*big.Int, len(items))
+	sameUnit := true
+	for i, it := range items {
+		weights[i] = &it.qty
+		if it.unit != items[0].unit {
+			sameUnit = false
+		}
+	}
+	if !sameUnit {
+		values := make([]*big.Int, len(items))
+		for i, it := range items {
+			a := &journal.Amount{Unit: it.unit}
+			a.Number.Set(&it.qty)
+			v, ok := b.j.Prices.Convert(a, b.base, d)
+			if !ok {
+				values = nil
+				break
+			}
+			values[i] = &v.Number
+		}
+		if values != nil {
+			weights = values
+		}
+	}
+	return allocate(total, weights)
+}
+
+// value returns the value of exchange items in the base unit, or
+// zero if there is no price.
+func (b *gainsBuilder) value(d civil.Date, items []*exchangeItem) []*big.Int {
+	values := make([]*big.Int, len(items))
+	for i, it := range items {
+		a := &journal.Amount{Unit: it.unit}
+		a.Number.Set(&it.qty)
+		values[i] = new(big.Int)
+		if v, ok := b.j.Prices.Convert(a, b.base, d); ok {
+			values[i].Set(&v.Number)
+		}
+	}
+	return values
+}
+
+// allocate splits the total in proportion to the weights.  The last
+// part gets the remainder from rounding.
+func allocate(total *big.Int, weights []*big.Int) []*big.Int {
+	var sum big.Int
+	for _, w := range weights {
+		sum.Add(&sum, w)
+	}
+	parts := make([]*big.Int, len(weights))
+	var rest big.Int
+	rest.Set(total)
+	for i, w := range weights {
+		parts[i] = new(big.Int)
+		if i == len(weights)-1 {
+			parts[i].Set(&rest)
+			break
+		}
+		if sum.Sign() != 0 {
+			parts[i].Mul(total, w)
+			parts[i].Quo(parts[i], &sum)
+		}
+		rest.Sub(&rest, parts[i])
+	}
+	return parts
+}
+
+// disposals returns the rows for a disposal item.
+func (b *gainsBuilder) disposals(t *journal.Transaction, it *exchangeItem, proceeds *big.Int) []CapitalGainsRow {
+	weights := make([]*big.Int, len(it.lots))
+	for i, l := range it.lots {
+		weights[i] = &l.qty
+	}
+	var rows []CapitalGainsRow
+	for i, p := range allocate(proceeds, weights) {
+		l := it.lots[i]
+		r := CapitalGainsRow{
+			Account:  it.account,
+			Amount:   &journal.Amount{Unit: it.unit},
+			Acquired: l.date,
+			Disposed: t.EntryDate,
+			Proceeds: &journal.Amount{Unit: b.base},
+			Cost:     &journal.Amount{Unit: b.base},
+			Gain:     &journal.Amount{Unit: b.base},
+			LongTerm: isLongTerm(l.date, t.EntryDate),
+			Ref:      t.Position().String(),
+		}
+		r.Amount.Number.Set(&l.qty)
+		r.Proceeds.Number.Set(p)
+		r.Cost.Number.Set(&l.cost)
+		r.Gain.Number.Sub(p, &l.cost)
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+// take removes acquisitions, oldest first, for the given quantity.
+// If there are not enough units, all of the acquisitions are
+// returned.
+func (h *holdingLots) take(qty *big.Int) []*gainLot {
+	return takeLots(&h.lots, qty)
+}
+
+// takeLots removes acquisitions from the slice, oldest first, for
+// the given quantity.  A partially taken acquisition is split.
+func takeLots(lots *[]*gainLot, qty *big.Int) []*gainLot {
+	var want big.Int
+	want.Set(qty)
+	var taken []*gainLot
+	for len(*lots) > 0 && want.Sign() > 0 {
+		l := (*lots)[0]
+		if l.qty.Cmp(&want) <= 0 {
+			taken = append(taken, l)
+			want.Sub(&want, &l.qty)
+			*lots = (*lots)[1:]
+			continue
+		}
+		part := &gainLot{date: l.date}
+		part.qty.Set(&want)
+		part.cost.Mul(&l.cost, &want)
+		part.cost.Quo(&part.cost, &l.qty)
+		l.qty.Sub(&l.qty, &want)
+		l.cost.Sub(&l.cost, &part.cost)
+		taken = append(taken, part)
+		break
+	}
+	return taken
+}
+
+// isLongTerm returns whether units acquired and disposed on the given
+// dates were held for more than a year.
+func isLongTerm(acquired, disposed civil.Date) bool {
+	return disposed.After(civil.DateOf(acquired.In(time.UTC).AddDate(1, 0, 0)))
+}
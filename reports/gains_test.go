@@ -0,0 +1,224 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/journal"
+)
+
+func TestNewCapitalGains(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, `unit USD 100
+unit VTI 1
+tx 2020-01-10 "Buy"
+Assets:Broker 10 VTI
+Trading:VTI -10 VTI
+Trading:VTI 1000 USD
+Assets:Cash -1000 USD
+end
+tx 2021-03-01 "Buy"
+Assets:Broker 10 VTI
+Trading:VTI -10 VTI
+Trading:VTI 1200 USD
+Assets:Cash -1200 USD
+end
+tx 2021-02-01 "Sell before period"
+Assets:Broker -2 VTI
+Trading:VTI 2 VTI
+Trading:VTI -210 USD
+Assets:Cash 210 USD
+end
+tx 2021-06-01 "Sell"
+Assets:Broker -12 VTI
+Trading:VTI 12 VTI
+Trading:VTI -1560 USD
+Assets:Cash 1560 USD
+end
+`)
+	got, err := NewCapitalGains(j, j.Units["USD"], civil.Date{2021, 3, 1}, civil.Date{2021, 12, 31})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Assets:Broker 8 VTI 2020-01-10 2021-06-01 1,040.00 USD 800.00 USD 240.00 USD long",
+		"Assets:Broker 4 VTI 2021-03-01 2021-06-01 520.00 USD 480.00 USD 40.00 USD short",
+	}
+	if diff := cmp.Diff(want, formatGainsRows(got.Rows)); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+	wantTotals := []string{
+		"VTI 12 VTI 1,560.00 USD 1,280.00 USD 280.00 USD 40.00 USD 240.00 USD",
+	}
+	if diff := cmp.Diff(wantTotals, formatGainsTotals(got.Totals)); diff != "" {
+		t.Errorf("totals mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewCapitalGains_transfer(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, `unit USD 100
+unit VTI 1
+tx 2020-01-10 "Buy"
+Assets:Broker 10 VTI
+Trading:VTI -10 VTI
+Trading:VTI 1000 USD
+Assets:Cash -1000 USD
+end
+tx 2020-02-01 "Transfer"
+Assets:Broker -10 VTI
+Assets:Broker2 10 VTI
+end
+tx 2020-03-01 "Sell"
+Assets:Broker2 -5 VTI
+Trading:VTI 5 VTI
+Trading:VTI -600 USD
+Assets:Cash 600 USD
+end
+`)
+	got, err := NewCapitalGains(j, j.Units["USD"], civil.Date{2020, 1, 1}, civil.Date{2020, 12, 31})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Assets:Broker2 5 VTI 2020-01-10 2020-03-01 600.00 USD 500.00 USD 100.00 USD short",
+	}
+	if diff := cmp.Diff(want, formatGainsRows(got.Rows)); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewCapitalGains_booked_lots(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, `unit USD 100
+unit VTI 1
+account Assets:Broker
+meta "booking" "lifo"
+end
+tx 2020-01-10 "Buy"
+Assets:Broker 10 VTI {100 USD}
+Assets:Cash
+end
+tx 2020-02-10 "Buy"
+Assets:Broker 10 VTI {110 USD}
+Assets:Cash
+end
+tx 2020-03-01 "Sell"
+Assets:Broker -15 VTI {}
+Assets:Cash 1800 USD
+Income:Gains
+end
+`)
+	got, err := NewCapitalGains(j, j.Units["USD"], civil.Date{2020, 1, 1}, civil.Date{2020, 12, 31})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Assets:Broker 10 VTI 2020-02-10 2020-03-01 1,200.00 USD 1,100.00 USD 100.00 USD short",
+		"Assets:Broker 5 VTI 2020-01-10 2020-03-01 600.00 USD 500.00 USD 100.00 USD short",
+	}
+	if diff := cmp.Diff(want, formatGainsRows(got.Rows)); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewCapitalGains_booked_lots_no_price(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, `unit USD 100
+unit EUR 100
+unit VTI 1
+tx 2020-01-10 "Buy"
+Assets:Broker 10 VTI {100 EUR}
+Assets:Cash
+end
+tx 2020-03-01 "Sell"
+Assets:Broker -5 VTI {}
+Assets:Cash 500 EUR
+end
+`)
+	_, err := NewCapitalGains(j, j.Units["USD"], civil.Date{2020, 1, 1}, civil.Date{2020, 12, 31})
+	if err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+	got := allocate(big.NewInt(100), []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)})
+	want := []string{"33", "33", "34"}
+	var gotS []string
+	for _, n := range got {
+		gotS = append(gotS, n.String())
+	}
+	if diff := cmp.Diff(want, gotS); diff != "" {
+		t.Errorf("allocation mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsLongTerm(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		acquired, disposed civil.Date
+		want               bool
+	}{
+		{civil.Date{2020, 1, 10}, civil.Date{2021, 1, 10}, false},
+		{civil.Date{2020, 1, 10}, civil.Date{2021, 1, 11}, true},
+		{civil.Date{2020, 2, 29}, civil.Date{2021, 3, 1}, false},
+		{civil.Date{2020, 2, 29}, civil.Date{2021, 3, 2}, true},
+	}
+	for _, c := range cases {
+		if got := isLongTerm(c.acquired, c.disposed); got != c.want {
+			t.Errorf("isLongTerm(%s, %s) = %v; want %v", c.acquired, c.disposed, got, c.want)
+		}
+	}
+}
+
+func compileText(t *testing.T, s string) *journal.Journal {
+	t.Helper()
+	j, err := journal.Compile(&journal.CompileArgs{
+		Inputs: []journal.CompileInput{journal.Bytes("testfile", []byte(s))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func formatGainsRows(rows []CapitalGainsRow) []string {
+	var s []string
+	for _, r := range rows {
+		term := "short"
+		if r.LongTerm {
+			term = "long"
+		}
+		s = append(s, fmt.Sprintf("%s %s %s %s %s %s %s %s",
+			r.Account, r.Amount, r.Acquired, r.Disposed, r.Proceeds, r.Cost, r.Gain, term))
+	}
+	return s
+}
+
+func formatGainsTotals(totals []CapitalGainsTotal) []string {
+	var s []string
+	for _, t := range totals {
+		s = append(s, fmt.Sprintf("%s %s %s %s %s %s %s",
+			t.Unit, t.Amount, t.Proceeds, t.Cost, t.Gain, t.ShortTerm, t.LongTerm))
+	}
+	return s
+}
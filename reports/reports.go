@@ -84,8 +84,11 @@ type LedgerRow struct {
 	Date        civil.Date
 	Description string
 	// A reference to the file location for the transaction split.
-	Ref  string
-	Pair Pair[*journal.Amount]
+	Ref string
+	// A reference to the file location of the rule that added
+	// the split, or empty.
+	RuleRef string
+	Pair    Pair[*journal.Amount]
 	// Running balance for the account.
 	Balance journal.Balance
 }
@@ -106,6 +109,9 @@ func NewAccountLedger(j *journal.Journal, a journal.Account) *AccountLedger {
 				if s.Account != a {
 					continue
 				}
+				if s.Rule != nil {
+					r.RuleRef = s.Rule.Position().String()
+				}
 				switch s.Amount.Sign() {
 				case -1:
 					r.Pair.Credit = s.Amount
@@ -62,6 +62,36 @@ func TestNewAccountLedger_multi_line_entry(t *testing.T) {
 	}
 }
 
+func TestNewAccountLedger_rule(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, `unit USD 100
+rule "Tax reserve"
+match Income:Consulting
+Liabilities:TaxReserve 20%
+Expenses:Taxes
+end
+tx 2020-01-05 "Invoice"
+Assets:Cash 1000 USD
+Income:Consulting
+end
+`)
+	got := NewAccountLedger(j, "Liabilities:TaxReserve")
+	u := journal.Unit{Symbol: "USD", Scale: 100}
+	want := []LedgerRow{
+		{
+			Date:        civil.Date{2020, 1, 5},
+			Description: "Invoice",
+			Ref:         "testfile:7:1",
+			RuleRef:     "testfile:2:1",
+			Pair:        Pair[*journal.Amount]{Credit: amount(-20000, u)},
+			Balance:     new(balFac).add(u, -20000).bal(),
+		},
+	}
+	if diff := cmpdiff(want, got.Rows); diff != "" {
+		t.Errorf("ledger mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func amount(n int64, u journal.Unit) *journal.Amount {
 	a := journal.Amount{
 		Unit: u,
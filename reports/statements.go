@@ -0,0 +1,313 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"cloud.google.com/go/civil"
+	"go.felesatra.moe/keeper/journal"
+)
+
+// A Classifier categorizes accounts for financial statements.
+type Classifier interface {
+	IsAssets(journal.Account) bool
+	IsLiabilities(journal.Account) bool
+	IsEquity(journal.Account) bool
+	IsIncome(journal.Account) bool
+	IsExpenses(journal.Account) bool
+	IsTrading(journal.Account) bool
+	IsCash(journal.Account) bool
+}
+
+// A StatementSection is a section of a financial statement.
+type StatementSection struct {
+	Rows  []StatementRow
+	Total journal.Balance
+}
+
+// A StatementRow is a row in a StatementSection.
+type StatementRow struct {
+	Account journal.Account
+	Balance journal.Balance
+}
+
+func (s *StatementSection) add(a journal.Account, b *journal.Balance) {
+	if b.Empty() {
+		return
+	}
+	r := StatementRow{Account: a}
+	r.Balance.Set(b)
+	s.Rows = append(s.Rows, r)
+	s.Total.AddBal(b)
+}
+
+func (s *StatementSection) addAmount(a journal.Account, am *journal.Amount) {
+	r := StatementRow{Account: a}
+	r.Balance.Add(am)
+	s.Rows = append(s.Rows, r)
+	s.Total.Add(am)
+}
+
+// An IncomeStatement is a report of income and expenses over a
+// period.  Income is shown as positive amounts.
+type IncomeStatement struct {
+	Start, End civil.Date
+	Income     StatementSection
+	Expenses   StatementSection
+	// NetProfit is income minus expenses.
+	NetProfit journal.Balance
+}
+
+// NewIncomeStatement returns the income statement for the period
+// between the start and end dates, inclusive.
+//
+// Closing transactions are ignored.  Thus the statement is the same
+// whether or not the books are closed during the period.
+func NewIncomeStatement(j *journal.Journal, c Classifier, start, end civil.Date) *IncomeStatement {
+	s := &IncomeStatement{Start: start, End: end}
+	b := make(journal.Balances)
+	for _, t := range transactions(j, start, end) {
+		if isClosing(c, t) {
+			continue
+		}
+		for _, sp := range t.Splits {
+			b.Add(sp.Account, sp.Amount)
+		}
+	}
+	for _, a := range b.Accounts() {
+		switch {
+		case c.IsIncome(a):
+			// Income is credit balance.
+			var n journal.Balance
+			n.Set(b[a])
+			n.Neg()
+			s.Income.add(a, &n)
+		case c.IsExpenses(a):
+			s.Expenses.add(a, b[a])
+		}
+	}
+	s.NetProfit.Set(&s.Income.Total)
+	var e journal.Balance
+	e.Set(&s.Expenses.Total)
+	e.Neg()
+	s.NetProfit.AddBal(&e)
+	return s
+}
+
+// ClosingTag is the tag for closing transactions, which move the
+// balances of income and expense accounts to equity.  keeper close
+// writes closing transactions with this tag.
+const ClosingTag = "closing"
+
+// legacyClosingDescription is the description of closing
+// transactions written by keeper close before ClosingTag was added.
+const legacyClosingDescription = "Closing"
+
+// isClosing returns true if the transaction is a closing
+// transaction.  Closing transactions are tagged with ClosingTag.
+// Untagged transactions written by older versions of keeper close
+// are recognized by their description and by only moving balances
+// between income, expense, trading, and equity accounts.
+func isClosing(c Classifier, t *journal.Transaction) bool {
+	if t.HasTag(ClosingTag) {
+		return true
+	}
+	if t.Description != legacyClosingDescription {
+		return false
+	}
+	equity := false
+	for _, s := range t.Splits {
+		switch a := s.Account; {
+		case c.IsEquity(a):
+			equity = true
+		case c.IsIncome(a), c.IsExpenses(a), c.IsTrading(a):
+		default:
+			return false
+		}
+	}
+	return equity
+}
+
+// A BalanceSheet is a report of assets, liabilities, and equity at
+// the close of a date.  Liabilities and equity are shown as positive
+// amounts.
+type BalanceSheet struct {
+	Date        civil.Date
+	Assets      StatementSection
+	Liabilities StatementSection
+	// Equity includes the balances of income, expense, and
+	// trading accounts that have not been closed.
+	Equity StatementSection
+	// LiabilitiesEquity is the total of liabilities and equity.
+	LiabilitiesEquity journal.Balance
+}
+
+// NewBalanceSheet returns the balance sheet at the close of the given
+// date.
+func NewBalanceSheet(j *journal.Journal, c Classifier, d civil.Date) *BalanceSheet {
+	s := &BalanceSheet{Date: d}
+	b := j.BalancesEnding(d)
+	for _, a := range b.Accounts() {
+		switch {
+		case c.IsAssets(a):
+			s.Assets.add(a, b[a])
+		case c.IsLiabilities(a):
+			b[a].Neg()
+			s.Liabilities.add(a, b[a])
+		case c.IsEquity(a), c.IsIncome(a), c.IsExpenses(a), c.IsTrading(a):
+			b[a].Neg()
+			s.Equity.add(a, b[a])
+		}
+	}
+	s.LiabilitiesEquity.Set(&s.Liabilities.Total)
+	s.LiabilitiesEquity.AddBal(&s.Equity.Total)
+	return s
+}
+
+// A CashFlow is a report of the flow of cash over a period.
+//
+// The inflow and outflow sections contain the accounts that cash
+// flowed from and to, respectively, with one row for each unit.
+type CashFlow struct {
+	Start, End civil.Date
+	Starting   StatementSection
+	Inflows    StatementSection
+	Outflows   StatementSection
+	Ending     StatementSection
+}
+
+// NewCashFlow returns the cash flow for the period between the start
+// and end dates, inclusive.
+func NewCashFlow(j *journal.Journal, c Classifier, start, end civil.Date) *CashFlow {
+	s := &CashFlow{Start: start, End: end}
+	a := filterAccounts(sortedAccounts(j), c.IsCash)
+	starting := j.BalancesEnding(start.AddDays(-1))
+	ending := j.BalancesEnding(end)
+	for _, a := range a {
+		s.Starting.add(a, starting[a])
+		s.Ending.add(a, ending[a])
+	}
+	delta := accountFlows(j, a, start, end)
+	// Amounts represent flow away from cash accounts.
+	delta.Neg()
+	splitFlows(delta, &s.Inflows, &s.Outflows)
+	return s
+}
+
+// A CapitalStatement is a report of the changes in equity over a
+// period.  Equity is shown as positive amounts.
+//
+// The increase and decrease sections contain the accounts that
+// equity flowed from and to, respectively, with one row for each
+// unit.
+type CapitalStatement struct {
+	Start, End civil.Date
+	Starting   StatementSection
+	Increases  StatementSection
+	Decreases  StatementSection
+	Ending     StatementSection
+}
+
+// NewCapitalStatement returns the capital statement for the period
+// between the start and end dates, inclusive.  Income and expense
+// accounts are included in equity.
+func NewCapitalStatement(j *journal.Journal, c Classifier, start, end civil.Date) *CapitalStatement {
+	s := &CapitalStatement{Start: start, End: end}
+	a := filterAccounts(sortedAccounts(j), func(a journal.Account) bool {
+		return c.IsEquity(a) || c.IsIncome(a) || c.IsExpenses(a)
+	})
+	starting := j.BalancesEnding(start.AddDays(-1))
+	starting.Neg()
+	ending := j.BalancesEnding(end)
+	ending.Neg()
+	for _, a := range a {
+		s.Starting.add(a, starting[a])
+		s.Ending.add(a, ending[a])
+	}
+	delta := accountFlows(j, a, start, end)
+	splitFlows(delta, &s.Increases, &s.Decreases)
+	return s
+}
+
+// splitFlows adds the positive amounts in the balances to the
+// inflow section and the negative amounts to the outflow section.
+func splitFlows(b journal.Balances, in, out *StatementSection) {
+	for _, a := range b.Accounts() {
+		for _, am := range b[a].Amounts() {
+			switch am.Sign() {
+			case -1:
+				out.addAmount(a, am)
+			case 1:
+				in.addAmount(a, am)
+			}
+		}
+	}
+}
+
+// accountFlows returns where the balances of the given accounts
+// flowed to or from between the start and end dates, inclusive.
+func accountFlows(j *journal.Journal, a []journal.Account, start, end civil.Date) journal.Balances {
+	p := make(map[journal.Account]bool)
+	for _, a := range a {
+		p[a] = true
+	}
+	delta := make(journal.Balances)
+	for _, t := range transactions(j, start, end) {
+		if !touches(t, p) {
+			continue
+		}
+		for _, s := range t.Splits {
+			if !p[s.Account] {
+				delta.Add(s.Account, s.Amount)
+			}
+		}
+	}
+	return delta
+}
+
+func touches(t *journal.Transaction, accounts map[journal.Account]bool) bool {
+	for _, s := range t.Splits {
+		if accounts[s.Account] {
+			return true
+		}
+	}
+	return false
+}
+
+// transactions returns the transactions between the start and end
+// dates, inclusive.
+func transactions(j *journal.Journal, start, end civil.Date) []*journal.Transaction {
+	var ts []*journal.Transaction
+	for _, e := range j.Entries {
+		t, ok := e.(*journal.Transaction)
+		if !ok || t.EntryDate.Before(start) {
+			continue
+		}
+		if t.EntryDate.After(end) {
+			break
+		}
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+func filterAccounts(a []journal.Account, f func(journal.Account) bool) []journal.Account {
+	var new []journal.Account
+	for _, a := range a {
+		if f(a) {
+			new = append(new, a)
+		}
+	}
+	return new
+}
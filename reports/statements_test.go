@@ -0,0 +1,187 @@
+// Copyright (C) 2026  Allen Li
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+	"go.felesatra.moe/keeper/internal/config"
+)
+
+const statementText = `unit USD 100
+tx 2020-01-01 "Opening"
+Assets:Cash 1000 USD
+Equity:Opening -1000 USD
+end
+tx 2020-01-15 "Salary"
+Assets:Cash 500 USD
+Income:Salary -500 USD
+end
+tx 2020-01-20 "Food"
+Liabilities:Card -30 USD
+Expenses:Food 30 USD
+end
+tx 2020-02-01 "Closing" #closing
+Income:Salary 500 USD
+Expenses:Food -30 USD
+Equity:Retained -470 USD
+end
+tx 2020-02-10 "Food"
+Assets:Cash -40 USD
+Expenses:Food 40 USD
+end
+tx 2020-02-20 "Pay card"
+Assets:Cash -30 USD
+Liabilities:Card 30 USD
+end
+`
+
+func statementConfig() *config.Config {
+	return &config.Config{
+		Account: config.Account{CashPrefix: []string{"Assets:Cash"}},
+	}
+}
+
+func TestNewIncomeStatement(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, statementText)
+	got := NewIncomeStatement(j, statementConfig(), civil.Date{2020, 2, 1}, civil.Date{2020, 2, 29})
+	want := []string{
+		"Expenses:Food 40.00 USD",
+		"total 40.00 USD",
+	}
+	if diff := cmp.Diff(want, formatSection(&got.Expenses)); diff != "" {
+		t.Errorf("expenses mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"total 0"}, formatSection(&got.Income)); diff != "" {
+		t.Errorf("income mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := got.NetProfit.String(), "-40.00 USD"; got != want {
+		t.Errorf("NetProfit = %q; want %q", got, want)
+	}
+}
+
+func TestNewIncomeStatement_equity_expense(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, `unit USD 100
+tx 2020-02-05 "Office supplies"
+Equity:Contributions -25 USD
+Expenses:Office 25 USD
+end
+`)
+	got := NewIncomeStatement(j, statementConfig(), civil.Date{2020, 2, 1}, civil.Date{2020, 2, 29})
+	if got, want := got.NetProfit.String(), "-25.00 USD"; got != want {
+		t.Errorf("NetProfit = %q; want %q", got, want)
+	}
+}
+
+func TestNewIncomeStatement_legacy_closing(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, `unit USD 100
+tx 2024-01-15 "Salary"
+Assets:Cash 100 USD
+Income:Salary -100 USD
+end
+tx 2024-02-01 "Closing"
+Income:Salary 100 USD
+Equity:Retained -100 USD
+end
+tx 2024-02-15 "Salary"
+Assets:Cash 100 USD
+Income:Salary -100 USD
+end
+`)
+	got := NewIncomeStatement(j, statementConfig(), civil.Date{2024, 2, 1}, civil.Date{2024, 2, 29})
+	if got, want := got.NetProfit.String(), "100.00 USD"; got != want {
+		t.Errorf("NetProfit = %q; want %q", got, want)
+	}
+}
+
+func TestNewBalanceSheet(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, statementText)
+	got := NewBalanceSheet(j, statementConfig(), civil.Date{2020, 1, 31})
+	wantAssets := []string{
+		"Assets:Cash 1,500.00 USD",
+		"total 1,500.00 USD",
+	}
+	if diff := cmp.Diff(wantAssets, formatSection(&got.Assets)); diff != "" {
+		t.Errorf("assets mismatch (-want +got):\n%s", diff)
+	}
+	wantEquity := []string{
+		"Equity:Opening 1,000.00 USD",
+		"Expenses:Food -30.00 USD",
+		"Income:Salary 500.00 USD",
+		"total 1,470.00 USD",
+	}
+	if diff := cmp.Diff(wantEquity, formatSection(&got.Equity)); diff != "" {
+		t.Errorf("equity mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := got.LiabilitiesEquity.String(), "1,500.00 USD"; got != want {
+		t.Errorf("LiabilitiesEquity = %q; want %q", got, want)
+	}
+}
+
+func TestNewCashFlow(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, statementText)
+	got := NewCashFlow(j, statementConfig(), civil.Date{2020, 2, 1}, civil.Date{2020, 2, 29})
+	want := [][]string{
+		{"Assets:Cash 1,500.00 USD", "total 1,500.00 USD"},
+		{"total 0"},
+		{"Expenses:Food -40.00 USD", "Liabilities:Card -30.00 USD", "total -70.00 USD"},
+		{"Assets:Cash 1,430.00 USD", "total 1,430.00 USD"},
+	}
+	gotSections := [][]string{
+		formatSection(&got.Starting),
+		formatSection(&got.Inflows),
+		formatSection(&got.Outflows),
+		formatSection(&got.Ending),
+	}
+	if diff := cmp.Diff(want, gotSections); diff != "" {
+		t.Errorf("sections mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewCapitalStatement(t *testing.T) {
+	t.Parallel()
+	j := compileText(t, statementText)
+	got := NewCapitalStatement(j, statementConfig(), civil.Date{2020, 2, 1}, civil.Date{2020, 2, 29})
+	want := [][]string{
+		{"Equity:Opening 1,000.00 USD", "Expenses:Food -30.00 USD", "Income:Salary 500.00 USD", "total 1,470.00 USD"},
+		{"total 0"},
+		{"Assets:Cash -40.00 USD", "total -40.00 USD"},
+		{"Equity:Opening 1,000.00 USD", "Equity:Retained 470.00 USD", "Expenses:Food -40.00 USD", "total 1,430.00 USD"},
+	}
+	gotSections := [][]string{
+		formatSection(&got.Starting),
+		formatSection(&got.Increases),
+		formatSection(&got.Decreases),
+		formatSection(&got.Ending),
+	}
+	if diff := cmp.Diff(want, gotSections); diff != "" {
+		t.Errorf("sections mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func formatSection(s *StatementSection) []string {
+	var r []string
+	for _, row := range s.Rows {
+		r = append(r, string(row.Account)+" "+row.Balance.String())
+	}
+	return append(r, "total "+s.Total.String())
+}